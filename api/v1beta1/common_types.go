@@ -19,6 +19,8 @@ package v1beta1
 import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // KubernetesConfig will be the JSON struct for Basic Redis Config
@@ -29,6 +31,28 @@ type KubernetesConfig struct {
 	ExistingPasswordSecret *ExistingPasswordSecret          `json:"redisSecret,omitempty"`
 	ImagePullSecrets       *[]corev1.LocalObjectReference   `json:"imagePullSecrets,omitempty"`
 	UpdateStrategy         appsv1.StatefulSetUpdateStrategy `json:"updateStrategy,omitempty"`
+	// ImageMode is operator for the opstree redis images, which write redis.conf in their entrypoint, and
+	// upstream for the official or vendor images, which run redis-server with the redis.conf of the operator
+	// +kubebuilder:validation:Enum=operator;upstream
+	// +optional
+	ImageMode ImageMode `json:"imageMode,omitempty"`
+}
+
+// ImageMode selects how the redis container of the pods is configured
+type ImageMode string
+
+// Modes of the redis images
+const (
+	// ImageModeOperator runs the entrypoint of the opstree images, which reads SETUP_MODE, REDIS_PASSWORD and
+	// the other variables of the container
+	ImageModeOperator ImageMode = "operator"
+	// ImageModeUpstream runs redis-server with the redis.conf generated into a ConfigMap by the operator
+	ImageModeUpstream ImageMode = "upstream"
+)
+
+// IsUpstreamImage returns true if the pods run the redis.conf generated by the operator
+func (c *KubernetesConfig) IsUpstreamImage() bool {
+	return c.ImageMode == ImageModeUpstream
 }
 
 // RedisConfig defines the external configuration of Redis
@@ -45,6 +69,50 @@ type ExistingPasswordSecret struct {
 // Storage is the inteface to add pvc and pv support in redis
 type Storage struct {
 	VolumeClaimTemplate corev1.PersistentVolumeClaim `json:"volumeClaimTemplate,omitempty"`
+	// RetentionPolicy decides whether the PVCs are kept when the object is deleted or the statefulsets are scaled down
+	RetentionPolicy *PVCRetentionPolicy `json:"retentionPolicy,omitempty"`
+}
+
+// Values of the PVC retention policy
+const (
+	PVCRetentionRetain = "Retain"
+	PVCRetentionDelete = "Delete"
+)
+
+// PVCRetentionPolicy is the lifecycle of the PVCs created from the volume claim template
+type PVCRetentionPolicy struct {
+	// WhenDeleted applies to the PVCs of all pods when the Redis or RedisCluster is deleted
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +kubebuilder:default:=Delete
+	WhenDeleted string `json:"whenDeleted,omitempty"`
+	// WhenScaled applies to the PVCs of the pods removed by a scale-down
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +kubebuilder:default:=Retain
+	WhenScaled string `json:"whenScaled,omitempty"`
+}
+
+// ConditionStorageResized reports whether the PVCs and the volume claim templates of the statefulsets have the size of spec.storage
+const ConditionStorageResized = "StorageResized"
+
+// Reasons of the StorageResized condition
+const (
+	StorageReasonResized                 = "Resized"
+	StorageReasonExpanding               = "Expanding"
+	StorageReasonFileSystemResizePending = "FileSystemResizePending"
+	StorageReasonStatefulSetRecreating   = "StatefulSetRecreating"
+	StorageReasonExpansionNotAllowed     = "ExpansionNotAllowed"
+	StorageReasonShrinkNotSupported      = "ShrinkNotSupported"
+	StorageReasonExpansionFailed         = "ExpansionFailed"
+)
+
+// DeletePVCsWhenDeleted returns true if the PVCs are deleted with the object, which is the default
+func (s *Storage) DeletePVCsWhenDeleted() bool {
+	return s == nil || s.RetentionPolicy == nil || s.RetentionPolicy.WhenDeleted != PVCRetentionRetain
+}
+
+// DeletePVCsWhenScaled returns true if the PVCs of the removed pods are deleted on scale-down, they are kept by default
+func (s *Storage) DeletePVCsWhenScaled() bool {
+	return s != nil && s.RetentionPolicy != nil && s.RetentionPolicy.WhenScaled == PVCRetentionDelete
 }
 
 // RedisExporter interface will have the information for redis exporter related stuff
@@ -54,6 +122,105 @@ type RedisExporter struct {
 	Resources       *corev1.ResourceRequirements `json:"resources,omitempty"`
 	ImagePullPolicy corev1.PullPolicy            `json:"imagePullPolicy,omitempty"`
 	EnvVars         *[]corev1.EnvVar             `json:"env,omitempty"`
+	ServiceMonitor  *ServiceMonitor              `json:"serviceMonitor,omitempty"`
+	PrometheusRule  *PrometheusRule              `json:"prometheusRule,omitempty"`
+}
+
+// ServiceMonitor is the interface to create a prometheus-operator ServiceMonitor for the redis exporter
+type ServiceMonitor struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// +kubebuilder:default:="30s"
+	Interval      string `json:"interval,omitempty"`
+	ScrapeTimeout string `json:"scrapeTimeout,omitempty"`
+	// Labels are added to the ServiceMonitor so that a Prometheus instance can select it
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// PrometheusRule is the interface to create a prometheus-operator PrometheusRule with redis alerts
+type PrometheusRule struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Labels are added to the PrometheusRule so that a Prometheus instance can select it
+	Labels map[string]string `json:"labels,omitempty"`
+	// DisableDefaultRules skips the alerts shipped by the operator
+	DisableDefaultRules bool `json:"disableDefaultRules,omitempty"`
+	// MemoryUsagePercent is the used_memory/maxmemory percentage above which the memory alert fires
+	// +kubebuilder:default:=90
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	MemoryUsagePercent *int32                `json:"memoryUsagePercent,omitempty"`
+	AdditionalRules    []PrometheusAlertRule `json:"additionalRules,omitempty"`
+}
+
+// PrometheusAlertRule is a single alerting rule of a PrometheusRule
+type PrometheusAlertRule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// RedisMemory sets the maxmemory and the eviction policy of the redis servers at runtime
+type RedisMemory struct {
+	// MaxMemory is the absolute maxmemory of every redis server, e.g. 1536Mi
+	MaxMemory *resource.Quantity `json:"maxMemory,omitempty"`
+	// MaxMemoryPercent is the maxmemory as percentage of the container memory limit,
+	// it can not be combined with maxMemory
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	MaxMemoryPercent *int32 `json:"maxMemoryPercent,omitempty"`
+	// +kubebuilder:validation:Enum=noeviction;allkeys-lru;allkeys-lfu;allkeys-random;volatile-lru;volatile-lfu;volatile-random;volatile-ttl
+	EvictionPolicy string `json:"evictionPolicy,omitempty"`
+	// NearLimitPercent is the used_memory/maxmemory percentage above which the status reports a pod as near its limit
+	// +kubebuilder:default:=90
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	NearLimitPercent *int32 `json:"nearLimitPercent,omitempty"`
+}
+
+// RedisMemoryStatus is the memory usage of a redis server, the used memory is only reported as
+// percentage of maxmemory so that the status does not change on every reconcile
+type RedisMemoryStatus struct {
+	PodName string `json:"podName"`
+	// MaxMemory is the maxmemory of the server in bytes, 0 means unlimited
+	MaxMemory int64 `json:"maxMemory"`
+	// UsedPercent is used_memory/maxmemory, it is 0 if maxmemory is unlimited
+	UsedPercent int32 `json:"usedPercent"`
+	NearLimit   bool  `json:"nearLimit,omitempty"`
+}
+
+// RedisModule is a module which the redis servers load on startup
+type RedisModule struct {
+	// Name is the name of the module in MODULE LIST compared case-insensitively, e.g. rejson, search or bf.
+	// It names the init container of the module, so it has to be a lowercase DNS label
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=56
+	Name string `json:"name"`
+	// LoadModule is the path of the shared library in the redis container, the libraries copied by the
+	// init container are in /modules
+	LoadModule string `json:"loadmodule"`
+	// Args are passed to the module after its path
+	Args []string `json:"args,omitempty"`
+	// Image of an init container which copies the shared library at source into /modules
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// Source is the path of the shared library in the image of the init container
+	Source string `json:"source,omitempty"`
+}
+
+// RedisModuleStatus compares the modules in MODULE LIST of a redis server with spec.modules
+type RedisModuleStatus struct {
+	PodName string `json:"podName"`
+	// Loaded are the modules of spec.modules in MODULE LIST
+	Loaded []RedisLoadedModule `json:"loaded,omitempty"`
+	// Missing are the modules of spec.modules which are not in MODULE LIST
+	Missing []string `json:"missing,omitempty"`
+}
+
+// RedisLoadedModule is a module in MODULE LIST
+type RedisLoadedModule struct {
+	Name    string `json:"name"`
+	Version int64  `json:"version"`
 }
 
 // TLS Configuration for redis instances
@@ -65,8 +232,31 @@ type TLSConfig struct {
 	Secret corev1.SecretVolumeSource `json:"secret"`
 }
 
+// ProbeMode selects the check of a readiness or liveness probe
+type ProbeMode string
+
+// Checks of the probes, all but script work with the upstream redis images
+const (
+	// ProbeModeScript runs /usr/bin/healthcheck.sh of the opstree redis images
+	ProbeModeScript ProbeMode = "script"
+	// ProbeModeTCP opens a connection to the redis port
+	ProbeModeTCP ProbeMode = "tcp"
+	// ProbeModePing expects PONG to a PING
+	ProbeModePing ProbeMode = "ping"
+	// ProbeModeClusterState expects cluster_state:ok in CLUSTER INFO once the slots of the cluster are assigned
+	ProbeModeClusterState ProbeMode = "clusterState"
+	// ProbeModeReplication expects a master, or a replica whose link to the master is up
+	ProbeModeReplication ProbeMode = "replication"
+	// ProbeModeLoading expects the dataset to be loaded from disk
+	ProbeModeLoading ProbeMode = "loading"
+)
+
 // Probe is a interface for ReadinessProbe and LivenessProbe
 type Probe struct {
+	// Mode selects the check of the probe, script is used if it is not set
+	// +kubebuilder:validation:Enum=script;tcp;ping;clusterState;replication;loading
+	// +optional
+	Mode ProbeMode `json:"mode,omitempty"`
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:default=1
 	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty" protobuf:"varint,2,opt,name=initialDelaySeconds"`
@@ -91,4 +281,33 @@ type Sidecar struct {
 	ImagePullPolicy corev1.PullPolicy            `json:"imagePullPolicy,omitempty"`
 	Resources       *corev1.ResourceRequirements `json:"resources,omitempty"`
 	EnvVars         *[]corev1.EnvVar             `json:"env,omitempty"`
+	EnvFrom         []corev1.EnvFromSource       `json:"envFrom,omitempty"`
+	Command         []string                     `json:"command,omitempty"`
+	Args            []string                     `json:"args,omitempty"`
+	WorkingDir      string                       `json:"workingDir,omitempty"`
+	Ports           []corev1.ContainerPort       `json:"ports,omitempty"`
+	// VolumeMounts can reference the volumes of the operator by name: "data" is the persistent
+	// volume of the redis container, "external-config" the additional redis config and
+	// "tls-certs" the TLS secret. The data volume is mounted on /data unless it is mounted here.
+	VolumeMounts    []corev1.VolumeMount    `json:"volumeMounts,omitempty"`
+	LivenessProbe   *corev1.Probe           `json:"livenessProbe,omitempty"`
+	ReadinessProbe  *corev1.Probe           `json:"readinessProbe,omitempty"`
+	StartupProbe    *corev1.Probe           `json:"startupProbe,omitempty"`
+	Lifecycle       *corev1.Lifecycle       `json:"lifecycle,omitempty"`
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+}
+
+// PodTemplate is strategically merged into the pod template generated by the operator, lists
+// such as containers, initContainers, volumes and topologySpreadConstraints are merged by
+// their keys so that an entry named like a generated one patches it instead of replacing it
+type PodTemplate struct {
+	// Labels are added to the pods, the labels used by the operator selectors can not be changed
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are added to the pods
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Spec is a partial corev1.PodSpec
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	Spec *runtime.RawExtension `json:"spec,omitempty"`
 }
@@ -42,10 +42,28 @@ type RedisSpec struct {
 	LivenessProbe      *Probe     `json:"livenessProbe,omitempty" protobuf:"bytes,11,opt,name=livenessProbe"`
 	Sidecars           *[]Sidecar `json:"sidecars,omitempty"`
 	ServiceAccountName *string    `json:"serviceAccountName,omitempty"`
+	// PodTemplate overrides the generated pod template of the statefulsets
+	PodTemplate *PodTemplate `json:"podTemplate,omitempty"`
+	// Memory sets the maxmemory and eviction policy through CONFIG SET
+	Memory *RedisMemory `json:"memory,omitempty"`
+	// PodDisruptionBudget protects the redis pod against voluntary disruptions like node drains, without
+	// minAvailable and maxUnavailable it keeps the single pod available and blocks its eviction
+	PodDisruptionBudget *RedisPodDisruptionBudget `json:"pdb,omitempty"`
+	// Modules are loaded by the redis server on startup
+	Modules []RedisModule `json:"modules,omitempty"`
 }
 
 // RedisStatus defines the observed state of Redis
 type RedisStatus struct {
+	// Memory reports the memory usage when spec.memory is set
+	Memory []RedisMemoryStatus `json:"memory,omitempty"`
+	// Modules reports the modules of spec.modules loaded by the redis server
+	Modules []RedisModuleStatus `json:"modules,omitempty"`
+	// Conditions report the progress of long running operations like the storage expansion
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -30,7 +30,13 @@ type RedisClusterSpec struct {
 	// +kubebuilder:default:={livenessProbe:{initialDelaySeconds: 1, timeoutSeconds: 1, periodSeconds: 10, successThreshold: 1, failureThreshold:3}, readinessProbe:{initialDelaySeconds: 1, timeoutSeconds: 1, periodSeconds: 10, successThreshold: 1, failureThreshold:3}}
 	RedisLeader RedisLeader `json:"redisLeader,omitempty"`
 	// +kubebuilder:default:={livenessProbe:{initialDelaySeconds: 1, timeoutSeconds: 1, periodSeconds: 10, successThreshold: 1, failureThreshold:3}, readinessProbe:{initialDelaySeconds: 1, timeoutSeconds: 1, periodSeconds: 10, successThreshold: 1, failureThreshold:3}}
-	RedisFollower      RedisFollower                `json:"redisFollower,omitempty"`
+	RedisFollower RedisFollower `json:"redisFollower,omitempty"`
+	// ReplicasPerLeader is the number of healthy followers every leader should have, when set
+	// the follower statefulset is sized to the leader count times this value
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ReplicasPerLeader  *int32                       `json:"replicasPerLeader,omitempty"`
+	TopologyAware      *TopologyAware               `json:"topologyAware,omitempty"`
 	RedisExporter      *RedisExporter               `json:"redisExporter,omitempty"`
 	Storage            *Storage                     `json:"storage,omitempty"`
 	NodeSelector       map[string]string            `json:"nodeSelector,omitempty"`
@@ -42,18 +48,60 @@ type RedisClusterSpec struct {
 	Sidecars           *[]Sidecar                   `json:"sidecars,omitempty"`
 	ServiceAccountName *string                      `json:"serviceAccountName,omitempty"`
 	PersistenceEnabled *bool                        `json:"persistenceEnabled,omitempty"`
+	// PodTemplate overrides the generated pod template of the statefulsets
+	PodTemplate *PodTemplate `json:"podTemplate,omitempty"`
+	// Memory sets the maxmemory and eviction policy of leaders and followers through CONFIG SET
+	Memory *RedisMemory `json:"memory,omitempty"`
+	// Modules are loaded by the leaders and followers on startup
+	Modules []RedisModule `json:"modules,omitempty"`
+	// Proxy deploys a proxy in front of the leaders for clients without cluster support
+	Proxy *RedisProxy `json:"proxy,omitempty"`
+}
+
+// RedisProxy is a Deployment of envoy whose redis_proxy filter routes the commands of the clients to
+// the masters of the slots, the Service <cluster>-proxy exposes it on port 6379
+type RedisProxy struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// Image of envoy or of another proxy which reads the envoy config from /etc/envoy/envoy.yaml
+	// +kubebuilder:default:="envoyproxy/envoy:v1.24.1"
+	Image           string            `json:"image,omitempty"`
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// +kubebuilder:default:=2
+	// +kubebuilder:validation:Minimum=1
+	Replicas  *int32                       `json:"replicas,omitempty"`
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// AuthPassthrough makes the clients authenticate at the proxy with the password of the cluster,
+	// without it the proxy accepts the clients without password. The proxy always authenticates at the
+	// cluster if it has a password.
+	// +kubebuilder:default:=true
+	AuthPassthrough *bool `json:"authPassthrough,omitempty"`
 }
 
 func (cr *RedisClusterSpec) GetReplicaCounts(t string) int32 {
 	replica := cr.Size
 	if t == "leader" && cr.RedisLeader.Replicas != nil {
 		replica = cr.RedisLeader.Replicas
+	} else if t == "follower" && cr.ReplicasPerLeader != nil {
+		followers := cr.GetReplicaCounts("leader") * *cr.ReplicasPerLeader
+		replica = &followers
 	} else if t == "follower" && cr.RedisFollower.Replicas != nil {
 		replica = cr.RedisFollower.Replicas
 	}
 	return *replica
 }
 
+// GetReplicasPerLeader returns the number of followers every leader should have
+func (cr *RedisClusterSpec) GetReplicasPerLeader() int32 {
+	if cr.ReplicasPerLeader != nil {
+		return *cr.ReplicasPerLeader
+	}
+	leaders := cr.GetReplicaCounts("leader")
+	if leaders == 0 {
+		return 0
+	}
+	return cr.GetReplicaCounts("follower") / leaders
+}
+
 // RedisLeader interface will have the redis leader configuration
 type RedisLeader struct {
 	Replicas            *int32                    `json:"replicas,omitempty"`
@@ -78,8 +126,52 @@ type RedisFollower struct {
 	LivenessProbe *Probe `json:"livenessProbe,omitempty" protobuf:"bytes,11,opt,name=livenessProbe"`
 }
 
+// TopologyAware spreads the cluster pods over the zones and nodes, and keeps the followers of a
+// leader out of the leader's zone so that losing one zone never takes out both copies of a shard
+type TopologyAware struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// ZoneKey is the node label which identifies the zone of a node
+	// +kubebuilder:default:=topology.kubernetes.io/zone
+	ZoneKey string `json:"zoneKey,omitempty"`
+}
+
+// IsTopologyAware returns true if the zone aware placement is enabled
+func (cr *RedisClusterSpec) IsTopologyAware() bool {
+	return cr.TopologyAware != nil && cr.TopologyAware.Enabled
+}
+
+// GetZoneKey returns the node label used to find the zone of a pod
+func (cr *RedisClusterSpec) GetZoneKey() string {
+	if cr.TopologyAware == nil || cr.TopologyAware.ZoneKey == "" {
+		return corev1.LabelTopologyZone
+	}
+	return cr.TopologyAware.ZoneKey
+}
+
 // RedisClusterStatus defines the observed state of RedisCluster
 type RedisClusterStatus struct {
+	// Shards reports the replication health of every master serving slots
+	Shards []RedisClusterShardStatus `json:"shards,omitempty"`
+	// Memory reports the memory usage of every pod when spec.memory is set
+	Memory []RedisMemoryStatus `json:"memory,omitempty"`
+	// Modules reports the modules of spec.modules loaded by every pod
+	Modules []RedisModuleStatus `json:"modules,omitempty"`
+	// Conditions report the progress of long running operations like the storage expansion
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// RedisClusterShardStatus is the replication state of a single leader and its followers
+type RedisClusterShardStatus struct {
+	// Leader is the pod of the master serving the slots, a follower pod after a failover
+	Leader          string   `json:"leader"`
+	NodeID          string   `json:"nodeID,omitempty"`
+	Slots           string   `json:"slots,omitempty"`
+	Replicas        []string `json:"replicas,omitempty"`
+	HealthyReplicas int32    `json:"healthyReplicas"`
+	DesiredReplicas int32    `json:"desiredReplicas"`
 }
 
 // RedisPodDisruptionBudget configure a PodDisruptionBudget on the resource (leader/follower)
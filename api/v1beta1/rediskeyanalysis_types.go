@@ -0,0 +1,172 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Phases of a RedisKeyAnalysis
+const (
+	KeyAnalysisPending   = "Pending"
+	KeyAnalysisRunning   = "Running"
+	KeyAnalysisCompleted = "Completed"
+	KeyAnalysisFailed    = "Failed"
+)
+
+// RedisKeyAnalysisSpec defines the keys to sample and the size of the report
+type RedisKeyAnalysisSpec struct {
+	// RedisCluster is the name of the RedisCluster in the same namespace whose masters are scanned
+	RedisCluster string `json:"redisCluster"`
+	// Match limits the scan to the keys matching the glob pattern, all keys are scanned by default
+	// +optional
+	Match string `json:"match,omitempty"`
+	// KeysPerSecond is the number of keys scanned per second on every master, SCAN visits them whether they match or not
+	// +kubebuilder:default:=1000
+	// +kubebuilder:validation:Minimum=1
+	KeysPerSecond *int32 `json:"keysPerSecond,omitempty"`
+	// ScanCount is the COUNT hint of the SCAN calls
+	// +kubebuilder:default:=100
+	// +kubebuilder:validation:Minimum=1
+	ScanCount *int32 `json:"scanCount,omitempty"`
+	// MaxKeys stops the scan of a master after this many keys, all keys are scanned by default
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxKeys *int64 `json:"maxKeys,omitempty"`
+	// MemorySamples is the SAMPLES argument of MEMORY USAGE for the elements of nested types
+	// +kubebuilder:default:=5
+	// +kubebuilder:validation:Minimum=0
+	MemorySamples *int32 `json:"memorySamples,omitempty"`
+	// TopKeys is the number of biggest keys and busiest slots reported
+	// +kubebuilder:default:=20
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000
+	TopKeys *int32 `json:"topKeys,omitempty"`
+	// PrefixDelimiter separates the segments of the key names
+	// +kubebuilder:default:=":"
+	PrefixDelimiter string `json:"prefixDelimiter,omitempty"`
+	// PrefixDepth is the number of segments which form the prefix of a key
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=1
+	PrefixDepth *int32 `json:"prefixDepth,omitempty"`
+	// MaxPrefixes limits the number of distinct prefixes tracked, keys of further prefixes are counted as (other)
+	// +kubebuilder:default:=100
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000
+	MaxPrefixes *int32 `json:"maxPrefixes,omitempty"`
+	// OutputConfigMap is the name of a ConfigMap which receives the full report including the key count of every slot
+	// +optional
+	OutputConfigMap string `json:"outputConfigMap,omitempty"`
+}
+
+// RedisKeyAnalysisStatus reports the progress and the result of the analysis
+type RedisKeyAnalysisStatus struct {
+	// +kubebuilder:validation:Enum=Pending;Running;Completed;Failed
+	Phase   string `json:"phase,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ObservedGeneration is the generation of the spec the analysis was started for, the analysis restarts when the spec changes
+	ObservedGeneration int64        `json:"observedGeneration,omitempty"`
+	StartTime          *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime     *metav1.Time `json:"completionTime,omitempty"`
+	// Masters is the scan progress of every master of the cluster
+	Masters []RedisKeyScanProgress `json:"masters,omitempty"`
+	// ScannedKeys is the number of keys sampled on all masters
+	ScannedKeys int64 `json:"scannedKeys,omitempty"`
+	// ScannedBytes is the MEMORY USAGE of all sampled keys
+	ScannedBytes int64 `json:"scannedBytes,omitempty"`
+	// TopKeys are the biggest keys by MEMORY USAGE
+	TopKeys []RedisKeyInfo `json:"topKeys,omitempty"`
+	// Prefixes is the distribution of the keys over their prefixes, ordered by memory
+	Prefixes []RedisKeyPrefixStats `json:"prefixes,omitempty"`
+	// TTLHistogram counts the keys by their remaining time to live
+	TTLHistogram []RedisKeyTTLBucket `json:"ttlHistogram,omitempty"`
+	// HotSlots are the slots holding the most keys, counted with CLUSTER COUNTKEYSINSLOT once the scan completed
+	HotSlots []RedisSlotKeys `json:"hotSlots,omitempty"`
+}
+
+// RedisKeyScanProgress is the SCAN cursor of a single master
+type RedisKeyScanProgress struct {
+	PodName string `json:"podName"`
+	NodeID  string `json:"nodeID,omitempty"`
+	Slots   string `json:"slots,omitempty"`
+	Cursor  uint64 `json:"cursor,omitempty"`
+	// ScannedKeys is the number of keys sampled on the master
+	ScannedKeys int64 `json:"scannedKeys,omitempty"`
+	Completed   bool  `json:"completed,omitempty"`
+}
+
+// RedisKeyInfo describes a single sampled key
+type RedisKeyInfo struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Bytes int64  `json:"bytes"`
+	// TTLSeconds is the remaining time to live, -1 for keys without expiry
+	TTLSeconds int64  `json:"ttlSeconds"`
+	Slot       int32  `json:"slot"`
+	PodName    string `json:"podName"`
+}
+
+// RedisKeyPrefixStats is the number and memory of the keys sharing a prefix
+type RedisKeyPrefixStats struct {
+	Prefix string `json:"prefix"`
+	Keys   int64  `json:"keys"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// RedisKeyTTLBucket is the number of keys whose time to live falls into the bucket
+type RedisKeyTTLBucket struct {
+	// Bucket is one of persistent, <1m, <1h, <1d, <7d and >=7d
+	Bucket string `json:"bucket"`
+	Keys   int64  `json:"keys"`
+}
+
+// RedisSlotKeys is the number of keys of a slot
+type RedisSlotKeys struct {
+	Slot    int32  `json:"slot"`
+	Keys    int64  `json:"keys"`
+	PodName string `json:"podName,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.redisCluster`,description=Scanned RedisCluster
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,description=Phase of the analysis
+// +kubebuilder:printcolumn:name="Keys",type=integer,JSONPath=`.status.scannedKeys`,description=Number of sampled keys
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`,description=Age of the analysis
+
+// RedisKeyAnalysis samples the keys of every master of a RedisCluster and reports the biggest keys,
+// the prefix, slot and TTL distribution
+type RedisKeyAnalysis struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RedisKeyAnalysisSpec   `json:"spec"`
+	Status RedisKeyAnalysisStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RedisKeyAnalysisList contains a list of RedisKeyAnalysis
+type RedisKeyAnalysisList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RedisKeyAnalysis `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RedisKeyAnalysis{}, &RedisKeyAnalysisList{})
+}
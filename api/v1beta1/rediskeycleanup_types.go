@@ -0,0 +1,112 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Phases of a RedisKeyCleanup
+const (
+	KeyCleanupPending   = "Pending"
+	KeyCleanupRunning   = "Running"
+	KeyCleanupCompleted = "Completed"
+	KeyCleanupFailed    = "Failed"
+)
+
+// RedisKeyCleanupSpec defines the keys to delete
+type RedisKeyCleanupSpec struct {
+	// RedisCluster is the name of the RedisCluster in the same namespace whose masters are cleaned up
+	RedisCluster string `json:"redisCluster"`
+	// Pattern is the SCAN MATCH glob of the keys to delete, e.g. snrs:*
+	// +kubebuilder:validation:MinLength=1
+	Pattern string `json:"pattern"`
+	// BatchSize is the COUNT hint of the SCAN calls, the keys of a batch are deleted in a single pipeline
+	// +kubebuilder:default:=100
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	BatchSize *int32 `json:"batchSize,omitempty"`
+	// KeysPerSecond is the number of keys scanned per second on every master
+	// +kubebuilder:default:=1000
+	// +kubebuilder:validation:Minimum=1
+	KeysPerSecond *int32 `json:"keysPerSecond,omitempty"`
+	// DryRun only counts the matching keys without deleting them
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// RedisKeyCleanupStatus reports the progress of the cleanup
+type RedisKeyCleanupStatus struct {
+	// +kubebuilder:validation:Enum=Pending;Running;Completed;Failed
+	Phase   string `json:"phase,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ObservedGeneration is the generation of the spec the cleanup was started for, the cleanup restarts when the spec changes
+	ObservedGeneration int64        `json:"observedGeneration,omitempty"`
+	StartTime          *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime     *metav1.Time `json:"completionTime,omitempty"`
+	// Masters is the progress of every master of the cluster
+	Masters []RedisKeyCleanupProgress `json:"masters,omitempty"`
+	// MatchedKeys is the number of keys matching the pattern on all masters
+	MatchedKeys int64 `json:"matchedKeys,omitempty"`
+	// DeletedKeys is the number of keys removed with UNLINK on all masters
+	DeletedKeys int64 `json:"deletedKeys,omitempty"`
+	// SampleKeys are some of the matching keys, so that a dry run shows what would be deleted
+	SampleKeys []string `json:"sampleKeys,omitempty"`
+}
+
+// RedisKeyCleanupProgress is the SCAN cursor and the key counts of a single master
+type RedisKeyCleanupProgress struct {
+	PodName     string `json:"podName"`
+	NodeID      string `json:"nodeID,omitempty"`
+	Slots       string `json:"slots,omitempty"`
+	Cursor      uint64 `json:"cursor,omitempty"`
+	MatchedKeys int64  `json:"matchedKeys,omitempty"`
+	DeletedKeys int64  `json:"deletedKeys,omitempty"`
+	Completed   bool   `json:"completed,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type=string,JSONPath=`.spec.redisCluster`,description=Cleaned up RedisCluster
+// +kubebuilder:printcolumn:name="Pattern",type=string,JSONPath=`.spec.pattern`,description=Pattern of the deleted keys
+// +kubebuilder:printcolumn:name="DryRun",type=boolean,JSONPath=`.spec.dryRun`,description=Keys are only counted
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,description=Phase of the cleanup
+// +kubebuilder:printcolumn:name="Matched",type=integer,JSONPath=`.status.matchedKeys`,description=Number of matching keys
+// +kubebuilder:printcolumn:name="Deleted",type=integer,JSONPath=`.status.deletedKeys`,description=Number of deleted keys
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`,description=Age of the cleanup
+
+// RedisKeyCleanup deletes the keys matching a pattern on every master of a RedisCluster
+type RedisKeyCleanup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RedisKeyCleanupSpec   `json:"spec"`
+	Status RedisKeyCleanupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RedisKeyCleanupList contains a list of RedisKeyCleanup
+type RedisKeyCleanupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RedisKeyCleanup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RedisKeyCleanup{}, &RedisKeyCleanupList{})
+}
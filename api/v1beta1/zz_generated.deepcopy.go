@@ -23,7 +23,8 @@ package v1beta1
 
 import (
 	"k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -86,6 +87,55 @@ func (in *KubernetesConfig) DeepCopy() *KubernetesConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PVCRetentionPolicy) DeepCopyInto(out *PVCRetentionPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCRetentionPolicy.
+func (in *PVCRetentionPolicy) DeepCopy() *PVCRetentionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PVCRetentionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodTemplate) DeepCopyInto(out *PodTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Spec != nil {
+		in, out := &in.Spec, &out.Spec
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodTemplate.
+func (in *PodTemplate) DeepCopy() *PodTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(PodTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Probe) DeepCopyInto(out *Probe) {
 	*out = *in
@@ -101,13 +151,76 @@ func (in *Probe) DeepCopy() *Probe {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusAlertRule) DeepCopyInto(out *PrometheusAlertRule) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusAlertRule.
+func (in *PrometheusAlertRule) DeepCopy() *PrometheusAlertRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusAlertRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusRule) DeepCopyInto(out *PrometheusRule) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MemoryUsagePercent != nil {
+		in, out := &in.MemoryUsagePercent, &out.MemoryUsagePercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AdditionalRules != nil {
+		in, out := &in.AdditionalRules, &out.AdditionalRules
+		*out = make([]PrometheusAlertRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusRule.
+func (in *PrometheusRule) DeepCopy() *PrometheusRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Redis) DeepCopyInto(out *Redis) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Redis.
@@ -134,7 +247,7 @@ func (in *RedisCluster) DeepCopyInto(out *RedisCluster) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisCluster.
@@ -187,6 +300,26 @@ func (in *RedisClusterList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisClusterShardStatus) DeepCopyInto(out *RedisClusterShardStatus) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterShardStatus.
+func (in *RedisClusterShardStatus) DeepCopy() *RedisClusterShardStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisClusterShardStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisClusterSpec) DeepCopyInto(out *RedisClusterSpec) {
 	*out = *in
@@ -203,6 +336,16 @@ func (in *RedisClusterSpec) DeepCopyInto(out *RedisClusterSpec) {
 	}
 	in.RedisLeader.DeepCopyInto(&out.RedisLeader)
 	in.RedisFollower.DeepCopyInto(&out.RedisFollower)
+	if in.ReplicasPerLeader != nil {
+		in, out := &in.ReplicasPerLeader, &out.ReplicasPerLeader
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TopologyAware != nil {
+		in, out := &in.TopologyAware, &out.TopologyAware
+		*out = new(TopologyAware)
+		**out = **in
+	}
 	if in.RedisExporter != nil {
 		in, out := &in.RedisExporter, &out.RedisExporter
 		*out = new(RedisExporter)
@@ -267,6 +410,28 @@ func (in *RedisClusterSpec) DeepCopyInto(out *RedisClusterSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(PodTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		*out = new(RedisMemory)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]RedisModule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(RedisProxy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterSpec.
@@ -282,6 +447,32 @@ func (in *RedisClusterSpec) DeepCopy() *RedisClusterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisClusterStatus) DeepCopyInto(out *RedisClusterStatus) {
 	*out = *in
+	if in.Shards != nil {
+		in, out := &in.Shards, &out.Shards
+		*out = make([]RedisClusterShardStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		*out = make([]RedisMemoryStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]RedisModuleStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterStatus.
@@ -333,6 +524,16 @@ func (in *RedisExporter) DeepCopyInto(out *RedisExporter) {
 			}
 		}
 	}
+	if in.ServiceMonitor != nil {
+		in, out := &in.ServiceMonitor, &out.ServiceMonitor
+		*out = new(ServiceMonitor)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrometheusRule != nil {
+		in, out := &in.PrometheusRule, &out.PrometheusRule
+		*out = new(PrometheusRule)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisExporter.
@@ -391,76 +592,58 @@ func (in *RedisFollower) DeepCopy() *RedisFollower {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RedisLeader) DeepCopyInto(out *RedisLeader) {
+func (in *RedisKeyAnalysis) DeepCopyInto(out *RedisKeyAnalysis) {
 	*out = *in
-	if in.Replicas != nil {
-		in, out := &in.Replicas, &out.Replicas
-		*out = new(int32)
-		**out = **in
-	}
-	if in.RedisConfig != nil {
-		in, out := &in.RedisConfig, &out.RedisConfig
-		*out = new(RedisConfig)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Affinity != nil {
-		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.PodDisruptionBudget != nil {
-		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
-		*out = new(RedisPodDisruptionBudget)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.ReadinessProbe != nil {
-		in, out := &in.ReadinessProbe, &out.ReadinessProbe
-		*out = new(Probe)
-		**out = **in
-	}
-	if in.LivenessProbe != nil {
-		in, out := &in.LivenessProbe, &out.LivenessProbe
-		*out = new(Probe)
-		**out = **in
-	}
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisLeader.
-func (in *RedisLeader) DeepCopy() *RedisLeader {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyAnalysis.
+func (in *RedisKeyAnalysis) DeepCopy() *RedisKeyAnalysis {
 	if in == nil {
 		return nil
 	}
-	out := new(RedisLeader)
+	out := new(RedisKeyAnalysis)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RedisKeyAnalysis) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RedisList) DeepCopyInto(out *RedisList) {
+func (in *RedisKeyAnalysisList) DeepCopyInto(out *RedisKeyAnalysisList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Redis, len(*in))
+		*out = make([]RedisKeyAnalysis, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisList.
-func (in *RedisList) DeepCopy() *RedisList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyAnalysisList.
+func (in *RedisKeyAnalysisList) DeepCopy() *RedisKeyAnalysisList {
 	if in == nil {
 		return nil
 	}
-	out := new(RedisList)
+	out := new(RedisKeyAnalysisList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RedisList) DeepCopyObject() runtime.Object {
+func (in *RedisKeyAnalysisList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -468,60 +651,577 @@ func (in *RedisList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RedisPodDisruptionBudget) DeepCopyInto(out *RedisPodDisruptionBudget) {
+func (in *RedisKeyAnalysisSpec) DeepCopyInto(out *RedisKeyAnalysisSpec) {
 	*out = *in
-	if in.MinAvailable != nil {
-		in, out := &in.MinAvailable, &out.MinAvailable
+	if in.KeysPerSecond != nil {
+		in, out := &in.KeysPerSecond, &out.KeysPerSecond
 		*out = new(int32)
 		**out = **in
 	}
-	if in.MaxUnavailable != nil {
-		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+	if in.ScanCount != nil {
+		in, out := &in.ScanCount, &out.ScanCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxKeys != nil {
+		in, out := &in.MaxKeys, &out.MaxKeys
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MemorySamples != nil {
+		in, out := &in.MemorySamples, &out.MemorySamples
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TopKeys != nil {
+		in, out := &in.TopKeys, &out.TopKeys
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PrefixDepth != nil {
+		in, out := &in.PrefixDepth, &out.PrefixDepth
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxPrefixes != nil {
+		in, out := &in.MaxPrefixes, &out.MaxPrefixes
 		*out = new(int32)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisPodDisruptionBudget.
-func (in *RedisPodDisruptionBudget) DeepCopy() *RedisPodDisruptionBudget {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyAnalysisSpec.
+func (in *RedisKeyAnalysisSpec) DeepCopy() *RedisKeyAnalysisSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(RedisPodDisruptionBudget)
+	out := new(RedisKeyAnalysisSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RedisSpec) DeepCopyInto(out *RedisSpec) {
+func (in *RedisKeyAnalysisStatus) DeepCopyInto(out *RedisKeyAnalysisStatus) {
 	*out = *in
-	in.KubernetesConfig.DeepCopyInto(&out.KubernetesConfig)
-	if in.RedisExporter != nil {
-		in, out := &in.RedisExporter, &out.RedisExporter
-		*out = new(RedisExporter)
-		(*in).DeepCopyInto(*out)
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Masters != nil {
+		in, out := &in.Masters, &out.Masters
+		*out = make([]RedisKeyScanProgress, len(*in))
+		copy(*out, *in)
+	}
+	if in.TopKeys != nil {
+		in, out := &in.TopKeys, &out.TopKeys
+		*out = make([]RedisKeyInfo, len(*in))
+		copy(*out, *in)
+	}
+	if in.Prefixes != nil {
+		in, out := &in.Prefixes, &out.Prefixes
+		*out = make([]RedisKeyPrefixStats, len(*in))
+		copy(*out, *in)
+	}
+	if in.TTLHistogram != nil {
+		in, out := &in.TTLHistogram, &out.TTLHistogram
+		*out = make([]RedisKeyTTLBucket, len(*in))
+		copy(*out, *in)
+	}
+	if in.HotSlots != nil {
+		in, out := &in.HotSlots, &out.HotSlots
+		*out = make([]RedisSlotKeys, len(*in))
+		copy(*out, *in)
 	}
-	if in.RedisConfig != nil {
-		in, out := &in.RedisConfig, &out.RedisConfig
-		*out = new(RedisConfig)
-		(*in).DeepCopyInto(*out)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyAnalysisStatus.
+func (in *RedisKeyAnalysisStatus) DeepCopy() *RedisKeyAnalysisStatus {
+	if in == nil {
+		return nil
 	}
-	if in.Storage != nil {
-		in, out := &in.Storage, &out.Storage
-		*out = new(Storage)
-		(*in).DeepCopyInto(*out)
+	out := new(RedisKeyAnalysisStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisKeyCleanup) DeepCopyInto(out *RedisKeyCleanup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyCleanup.
+func (in *RedisKeyCleanup) DeepCopy() *RedisKeyCleanup {
+	if in == nil {
+		return nil
 	}
-	if in.NodeSelector != nil {
-		in, out := &in.NodeSelector, &out.NodeSelector
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	out := new(RedisKeyCleanup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RedisKeyCleanup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	if in.SecurityContext != nil {
-		in, out := &in.SecurityContext, &out.SecurityContext
-		*out = new(v1.PodSecurityContext)
-		(*in).DeepCopyInto(*out)
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisKeyCleanupList) DeepCopyInto(out *RedisKeyCleanupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RedisKeyCleanup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyCleanupList.
+func (in *RedisKeyCleanupList) DeepCopy() *RedisKeyCleanupList {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisKeyCleanupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RedisKeyCleanupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisKeyCleanupProgress) DeepCopyInto(out *RedisKeyCleanupProgress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyCleanupProgress.
+func (in *RedisKeyCleanupProgress) DeepCopy() *RedisKeyCleanupProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisKeyCleanupProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisKeyCleanupSpec) DeepCopyInto(out *RedisKeyCleanupSpec) {
+	*out = *in
+	if in.BatchSize != nil {
+		in, out := &in.BatchSize, &out.BatchSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.KeysPerSecond != nil {
+		in, out := &in.KeysPerSecond, &out.KeysPerSecond
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyCleanupSpec.
+func (in *RedisKeyCleanupSpec) DeepCopy() *RedisKeyCleanupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisKeyCleanupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisKeyCleanupStatus) DeepCopyInto(out *RedisKeyCleanupStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Masters != nil {
+		in, out := &in.Masters, &out.Masters
+		*out = make([]RedisKeyCleanupProgress, len(*in))
+		copy(*out, *in)
+	}
+	if in.SampleKeys != nil {
+		in, out := &in.SampleKeys, &out.SampleKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyCleanupStatus.
+func (in *RedisKeyCleanupStatus) DeepCopy() *RedisKeyCleanupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisKeyCleanupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisKeyInfo) DeepCopyInto(out *RedisKeyInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyInfo.
+func (in *RedisKeyInfo) DeepCopy() *RedisKeyInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisKeyInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisKeyPrefixStats) DeepCopyInto(out *RedisKeyPrefixStats) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyPrefixStats.
+func (in *RedisKeyPrefixStats) DeepCopy() *RedisKeyPrefixStats {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisKeyPrefixStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisKeyScanProgress) DeepCopyInto(out *RedisKeyScanProgress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyScanProgress.
+func (in *RedisKeyScanProgress) DeepCopy() *RedisKeyScanProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisKeyScanProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisKeyTTLBucket) DeepCopyInto(out *RedisKeyTTLBucket) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisKeyTTLBucket.
+func (in *RedisKeyTTLBucket) DeepCopy() *RedisKeyTTLBucket {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisKeyTTLBucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisLeader) DeepCopyInto(out *RedisLeader) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RedisConfig != nil {
+		in, out := &in.RedisConfig, &out.RedisConfig
+		*out = new(RedisConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(RedisPodDisruptionBudget)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadinessProbe != nil {
+		in, out := &in.ReadinessProbe, &out.ReadinessProbe
+		*out = new(Probe)
+		**out = **in
+	}
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(Probe)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisLeader.
+func (in *RedisLeader) DeepCopy() *RedisLeader {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisLeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisList) DeepCopyInto(out *RedisList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Redis, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisList.
+func (in *RedisList) DeepCopy() *RedisList {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RedisList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisLoadedModule) DeepCopyInto(out *RedisLoadedModule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisLoadedModule.
+func (in *RedisLoadedModule) DeepCopy() *RedisLoadedModule {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisLoadedModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisMemory) DeepCopyInto(out *RedisMemory) {
+	*out = *in
+	if in.MaxMemory != nil {
+		in, out := &in.MaxMemory, &out.MaxMemory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxMemoryPercent != nil {
+		in, out := &in.MaxMemoryPercent, &out.MaxMemoryPercent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NearLimitPercent != nil {
+		in, out := &in.NearLimitPercent, &out.NearLimitPercent
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisMemory.
+func (in *RedisMemory) DeepCopy() *RedisMemory {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisMemory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisMemoryStatus) DeepCopyInto(out *RedisMemoryStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisMemoryStatus.
+func (in *RedisMemoryStatus) DeepCopy() *RedisMemoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisMemoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisModule) DeepCopyInto(out *RedisModule) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisModule.
+func (in *RedisModule) DeepCopy() *RedisModule {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisModuleStatus) DeepCopyInto(out *RedisModuleStatus) {
+	*out = *in
+	if in.Loaded != nil {
+		in, out := &in.Loaded, &out.Loaded
+		*out = make([]RedisLoadedModule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Missing != nil {
+		in, out := &in.Missing, &out.Missing
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisModuleStatus.
+func (in *RedisModuleStatus) DeepCopy() *RedisModuleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisModuleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisPodDisruptionBudget) DeepCopyInto(out *RedisPodDisruptionBudget) {
+	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisPodDisruptionBudget.
+func (in *RedisPodDisruptionBudget) DeepCopy() *RedisPodDisruptionBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisPodDisruptionBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisProxy) DeepCopyInto(out *RedisProxy) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AuthPassthrough != nil {
+		in, out := &in.AuthPassthrough, &out.AuthPassthrough
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisProxy.
+func (in *RedisProxy) DeepCopy() *RedisProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisSlotKeys) DeepCopyInto(out *RedisSlotKeys) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisSlotKeys.
+func (in *RedisSlotKeys) DeepCopy() *RedisSlotKeys {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisSlotKeys)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisSpec) DeepCopyInto(out *RedisSpec) {
+	*out = *in
+	in.KubernetesConfig.DeepCopyInto(&out.KubernetesConfig)
+	if in.RedisExporter != nil {
+		in, out := &in.RedisExporter, &out.RedisExporter
+		*out = new(RedisExporter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RedisConfig != nil {
+		in, out := &in.RedisConfig, &out.RedisConfig
+		*out = new(RedisConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(Storage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Affinity != nil {
 		in, out := &in.Affinity, &out.Affinity
@@ -570,6 +1270,28 @@ func (in *RedisSpec) DeepCopyInto(out *RedisSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.PodTemplate != nil {
+		in, out := &in.PodTemplate, &out.PodTemplate
+		*out = new(PodTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		*out = new(RedisMemory)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(RedisPodDisruptionBudget)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]RedisModule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisSpec.
@@ -585,6 +1307,25 @@ func (in *RedisSpec) DeepCopy() *RedisSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisStatus) DeepCopyInto(out *RedisStatus) {
 	*out = *in
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		*out = make([]RedisMemoryStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]RedisModuleStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisStatus.
@@ -597,6 +1338,28 @@ func (in *RedisStatus) DeepCopy() *RedisStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceMonitor) DeepCopyInto(out *ServiceMonitor) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceMonitor.
+func (in *ServiceMonitor) DeepCopy() *ServiceMonitor {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceMonitor)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Sidecar) DeepCopyInto(out *Sidecar) {
 	*out = *in
@@ -616,6 +1379,60 @@ func (in *Sidecar) DeepCopyInto(out *Sidecar) {
 			}
 		}
 	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]v1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]v1.ContainerPort, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadinessProbe != nil {
+		in, out := &in.ReadinessProbe, &out.ReadinessProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StartupProbe != nil {
+		in, out := &in.StartupProbe, &out.StartupProbe
+		*out = new(v1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(v1.Lifecycle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Sidecar.
@@ -632,6 +1449,11 @@ func (in *Sidecar) DeepCopy() *Sidecar {
 func (in *Storage) DeepCopyInto(out *Storage) {
 	*out = *in
 	in.VolumeClaimTemplate.DeepCopyInto(&out.VolumeClaimTemplate)
+	if in.RetentionPolicy != nil {
+		in, out := &in.RetentionPolicy, &out.RetentionPolicy
+		*out = new(PVCRetentionPolicy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Storage.
@@ -659,3 +1481,18 @@ func (in *TLSConfig) DeepCopy() *TLSConfig {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyAware) DeepCopyInto(out *TopologyAware) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyAware.
+func (in *TopologyAware) DeepCopy() *TopologyAware {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyAware)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,616 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"redis-operator/k8sutils"
+)
+
+// connection holds the flags which select the cluster and the namespace, like the ones of kubectl
+type connection struct {
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+// plugin holds the clients shared by the commands
+type plugin struct {
+	cl       client.Client
+	executor k8sutils.PodExecutor
+	// attach runs a command in a container with the terminal attached to it
+	attach    func(namespace, podName, container string, cmd []string) error
+	namespace string
+	out       io.Writer
+}
+
+// command is a subcommand of the plugin, setup registers its flags and returns the function running it
+type command struct {
+	name  string
+	args  string
+	help  string
+	setup func(fs *flag.FlagSet) func(p *plugin, args []string) error
+	// nargs is the number of arguments, or the minimum number if variadic is set
+	nargs    int
+	variadic bool
+}
+
+var commands = []command{
+	{
+		name:  "topology",
+		args:  "CLUSTER",
+		help:  "Show the shards of a RedisCluster with the nodes, roles and slots.",
+		nargs: 1,
+		setup: func(fs *flag.FlagSet) func(p *plugin, args []string) error {
+			return func(p *plugin, args []string) error { return p.topology(args[0]) }
+		},
+	},
+	{
+		name:  "failover",
+		args:  "CLUSTER SHARD",
+		help:  "Promote a replica of a shard, the shard is its index, leader pod or node ID.",
+		nargs: 2,
+		setup: func(fs *flag.FlagSet) func(p *plugin, args []string) error {
+			replica := fs.String("replica", "", "Pod of the replica to promote, a healthy replica of the shard if empty.")
+			force := fs.Bool("force", false, "Promote the replica without the agreement of the unreachable master.")
+			takeover := fs.Bool("takeover", false, "Promote the replica without the agreement of the other masters.")
+			return func(p *plugin, args []string) error {
+				if *force && *takeover {
+					return fmt.Errorf("--force and --takeover are mutually exclusive")
+				}
+				mode := ""
+				if *force {
+					mode = "FORCE"
+				} else if *takeover {
+					mode = "TAKEOVER"
+				}
+				return p.failover(args[0], args[1], *replica, mode)
+			}
+		},
+	},
+	{
+		name:  "rebalance",
+		args:  "CLUSTER",
+		help:  "Move slots between the masters until they serve the same number of slots.",
+		nargs: 1,
+		setup: func(fs *flag.FlagSet) func(p *plugin, args []string) error {
+			useEmptyMasters := fs.Bool("use-empty-masters", true, "Move slots to masters which serve no slots yet.")
+			simulate := fs.Bool("simulate", false, "Only print the slot moves.")
+			return func(p *plugin, args []string) error { return p.rebalance(args[0], *useEmptyMasters, *simulate) }
+		},
+	},
+	{
+		name:  "forget",
+		args:  "CLUSTER NODE-ID",
+		help:  "Remove a failed node from the node tables of all other nodes.",
+		nargs: 2,
+		setup: func(fs *flag.FlagSet) func(p *plugin, args []string) error {
+			return func(p *plugin, args []string) error { return p.forget(args[0], args[1]) }
+		},
+	},
+	{
+		name:  "skip-reconcile",
+		args:  "NAME on|off",
+		help:  "Pause or resume the reconcile of a RedisCluster or Redis by the operator.",
+		nargs: 2,
+		setup: func(fs *flag.FlagSet) func(p *plugin, args []string) error {
+			kind := fs.String("kind", "rediscluster", "Kind of the object, rediscluster or redis.")
+			return func(p *plugin, args []string) error { return p.skipReconcile(*kind, args[0], args[1]) }
+		},
+	},
+	{
+		name:     "cli",
+		args:     "NAME [-- REDIS-CLI-ARGS]",
+		help:     "Open redis-cli on a pod of a RedisCluster or Redis, authenticated with the password of the object.",
+		nargs:    1,
+		variadic: true,
+		setup: func(fs *flag.FlagSet) func(p *plugin, args []string) error {
+			kind := fs.String("kind", "rediscluster", "Kind of the object, rediscluster or redis.")
+			pod := fs.String("pod", "", "Pod to connect to, the first leader or the redis pod if empty.")
+			return func(p *plugin, args []string) error { return p.cli(*kind, args[0], *pod, args[1:]) }
+		},
+	},
+}
+
+// printUsage writes the list of commands
+func printUsage(out io.Writer) {
+	fmt.Fprintln(out, "kubectl redis runs the day two operations on the RedisClusters and Redis of the redis operator.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", c.name, c.args, c.help)
+	}
+	_ = w.Flush()
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "All commands accept -n/--namespace, --context and --kubeconfig, run kubectl redis COMMAND -h for the other flags.")
+}
+
+// run parses the command line and runs the command on the plugin returned by connect
+func run(args []string, out io.Writer, connect func(connection) (*plugin, error)) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(out)
+		return nil
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		return fmt.Errorf("unknown command %q, run kubectl redis --help for the commands", args[0])
+	}
+
+	fs := flag.NewFlagSet("kubectl redis "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Usage = func() {
+		fmt.Fprintf(out, "%s\n\nUsage: kubectl redis %s [flags] %s\n\nFlags:\n", cmd.help, cmd.name, cmd.args)
+		fs.PrintDefaults()
+	}
+	var conn connection
+	addConnectionFlags(fs, &conn)
+	runCommand := cmd.setup(fs)
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(positional) < cmd.nargs || (!cmd.variadic && len(positional) > cmd.nargs) {
+		return fmt.Errorf("usage: kubectl redis %s [flags] %s", cmd.name, cmd.args)
+	}
+
+	p, err := connect(conn)
+	if err != nil {
+		return err
+	}
+	p.out = out
+	return runCommand(p, positional)
+}
+
+// addConnectionFlags registers the kubectl flags which all commands accept
+func addConnectionFlags(fs *flag.FlagSet, conn *connection) {
+	fs.StringVar(&conn.namespace, "n", "", "Namespace of the object, the namespace of the context if empty.")
+	fs.StringVar(&conn.namespace, "namespace", "", "Namespace of the object, the namespace of the context if empty.")
+	fs.StringVar(&conn.context, "context", "", "Name of the kubeconfig context to use.")
+	fs.StringVar(&conn.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+}
+
+// parseInterspersed parses the flags wherever they are on the command line, as kubectl does, and
+// returns the other arguments. Everything after -- is returned as is.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// redisErrorCodes are the error replies which redis-cli prints without failing
+var redisErrorCodes = []string{"ERR", "NOAUTH", "WRONGPASS", "NOPERM", "CLUSTERDOWN", "LOADING", "MASTERDOWN", "READONLY"}
+
+// redisReplyError returns the error of the redis-cli output, or nil if it is no error reply
+func redisReplyError(output string) error {
+	reply := strings.TrimPrefix(strings.TrimSpace(output), "(error) ")
+	for _, code := range redisErrorCodes {
+		if strings.HasPrefix(reply, code+" ") {
+			return fmt.Errorf("%s", reply)
+		}
+	}
+	return nil
+}
+
+// redisContainer returns the redis container of the pod, which is named like the statefulset
+func redisContainer(pod *corev1.Pod) string {
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	if i := strings.LastIndex(pod.Name, "-"); i > 0 {
+		for _, container := range pod.Spec.Containers {
+			if container.Name == pod.Name[:i] {
+				return container.Name
+			}
+		}
+	}
+	return pod.Spec.Containers[0].Name
+}
+
+// redisCLI runs redis-cli with the args in the pod and returns its output
+func (p *plugin) redisCLI(kubernetesConfig redisv1beta1.KubernetesConfig, tlsConfig *redisv1beta1.TLSConfig, pod *corev1.Pod, args ...string) (string, error) {
+	cmd, err := k8sutils.GenerateRedisCLICommand(p.cl, pod.Namespace, kubernetesConfig, tlsConfig, pod.Name, args...)
+	if err != nil {
+		return "", err
+	}
+	stdout, stderr, err := p.executor.Exec(pod.Namespace, pod.Name, redisContainer(pod), cmd)
+	if err != nil {
+		return stdout, fmt.Errorf("%s: %v %s", pod.Name, err, strings.TrimSpace(stderr))
+	}
+	if err := redisReplyError(stdout); err != nil {
+		return stdout, fmt.Errorf("%s: %v", pod.Name, err)
+	}
+	return stdout, nil
+}
+
+// getRedisCluster returns the RedisCluster of the namespace
+func (p *plugin) getRedisCluster(name string) (*redisv1beta1.RedisCluster, error) {
+	cr := &redisv1beta1.RedisCluster{}
+	if err := p.cl.Get(context.TODO(), types.NamespacedName{Namespace: p.namespace, Name: name}, cr); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// redisClusterView is the state of a cluster as seen by CLUSTER NODES on one of its pods
+type redisClusterView struct {
+	cr *redisv1beta1.RedisCluster
+	// pods are the running leader and follower pods by name
+	pods     map[string]*corev1.Pod
+	podNames []string
+	nodes    []k8sutils.RedisClusterNode
+}
+
+// clusterCLI runs redis-cli with the args in a pod of the cluster
+func (v *redisClusterView) clusterCLI(p *plugin, podName string, args ...string) (string, error) {
+	return p.redisCLI(v.cr.Spec.KubernetesConfig, v.cr.Spec.TLS, v.pods[podName], args...)
+}
+
+// getRedisClusterView reads CLUSTER NODES from the first running pod which answers, leaders first
+func (p *plugin) getRedisClusterView(name string) (*redisClusterView, error) {
+	cr, err := p.getRedisCluster(name)
+	if err != nil {
+		return nil, err
+	}
+	view := &redisClusterView{cr: cr, pods: map[string]*corev1.Pod{}}
+	podsByIP := map[string]string{}
+	for _, role := range []string{"leader", "follower"} {
+		for i := 0; i < int(cr.Spec.GetReplicaCounts(role)); i++ {
+			pod := &corev1.Pod{}
+			podName := cr.Name + "-" + role + "-" + strconv.Itoa(i)
+			err := p.cl.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: podName}, pod)
+			if apierrors.IsNotFound(err) || (err == nil && pod.Status.PodIP == "") {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			view.pods[podName] = pod
+			view.podNames = append(view.podNames, podName)
+			podsByIP[pod.Status.PodIP] = podName
+		}
+	}
+
+	err = fmt.Errorf("redis cluster %s has no running pods", name)
+	for _, podName := range view.podNames {
+		var output string
+		output, err = view.clusterCLI(p, podName, "CLUSTER", "NODES")
+		if err == nil {
+			view.nodes = k8sutils.ParseRedisClusterNodes(output, podsByIP)
+			return view, nil
+		}
+	}
+	return nil, err
+}
+
+// redisShard is a master which serves slots and its replicas
+type redisShard struct {
+	master   k8sutils.RedisClusterNode
+	replicas []k8sutils.RedisClusterNode
+}
+
+// firstSlot returns the lowest slot of the CLUSTER NODES slot list, which starts with the lowest range
+func firstSlot(slots string) int {
+	end := strings.IndexAny(slots, "- ")
+	if end < 0 {
+		end = len(slots)
+	}
+	slot, err := strconv.Atoi(slots[:end])
+	if err != nil {
+		return -1
+	}
+	return slot
+}
+
+// groupShards returns the masters serving slots ordered by their first slot with their replicas, and
+// the other nodes, which are masters without slots and replicas of those
+func groupShards(nodes []k8sutils.RedisClusterNode) ([]redisShard, []k8sutils.RedisClusterNode) {
+	var shards []redisShard
+	for _, node := range nodes {
+		if node.Master && node.Slots != "" {
+			shards = append(shards, redisShard{master: node})
+		}
+	}
+	sort.Slice(shards, func(i, j int) bool { return firstSlot(shards[i].master.Slots) < firstSlot(shards[j].master.Slots) })
+	var others []k8sutils.RedisClusterNode
+	for _, node := range nodes {
+		if node.Master && node.Slots != "" {
+			continue
+		}
+		placed := false
+		for i := range shards {
+			if node.MasterID != "" && shards[i].master.ID == node.MasterID {
+				shards[i].replicas = append(shards[i].replicas, node)
+				placed = true
+			}
+		}
+		if !placed {
+			others = append(others, node)
+		}
+	}
+	return shards, others
+}
+
+// findShard returns the shard with the index, the master pod or the master node ID
+func findShard(shards []redisShard, ref string) (*redisShard, error) {
+	if i, err := strconv.Atoi(ref); err == nil && i >= 0 && i < len(shards) {
+		return &shards[i], nil
+	}
+	for i := range shards {
+		if (shards[i].master.PodName != "" && shards[i].master.PodName == ref) || shards[i].master.ID == ref {
+			return &shards[i], nil
+		}
+	}
+	return nil, fmt.Errorf("shard %s not found, the shards are listed by kubectl redis topology", ref)
+}
+
+// nodeStatus is the STATUS column of the topology
+func nodeStatus(node k8sutils.RedisClusterNode) string {
+	if node.Healthy {
+		return "ok"
+	}
+	return "fail"
+}
+
+// orDash returns "-" for the empty columns of the topology
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// topology prints the shards of the cluster with their nodes
+func (p *plugin) topology(name string) error {
+	view, err := p.getRedisClusterView(name)
+	if err != nil {
+		return err
+	}
+	shards, others := groupShards(view.nodes)
+	w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SHARD\tPOD\tROLE\tADDRESS\tNODE-ID\tSLOTS\tSTATUS")
+	row := func(shard, role string, node k8sutils.RedisClusterNode) {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", shard, orDash(node.PodName), role, node.IP, node.ID, orDash(node.Slots), nodeStatus(node))
+	}
+	known := map[string]bool{}
+	for i, shard := range shards {
+		row(strconv.Itoa(i), "master", shard.master)
+		known[shard.master.PodName] = true
+		for _, replica := range shard.replicas {
+			row(strconv.Itoa(i), "replica", replica)
+			known[replica.PodName] = true
+		}
+	}
+	for _, node := range others {
+		role := "master"
+		if !node.Master {
+			role = "replica"
+		}
+		row("-", role, node)
+		known[node.PodName] = true
+	}
+	for _, podName := range view.podNames {
+		if !known[podName] {
+			fmt.Fprintf(w, "-\t%s\t-\t%s\t-\t-\tnot in cluster\n", podName, view.pods[podName].Status.PodIP)
+		}
+	}
+	return w.Flush()
+}
+
+// failover promotes a replica of the shard with CLUSTER FAILOVER
+func (p *plugin) failover(name, shardRef, replica, mode string) error {
+	view, err := p.getRedisClusterView(name)
+	if err != nil {
+		return err
+	}
+	shards, _ := groupShards(view.nodes)
+	shard, err := findShard(shards, shardRef)
+	if err != nil {
+		return err
+	}
+	var target *k8sutils.RedisClusterNode
+	for i := range shard.replicas {
+		node := &shard.replicas[i]
+		if node.PodName == "" {
+			continue
+		}
+		if (replica == "" && node.Healthy) || node.PodName == replica {
+			target = node
+			break
+		}
+	}
+	if target == nil {
+		if replica != "" {
+			return fmt.Errorf("%s is no replica of shard %s", replica, shardRef)
+		}
+		return fmt.Errorf("shard %s has no healthy replica to promote", shardRef)
+	}
+
+	args := []string{"CLUSTER", "FAILOVER"}
+	if mode != "" {
+		args = append(args, mode)
+	}
+	if _, err := view.clusterCLI(p, target.PodName, args...); err != nil {
+		return err
+	}
+	fmt.Fprintf(p.out, "Failover started, %s takes over the slots %s from %s\n", target.PodName, shard.master.Slots, orDash(shard.master.PodName))
+	return nil
+}
+
+// rebalance runs redis-cli --cluster rebalance on the first master
+func (p *plugin) rebalance(name string, useEmptyMasters, simulate bool) error {
+	view, err := p.getRedisClusterView(name)
+	if err != nil {
+		return err
+	}
+	shards, _ := groupShards(view.nodes)
+	var pod *corev1.Pod
+	for _, shard := range shards {
+		if shard.master.PodName != "" && shard.master.Healthy {
+			pod = view.pods[shard.master.PodName]
+			break
+		}
+	}
+	if pod == nil {
+		return fmt.Errorf("redis cluster %s has no healthy master", name)
+	}
+	args := []string{"--cluster", "rebalance", net.JoinHostPort(pod.Status.PodIP, "6379")}
+	if useEmptyMasters {
+		args = append(args, "--cluster-use-empty-masters")
+	}
+	if simulate {
+		args = append(args, "--cluster-simulate")
+	}
+	output, err := view.clusterCLI(p, pod.Name, args...)
+	fmt.Fprint(p.out, output)
+	return err
+}
+
+// forget sends CLUSTER FORGET for a failed node to all other nodes, which have to forget it within a minute
+// as the node is learned again from the gossip of the others otherwise
+func (p *plugin) forget(name, nodeID string) error {
+	view, err := p.getRedisClusterView(name)
+	if err != nil {
+		return err
+	}
+	var target *k8sutils.RedisClusterNode
+	for i := range view.nodes {
+		if view.nodes[i].ID == nodeID {
+			target = &view.nodes[i]
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("node %s is not known to redis cluster %s", nodeID, name)
+	}
+	if target.Healthy {
+		return fmt.Errorf("node %s is connected, only failed nodes can be forgotten", nodeID)
+	}
+
+	var failed, total int
+	for _, node := range view.nodes {
+		if node.ID == nodeID || node.PodName == "" {
+			continue
+		}
+		total++
+		if _, err := view.clusterCLI(p, node.PodName, "CLUSTER", "FORGET", nodeID); err != nil {
+			failed++
+			fmt.Fprintf(p.out, "%s: %v\n", node.PodName, err)
+			continue
+		}
+		fmt.Fprintf(p.out, "%s: forgot %s\n", node.PodName, nodeID)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d nodes could not forget node %s", failed, total, nodeID)
+	}
+	return nil
+}
+
+// skipReconcile sets or removes the annotation which makes the operator skip the object
+func (p *plugin) skipReconcile(kind, name, state string) error {
+	var obj client.Object
+	var annotation string
+	switch kind {
+	case "rediscluster":
+		obj, annotation = &redisv1beta1.RedisCluster{}, k8sutils.RedisClusterSkipReconcileAnnotation
+	case "redis":
+		obj, annotation = &redisv1beta1.Redis{}, k8sutils.RedisSkipReconcileAnnotation
+	default:
+		return fmt.Errorf("unknown kind %q, the kind is rediscluster or redis", kind)
+	}
+	if state != "on" && state != "off" {
+		return fmt.Errorf("unknown state %q, the state is on or off", state)
+	}
+	if err := p.cl.Get(context.TODO(), types.NamespacedName{Namespace: p.namespace, Name: name}, obj); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if state == "on" {
+		annotations[annotation] = "true"
+	} else {
+		delete(annotations, annotation)
+	}
+	obj.SetAnnotations(annotations)
+	if err := p.cl.Patch(context.TODO(), obj, patch); err != nil {
+		return err
+	}
+	if state == "on" {
+		fmt.Fprintf(p.out, "%s/%s is not reconciled until skip-reconcile is turned off\n", kind, name)
+	} else {
+		fmt.Fprintf(p.out, "%s/%s is reconciled again\n", kind, name)
+	}
+	return nil
+}
+
+// cli attaches the terminal to redis-cli on a pod of the object, cluster sessions follow the redirects
+func (p *plugin) cli(kind, name, podName string, args []string) error {
+	var kubernetesConfig redisv1beta1.KubernetesConfig
+	var tlsConfig *redisv1beta1.TLSConfig
+	switch kind {
+	case "rediscluster":
+		cr, err := p.getRedisCluster(name)
+		if err != nil {
+			return err
+		}
+		kubernetesConfig, tlsConfig = cr.Spec.KubernetesConfig, cr.Spec.TLS
+		if podName == "" {
+			podName = name + "-leader-0"
+		}
+		args = append([]string{"-c"}, args...)
+	case "redis":
+		cr := &redisv1beta1.Redis{}
+		if err := p.cl.Get(context.TODO(), types.NamespacedName{Namespace: p.namespace, Name: name}, cr); err != nil {
+			return err
+		}
+		kubernetesConfig, tlsConfig = cr.Spec.KubernetesConfig, cr.Spec.TLS
+		if podName == "" {
+			podName = name + "-0"
+		}
+	default:
+		return fmt.Errorf("unknown kind %q, the kind is rediscluster or redis", kind)
+	}
+
+	pod := &corev1.Pod{}
+	if err := p.cl.Get(context.TODO(), types.NamespacedName{Namespace: p.namespace, Name: podName}, pod); err != nil {
+		return err
+	}
+	cmd, err := k8sutils.GenerateRedisCLICommand(p.cl, p.namespace, kubernetesConfig, tlsConfig, podName, args...)
+	if err != nil {
+		return err
+	}
+	return p.attach(p.namespace, podName, redisContainer(pod), cmd)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"redis-operator/k8sutils"
+)
+
+// fakeExecutor records the commands and answers them with the reply of the longest prefix of "pod/container: command"
+type fakeExecutor struct {
+	replies map[string]string
+	calls   []string
+}
+
+func (e *fakeExecutor) Exec(namespace, podName, container string, cmd []string) (string, string, error) {
+	call := podName + "/" + container + ": " + strings.Join(cmd, " ")
+	e.calls = append(e.calls, call)
+	match := ""
+	for prefix := range e.replies {
+		if strings.HasPrefix(call, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return "", "Could not connect to Redis", fmt.Errorf("command terminated with exit code 1")
+	}
+	return e.replies[match], "", nil
+}
+
+const testClusterNodes = `aaaa 10.0.0.1:6379@16379 myself,master - 0 0 1 connected 8192-16383
+bbbb 10.0.0.2:6379@16379 master - 0 0 2 connected 0-8191
+cccc 10.0.0.3:6379@16379 slave aaaa 0 0 1 connected
+dddd 10.0.0.4:6379@16379 slave bbbb 0 0 2 connected
+eeee 10.0.0.9:6379@16379 slave,fail bbbb 0 0 2 disconnected
+`
+
+// newTestPlugin returns a plugin for a cluster with two leaders and two followers
+func newTestPlugin(replies map[string]string) (*plugin, *fakeExecutor, *bytes.Buffer) {
+	size := int32(2)
+	objs := []client.Object{&redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default"},
+		Spec:       redisv1beta1.RedisClusterSpec{Size: &size},
+	}}
+	for i, podName := range []string{"redis-cluster-leader-0", "redis-cluster-leader-1", "redis-cluster-follower-0", "redis-cluster-follower-1"} {
+		objs = append(objs, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: "default"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "redis-exporter"},
+				{Name: podName[:len(podName)-2]},
+			}},
+			Status: corev1.PodStatus{PodIP: fmt.Sprintf("10.0.0.%d", i+1)},
+		})
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	executor := &fakeExecutor{replies: replies}
+	out := &bytes.Buffer{}
+	return &plugin{cl: cl, executor: executor, namespace: "default", out: out}, executor, out
+}
+
+func TestParseInterspersed(t *testing.T) {
+	var tests = []struct {
+		args       []string
+		namespace  string
+		positional []string
+	}{
+		{[]string{"redis-cluster", "-n", "prod"}, "prod", []string{"redis-cluster"}},
+		{[]string{"--namespace=prod", "redis-cluster", "1"}, "prod", []string{"redis-cluster", "1"}},
+		{[]string{"redis-cluster", "--", "-n", "INFO"}, "", []string{"redis-cluster", "-n", "INFO"}},
+	}
+
+	for _, tt := range tests {
+		var conn connection
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		addConnectionFlags(fs, &conn)
+		got, err := parseInterspersed(fs, tt.args)
+		if err != nil {
+			t.Errorf("parseInterspersed(%v) returned %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.positional) || conn.namespace != tt.namespace {
+			t.Errorf("parseInterspersed(%v) = %v with namespace %q, want %v with namespace %q", tt.args, got, conn.namespace, tt.positional, tt.namespace)
+		}
+	}
+}
+
+func TestRunValidatesArguments(t *testing.T) {
+	connect := func(connection) (*plugin, error) { return nil, fmt.Errorf("connect must not be called") }
+	for _, args := range [][]string{{"unknown"}, {"failover", "redis-cluster"}, {"topology", "a", "b"}} {
+		if err := run(args, &bytes.Buffer{}, connect); err == nil || err.Error() == "connect must not be called" {
+			t.Errorf("run(%v) returned %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestRedisReplyError(t *testing.T) {
+	var tests = []struct {
+		output string
+		fails  bool
+	}{
+		{"OK\n", false},
+		{"ERR You should send CLUSTER FAILOVER to a replica\n", true},
+		{"(error) NOAUTH Authentication required.\n", true},
+		{"ERRORS\n", false},
+	}
+
+	for _, tt := range tests {
+		if err := redisReplyError(tt.output); (err != nil) != tt.fails {
+			t.Errorf("redisReplyError(%q) = %v", tt.output, err)
+		}
+	}
+}
+
+func TestGroupShards(t *testing.T) {
+	nodes := k8sutils.ParseRedisClusterNodes(testClusterNodes, nil)
+	shards, others := groupShards(nodes)
+	if len(shards) != 2 || shards[0].master.ID != "bbbb" || shards[1].master.ID != "aaaa" {
+		t.Fatalf("got shards %+v, want bbbb and aaaa ordered by slot", shards)
+	}
+	if len(shards[0].replicas) != 2 || len(shards[1].replicas) != 1 || len(others) != 0 {
+		t.Errorf("got replicas %+v and %+v, others %+v", shards[0].replicas, shards[1].replicas, others)
+	}
+	for _, ref := range []string{"0", "bbbb"} {
+		if shard, err := findShard(shards, ref); err != nil || shard.master.ID != "bbbb" {
+			t.Errorf("findShard(%q) = %+v, %v", ref, shard, err)
+		}
+	}
+	if _, err := findShard(shards, "2"); err == nil {
+		t.Errorf("findShard() found a shard out of range")
+	}
+}
+
+func TestTopology(t *testing.T) {
+	p, executor, out := newTestPlugin(map[string]string{"redis-cluster-leader-0/redis-cluster-leader: redis-cli CLUSTER NODES": testClusterNodes})
+	if err := p.topology("redis-cluster"); err != nil {
+		t.Fatalf("topology() returned %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got topology\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); !reflect.DeepEqual(fields, []string{"0", "redis-cluster-leader-1", "master", "10.0.0.2", "bbbb", "0-8191", "ok"}) {
+		t.Errorf("got first shard %v", fields)
+	}
+	if fields := strings.Fields(lines[3]); fields[1] != "-" || fields[6] != "fail" {
+		t.Errorf("got failed node %v", fields)
+	}
+	if len(executor.calls) != 1 {
+		t.Errorf("got calls %v", executor.calls)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	p, executor, _ := newTestPlugin(map[string]string{
+		"redis-cluster-leader-0/redis-cluster-leader: redis-cli CLUSTER NODES":        testClusterNodes,
+		"redis-cluster-follower-1/redis-cluster-follower: redis-cli CLUSTER FAILOVER": "OK\n",
+	})
+	if err := p.failover("redis-cluster", "redis-cluster-leader-1", "", "FORCE"); err != nil {
+		t.Fatalf("failover() returned %v", err)
+	}
+	if want := "redis-cluster-follower-1/redis-cluster-follower: redis-cli CLUSTER FAILOVER FORCE"; executor.calls[1] != want {
+		t.Errorf("got calls %v, want %s", executor.calls, want)
+	}
+	if err := p.failover("redis-cluster", "1", "redis-cluster-follower-1", ""); err == nil {
+		t.Errorf("failover() accepted a replica of another shard")
+	}
+}
+
+func TestForget(t *testing.T) {
+	p, executor, _ := newTestPlugin(map[string]string{
+		"redis-cluster-leader-0/redis-cluster-leader: redis-cli CLUSTER NODES": testClusterNodes,
+		"redis-cluster-": "OK\n",
+	})
+	if err := p.forget("redis-cluster", "bbbb"); err == nil {
+		t.Errorf("forget() accepted a connected node")
+	}
+	executor.calls = nil
+	if err := p.forget("redis-cluster", "eeee"); err != nil {
+		t.Fatalf("forget() returned %v", err)
+	}
+	// CLUSTER NODES and CLUSTER FORGET on the four pods
+	if len(executor.calls) != 5 {
+		t.Errorf("got calls %v", executor.calls)
+	}
+}
+
+func TestSkipReconcile(t *testing.T) {
+	p, _, _ := newTestPlugin(nil)
+	cr := &redisv1beta1.RedisCluster{}
+	key := types.NamespacedName{Namespace: "default", Name: "redis-cluster"}
+
+	if err := p.skipReconcile("rediscluster", "redis-cluster", "on"); err != nil {
+		t.Fatalf("skipReconcile() returned %v", err)
+	}
+	if err := p.cl.Get(context.TODO(), key, cr); err != nil || cr.Annotations[k8sutils.RedisClusterSkipReconcileAnnotation] != "true" {
+		t.Errorf("got annotations %v, %v", cr.Annotations, err)
+	}
+	if err := p.skipReconcile("rediscluster", "redis-cluster", "off"); err != nil {
+		t.Fatalf("skipReconcile() returned %v", err)
+	}
+	if err := p.cl.Get(context.TODO(), key, cr); err != nil {
+		t.Fatalf("could not get cluster: %v", err)
+	}
+	if _, found := cr.Annotations[k8sutils.RedisClusterSkipReconcileAnnotation]; found {
+		t.Errorf("got annotations %v", cr.Annotations)
+	}
+}
+
+func TestCLI(t *testing.T) {
+	p, _, _ := newTestPlugin(nil)
+	var attached []string
+	p.attach = func(namespace, podName, container string, cmd []string) error {
+		attached = append([]string{podName, container}, cmd...)
+		return nil
+	}
+	if err := p.cli("rediscluster", "redis-cluster", "", []string{"--raw"}); err != nil {
+		t.Fatalf("cli() returned %v", err)
+	}
+	if want := []string{"redis-cluster-leader-0", "redis-cluster-leader", "redis-cli", "-c", "--raw"}; !reflect.DeepEqual(attached, want) {
+		t.Errorf("got %v, want %v", attached, want)
+	}
+}
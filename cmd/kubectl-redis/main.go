@@ -0,0 +1,123 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-redis is a kubectl plugin for the day two operations on the Redis and RedisCluster
+// objects of the operator. The redis commands run inside the pods through the exec subresource,
+// so the pods do not have to be reachable from the machine running the plugin.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"redis-operator/k8sutils"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(redisv1beta1.AddToScheme(scheme))
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, connect); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// connect creates the clients of the plugin from the kubeconfig, like kubectl does
+func connect(conn connection) (*plugin, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = conn.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: conn.context}
+	overrides.Context.Namespace = conn.namespace
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, err
+	}
+	cl, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	kc, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin{
+		cl:        cl,
+		executor:  k8sutils.NewPodExecutor(config, kc),
+		attach:    newAttacher(config, kc),
+		namespace: namespace,
+	}, nil
+}
+
+// newAttacher returns a function which runs the command in the container with the terminal attached to it
+func newAttacher(config *rest.Config, kc kubernetes.Interface) func(namespace, podName, container string, cmd []string) error {
+	return func(namespace, podName, container string, cmd []string) error {
+		fd := int(os.Stdin.Fd())
+		tty := term.IsTerminal(fd)
+		req := kc.CoreV1().RESTClient().Post().Resource("pods").Name(podName).Namespace(namespace).SubResource("exec")
+		req.VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   cmd,
+			Stdin:     true,
+			Stdout:    true,
+			Stderr:    !tty,
+			TTY:       tty,
+		}, clientgoscheme.ParameterCodec)
+		exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+		if err != nil {
+			return err
+		}
+		options := remotecommand.StreamOptions{Stdin: os.Stdin, Stdout: os.Stdout, Tty: tty}
+		if tty {
+			// the remote terminal echoes and edits the input, the local one passes the keys through
+			state, err := term.MakeRaw(fd)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = term.Restore(fd, state) }()
+		} else {
+			options.Stderr = os.Stderr
+		}
+		return exec.Stream(options)
+	}
+}
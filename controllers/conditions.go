@@ -0,0 +1,41 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setStatusCondition sets the condition, or removes the condition of the type if it is nil, and reports
+// whether the conditions changed so that the status has to be written
+func setStatusCondition(conditions *[]metav1.Condition, conditionType string, condition *metav1.Condition) bool {
+	existing := meta.FindStatusCondition(*conditions, conditionType)
+	if condition == nil {
+		if existing == nil {
+			return false
+		}
+		meta.RemoveStatusCondition(conditions, conditionType)
+		return true
+	}
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+		return false
+	}
+	meta.SetStatusCondition(conditions, *condition)
+	return true
+}
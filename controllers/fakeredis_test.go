@@ -0,0 +1,549 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"redis-operator/k8sutils"
+)
+
+// fakeRedisNode is the state of a single redis server in the fake cluster
+type fakeRedisNode struct {
+	id        string
+	ip        string
+	namespace string
+	podName   string
+	// joined is set once the node was made part of the cluster by redis-cli
+	joined   bool
+	masterID string
+	slots    string
+	failed   bool
+	keys     int
+	resets   int
+	config   map[string]string
+	// usedMemory is reported as used_memory by INFO memory
+	usedMemory int64
+	// data are the keys returned by SCAN, they count towards DBSIZE next to keys
+	data map[string]fakeRedisKey
+}
+
+// fakeRedisKey is a key as reported by TYPE, MEMORY USAGE and TTL
+type fakeRedisKey struct {
+	keyType string
+	bytes   int
+	ttl     int
+}
+
+// fakeRedisCluster is an in-process stand-in for the redis pods. Every pod gets its own
+// loopback address on which a small RESP server answers the commands the operator sends,
+// and it implements k8sutils.PodExecutor for the redis-cli commands run inside the pods.
+type fakeRedisCluster struct {
+	mu        sync.Mutex
+	nodes     map[string]*fakeRedisNode
+	pods      map[string]string
+	listeners []net.Listener
+	nextIP    int
+}
+
+func newFakeRedisCluster() *fakeRedisCluster {
+	return &fakeRedisCluster{
+		nodes: map[string]*fakeRedisNode{},
+		pods:  map[string]string{},
+	}
+}
+
+// podIP returns the loopback address of the redis server of the pod, starting the server
+// on first use. This is the pod IP shim: the operator dials <pod IP>:6379 as it would in a cluster.
+func (f *fakeRedisCluster) podIP(namespace, podName string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ip, ok := f.pods[namespace+"/"+podName]; ok {
+		return ip, nil
+	}
+	f.nextIP++
+	ip := fmt.Sprintf("127.0.%d.%d", 1+f.nextIP/250, 1+f.nextIP%250)
+	listener, err := net.Listen("tcp", ip+":6379")
+	if err != nil {
+		return "", err
+	}
+	node := &fakeRedisNode{
+		id:        randomNodeID(),
+		ip:        ip,
+		namespace: namespace,
+		podName:   podName,
+		config:    map[string]string{},
+	}
+	f.nodes[ip] = node
+	f.pods[namespace+"/"+podName] = ip
+	f.listeners = append(f.listeners, listener)
+	go f.serve(listener, node)
+	return ip, nil
+}
+
+// close stops all the redis servers
+func (f *fakeRedisCluster) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, listener := range f.listeners {
+		listener.Close()
+	}
+}
+
+// node returns a copy of the state of the pod's redis server
+func (f *fakeRedisCluster) node(namespace, podName string) (fakeRedisNode, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	node, ok := f.nodes[f.pods[namespace+"/"+podName]]
+	if !ok {
+		return fakeRedisNode{}, false
+	}
+	return *node, true
+}
+
+// update changes the state of all redis servers of the namespace
+func (f *fakeRedisCluster) update(namespace string, fn func(*fakeRedisNode)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, node := range f.nodes {
+		if node.namespace == namespace {
+			fn(node)
+		}
+	}
+}
+
+// members returns the number of masters and replicas which joined the cluster in the namespace
+func (f *fakeRedisCluster) members(namespace string) (masters int, replicas int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, node := range f.nodes {
+		if node.namespace != namespace || !node.joined {
+			continue
+		}
+		if node.masterID == "" {
+			masters++
+		} else {
+			replicas++
+		}
+	}
+	return masters, replicas
+}
+
+// Exec implements k8sutils.PodExecutor for the redis-cli invocations of the operator
+func (f *fakeRedisCluster) Exec(namespace, podName, container string, cmd []string) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	args := cmd[1:]
+	switch {
+	case len(args) > 2 && args[0] == "--cluster" && args[1] == "create":
+		var masters []*fakeRedisNode
+		for _, addr := range args[2:] {
+			if strings.HasPrefix(addr, "--") {
+				break
+			}
+			node, err := f.resolve(namespace, addr)
+			if err != nil {
+				return "", err.Error(), err
+			}
+			masters = append(masters, node)
+		}
+		for i, node := range masters {
+			node.joined = true
+			node.masterID = ""
+			node.slots = fmt.Sprintf("%d-%d", i*16384/len(masters), (i+1)*16384/len(masters)-1)
+		}
+		return "[OK] All 16384 slots covered.", "", nil
+	case len(args) > 3 && args[0] == "--cluster" && args[1] == "add-node":
+		replica, err := f.resolve(namespace, args[2])
+		if err != nil {
+			return "", err.Error(), err
+		}
+		master, err := f.resolve(namespace, args[3])
+		if err != nil {
+			return "", err.Error(), err
+		}
+		replica.joined = true
+		replica.masterID = master.id
+		for i := 4; i < len(args)-1; i++ {
+			if args[i] == "--cluster-master-id" {
+				replica.masterID = args[i+1]
+			}
+		}
+		return "[OK] New node added correctly.", "", nil
+	case len(args) > 1 && strings.EqualFold(args[0], "cluster") && strings.EqualFold(args[1], "addslots"):
+		node, err := f.resolve(namespace, podName)
+		if err != nil {
+			return "", err.Error(), err
+		}
+		node.joined = true
+		node.slots = "0-16383"
+		return "OK", "", nil
+	}
+	err := fmt.Errorf("unsupported command %v", cmd)
+	return "", err.Error(), err
+}
+
+// resolve finds the node of an address, which is either <pod IP>:6379 or <pod>.<service>.<namespace>.svc:6379
+func (f *fakeRedisCluster) resolve(namespace, addr string) (*fakeRedisNode, error) {
+	host := strings.TrimSuffix(addr, ":6379")
+	if node, ok := f.nodes[host]; ok {
+		return node, nil
+	}
+	parts := strings.Split(host, ".")
+	if len(parts) > 2 {
+		namespace = parts[2]
+	}
+	if node, ok := f.nodes[f.pods[namespace+"/"+parts[0]]]; ok {
+		return node, nil
+	}
+	return nil, fmt.Errorf("unknown redis node %s", addr)
+}
+
+// clusterNodes renders the CLUSTER NODES output as seen by the node
+func (f *fakeRedisCluster) clusterNodes(self *fakeRedisNode) string {
+	var view []*fakeRedisNode
+	if self.joined {
+		for _, node := range f.nodes {
+			if node.joined && node.namespace == self.namespace {
+				view = append(view, node)
+			}
+		}
+	} else {
+		view = append(view, self)
+	}
+	sort.Slice(view, func(i, j int) bool { return view[i].podName < view[j].podName })
+
+	var out strings.Builder
+	for _, node := range view {
+		flags, master, link := "master", "-", "connected"
+		if node.masterID != "" {
+			flags, master = "slave", node.masterID
+		}
+		if node == self {
+			flags = "myself," + flags
+		}
+		if node.failed {
+			flags, link = flags+",fail", "disconnected"
+		}
+		fmt.Fprintf(&out, "%s %s:6379@16379 %s %s 0 0 1 %s", node.id, node.ip, flags, master, link)
+		if node.slots != "" {
+			out.WriteString(" " + node.slots)
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// clusterSlots renders the CLUSTER SLOTS output, the masters followed by their replicas
+func (f *fakeRedisCluster) clusterSlots(self *fakeRedisNode) []interface{} {
+	reply := []interface{}{}
+	if !self.joined {
+		return reply
+	}
+	var masters []*fakeRedisNode
+	for _, node := range f.nodes {
+		if node.joined && node.namespace == self.namespace && node.masterID == "" && node.slots != "" {
+			masters = append(masters, node)
+		}
+	}
+	sort.Slice(masters, func(i, j int) bool { return masters[i].slots < masters[j].slots })
+	for _, master := range masters {
+		bounds := strings.SplitN(master.slots, "-", 2)
+		start, _ := strconv.Atoi(bounds[0])
+		end := start
+		if len(bounds) == 2 {
+			end, _ = strconv.Atoi(bounds[1])
+		}
+		entry := []interface{}{start, end, []interface{}{master.ip, 6379, master.id}}
+		for _, node := range f.nodes {
+			if node.joined && node.masterID == master.id {
+				entry = append(entry, []interface{}{node.ip, 6379, node.id})
+			}
+		}
+		reply = append(reply, entry)
+	}
+	return reply
+}
+
+// handle executes a single command against the node
+func (f *fakeRedisCluster) handle(node *fakeRedisNode, args []string) interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	command := strings.ToUpper(args[0])
+	if (command == "CLUSTER" || command == "CONFIG" || command == "MEMORY") && len(args) > 1 {
+		command += " " + strings.ToUpper(args[1])
+	}
+	switch command {
+	case "PING":
+		return respStatus("PONG")
+	case "AUTH", "SELECT", "CLIENT SETNAME":
+		return respStatus("OK")
+	case "FLUSHALL", "FLUSHDB":
+		node.keys, node.data = 0, nil
+		return respStatus("OK")
+	case "DBSIZE":
+		return node.keys + len(node.data)
+	case "SCAN":
+		return scanKeys(node.data, args[1:])
+	case "TYPE":
+		if key, ok := node.data[args[1]]; ok {
+			return respStatus(key.keyType)
+		}
+		return respStatus("none")
+	case "MEMORY USAGE":
+		if key, ok := node.data[args[2]]; ok {
+			return key.bytes
+		}
+		return nil
+	case "TTL":
+		if key, ok := node.data[args[1]]; ok {
+			return key.ttl
+		}
+		return -2
+	case "UNLINK", "DEL":
+		for _, key := range args[2:] {
+			if k8sutils.Slot(key) != k8sutils.Slot(args[1]) {
+				return respError("CROSSSLOT Keys in request don't hash to the same slot")
+			}
+		}
+		removed := 0
+		for _, key := range args[1:] {
+			if _, ok := node.data[key]; ok {
+				delete(node.data, key)
+				removed++
+			}
+		}
+		return removed
+	case "CLUSTER SLOTS":
+		return f.clusterSlots(node)
+	case "CLUSTER COUNTKEYSINSLOT":
+		slot, _ := strconv.Atoi(args[2])
+		count := 0
+		for key := range node.data {
+			if k8sutils.Slot(key) == slot {
+				count++
+			}
+		}
+		return count
+	case "CLUSTER NODES":
+		return f.clusterNodes(node)
+	case "CLUSTER RESET":
+		node.resets++
+		if node.keys+len(node.data) > 0 && node.masterID == "" {
+			return respError("ERR CLUSTER RESET can't be called with master nodes containing keys")
+		}
+		node.joined, node.masterID, node.slots, node.failed = false, "", "", false
+		return respStatus("OK")
+	case "CLUSTER MYID":
+		return node.id
+	case "CLUSTER REPLICATE":
+		if len(args) < 3 {
+			return respError("ERR wrong number of arguments for 'cluster|replicate' command")
+		}
+		if !node.joined {
+			return respError("ERR Unknown node " + args[2])
+		}
+		node.masterID, node.slots = args[2], ""
+		return respStatus("OK")
+	case "CONFIG SET":
+		if len(args) < 4 {
+			return respError("ERR wrong number of arguments for 'config|set' command")
+		}
+		node.config[strings.ToLower(args[2])] = args[3]
+		return respStatus("OK")
+	case "INFO":
+		return fmt.Sprintf("# Memory\r\nused_memory:%d\r\nmaxmemory:%s\r\nmaxmemory_policy:%s\r\n",
+			node.usedMemory, configOrDefault(node.config, "maxmemory", "0"), configOrDefault(node.config, "maxmemory-policy", "noeviction"))
+	case "CONFIG GET":
+		if len(args) < 3 {
+			return respError("ERR wrong number of arguments for 'config|get' command")
+		}
+		key := strings.ToLower(args[2])
+		if value, ok := node.config[key]; ok {
+			return []string{key, value}
+		}
+		return []string{}
+	}
+	return respError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+}
+
+// serve answers the RESP requests on the listener
+func (f *fakeRedisCluster) serve(listener net.Listener, node *fakeRedisNode) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for {
+				args, err := readRESPCommand(reader)
+				if err != nil {
+					return
+				}
+				if len(args) == 0 {
+					continue
+				}
+				if _, err := io.WriteString(conn, encodeRESP(f.handle(node, args))); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// scanKeys implements SCAN over the keys ordered by their hash, the cursor is the hash of the next key
+// plus one. Like in redis the keys which exist during the whole scan are returned even if others are deleted.
+func scanKeys(data map[string]fakeRedisKey, args []string) interface{} {
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return respError("ERR invalid cursor")
+	}
+	match, count := "*", 10
+	for i := 1; i < len(args)-1; i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			match = args[i+1]
+		case "COUNT":
+			count, _ = strconv.Atoi(args[i+1])
+		}
+	}
+	hash := func(key string) uint64 {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		return uint64(h.Sum32())
+	}
+	var keys []string
+	for key := range data {
+		if cursor == 0 || hash(key) >= cursor-1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return hash(keys[i]) < hash(keys[j]) })
+
+	next := uint64(0)
+	if len(keys) > count {
+		next = hash(keys[count]) + 1
+		keys = keys[:count]
+	}
+	matched := []string{}
+	for _, key := range keys {
+		if ok, _ := path.Match(match, key); ok {
+			matched = append(matched, key)
+		}
+	}
+	return []interface{}{strconv.FormatUint(next, 10), matched}
+}
+
+// configOrDefault returns the parameter set with CONFIG SET or its default
+func configOrDefault(config map[string]string, key, value string) string {
+	if v, ok := config[key]; ok {
+		return v
+	}
+	return value
+}
+
+type respStatus string
+
+type respError string
+
+// readRESPCommand reads an array of bulk strings from the connection
+func readRESPCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readRESPLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		header, err := readRESPLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(header, "$"))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readRESPLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// encodeRESP encodes a reply of the fake server
+func encodeRESP(reply interface{}) string {
+	switch v := reply.(type) {
+	case respStatus:
+		return "+" + string(v) + "\r\n"
+	case respError:
+		return "-" + string(v) + "\r\n"
+	case int:
+		return ":" + strconv.Itoa(v) + "\r\n"
+	case string:
+		return "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+	case []string:
+		out := "*" + strconv.Itoa(len(v)) + "\r\n"
+		for _, item := range v {
+			out += encodeRESP(item)
+		}
+		return out
+	case []interface{}:
+		out := "*" + strconv.Itoa(len(v)) + "\r\n"
+		for _, item := range v {
+			out += encodeRESP(item)
+		}
+		return out
+	}
+	return "$-1\r\n"
+}
+
+func randomNodeID() string {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeNodeZones are the zones of the nodes created for the suite, pod N of every statefulset
+// is scheduled on node N modulo the zone count, so leader N and follower N share a zone
+var fakeNodeZones = []string{"zone-a", "zone-b", "zone-c"}
+
+// fakeNodeName returns the node the pod with the ordinal is scheduled on
+func fakeNodeName(ordinal int) string {
+	return "redis-test-node-" + strconv.Itoa(ordinal%len(fakeNodeZones))
+}
+
+// createFakeNodes registers the nodes the fake pods are scheduled on
+func createFakeNodes(ctx context.Context, cl client.Client) error {
+	for i, zone := range fakeNodeZones {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   fakeNodeName(i),
+			Labels: map[string]string{corev1.LabelTopologyZone: zone, corev1.LabelHostname: fakeNodeName(i)},
+		}}
+		if err := cl.Create(ctx, node); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// fakeStatefulSetReconciler plays the statefulset controller, the scheduler and the kubelet,
+// which do not run in envtest: it creates the pods of every statefulset on the fake nodes, gives
+// them the address of a fake redis server as pod IP and reports them as ready.
+type fakeStatefulSetReconciler struct {
+	client.Client
+	Redis *fakeRedisCluster
+}
+
+func (r *fakeStatefulSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	sts := &appsv1.StatefulSet{}
+	if err := r.Get(ctx, req.NamespacedName, sts); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if sts.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+	replicas := 1
+	if sts.Spec.Replicas != nil {
+		replicas = int(*sts.Spec.Replicas)
+	}
+
+	for i := 0; i < replicas; i++ {
+		if err := r.ensurePod(ctx, sts, i); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(sts.Namespace), client.MatchingLabels(sts.Spec.Selector.MatchLabels)); err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range pods.Items {
+		ordinal, err := strconv.Atoi(strings.TrimPrefix(pods.Items[i].Name, sts.Name+"-"))
+		if err == nil && ordinal >= replicas {
+			if err := r.Delete(ctx, &pods.Items[i]); client.IgnoreNotFound(err) != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	sts.Status.ObservedGeneration = sts.Generation
+	sts.Status.Replicas = int32(replicas)
+	sts.Status.ReadyReplicas = int32(replicas)
+	sts.Status.CurrentReplicas = int32(replicas)
+	sts.Status.UpdatedReplicas = int32(replicas)
+	return ctrl.Result{}, r.Status().Update(ctx, sts)
+}
+
+// ensurePod creates the pod from the template of the statefulset and marks it as running
+func (r *fakeStatefulSetReconciler) ensurePod(ctx context.Context, sts *appsv1.StatefulSet, ordinal int) error {
+	name := sts.Name + "-" + strconv.Itoa(ordinal)
+	pod := &corev1.Pod{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: sts.Namespace, Name: name}, pod)
+	if errors.IsNotFound(err) {
+		trueVar := true
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   sts.Namespace,
+				Labels:      sts.Spec.Template.Labels,
+				Annotations: sts.Spec.Template.Annotations,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "StatefulSet",
+					Name:       sts.Name,
+					UID:        sts.UID,
+					Controller: &trueVar,
+				}},
+			},
+			Spec: *sts.Spec.Template.Spec.DeepCopy(),
+		}
+		pod.Spec.NodeName = fakeNodeName(ordinal)
+		if err := r.Create(ctx, pod); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if pod.Status.PodIP != "" {
+		return nil
+	}
+
+	ip, err := r.Redis.podIP(pod.Namespace, pod.Name)
+	if err != nil {
+		return err
+	}
+	pod.Status = corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		PodIP: ip,
+		Conditions: []corev1.PodCondition{{
+			Type:   corev1.PodReady,
+			Status: corev1.ConditionTrue,
+		}},
+	}
+	return r.Status().Update(ctx, pod)
+}
+
+func (r *fakeStatefulSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&appsv1.StatefulSet{}).
+		Owns(&corev1.Pod{}).
+		Complete(r)
+}
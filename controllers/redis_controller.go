@@ -23,10 +23,16 @@ import (
 	"redis-operator/k8sutils"
 
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	redisv1beta1 "redis-operator/api/v1beta1"
 )
@@ -34,10 +40,24 @@ import (
 // RedisReconciler reconciles a Redis object
 type RedisReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// ResyncPeriod is the interval of the periodic health check reconcile
+	ResyncPeriod time.Duration
 }
 
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=redis,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=redis/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=redis/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=pods;pods/exec;services;configmaps;secrets;persistentvolumes;persistentvolumeclaims;events,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=statefulsets;deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors;prometheusrules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+
 // Reconcile is part of the main kubernetes reconciliation loop which aims
 func (r *RedisReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reqLogger := r.Log.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
@@ -51,9 +71,9 @@ func (r *RedisReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		}
 		return ctrl.Result{}, err
 	}
-	if _, found := instance.ObjectMeta.GetAnnotations()["redis.opstreelabs.in/skip-reconcile"]; found {
+	if _, found := instance.ObjectMeta.GetAnnotations()[k8sutils.RedisSkipReconcileAnnotation]; found {
 		reqLogger.Info("Found annotations redis.opstreelabs.in/skip-reconcile, so skipping reconcile")
-		return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		return resyncAfter(r.ResyncPeriod), nil
 	}
 	if err := k8sutils.HandleRedisFinalizer(instance, r.Client); err != nil {
 		return ctrl.Result{}, err
@@ -63,22 +83,54 @@ func (r *RedisReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, err
 	}
 
-	err = k8sutils.CreateStandaloneRedis(instance)
+	err = k8sutils.CreateStandaloneRedis(instance, r.Client, r.Recorder)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	err = k8sutils.CreateStandaloneService(instance, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	err = k8sutils.ReconcileStandalonePodDisruptionBudget(instance, r.Client)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
-	err = k8sutils.CreateStandaloneService(instance)
+	err = k8sutils.ReconcileRedisMonitoring(instance, r.Client)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
 
-	reqLogger.Info("Will reconcile redis operator in again 10 seconds")
-	return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+	reqLogger.Info("Will reconcile redis operator again after the resync period")
+	return resyncAfter(r.ResyncPeriod), nil
+}
+
+// updateStatus writes the memory usage and the loaded modules of the redis pod and the progress of the storage
+// expansion into the status
+func (r *RedisReconciler) updateStatus(ctx context.Context, instance *redisv1beta1.Redis) error {
+	memory := k8sutils.ReconcileRedisMemory(instance, r.Client, r.Recorder)
+	modules := k8sutils.ReconcileRedisModules(instance, r.Client, r.Recorder)
+	storageCondition := k8sutils.ReconcileRedisStorage(instance, r.Client, r.Recorder)
+	conditionsChanged := setStatusCondition(&instance.Status.Conditions, redisv1beta1.ConditionStorageResized, storageCondition)
+	if !conditionsChanged && apiequality.Semantic.DeepEqual(memory, instance.Status.Memory) && apiequality.Semantic.DeepEqual(modules, instance.Status.Modules) {
+		return nil
+	}
+	instance.Status.Memory = memory
+	instance.Status.Modules = modules
+	return r.Client.Status().Update(ctx, instance)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RedisReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&redisv1beta1.Redis{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, labelsToRequest(k8sutils.RedisNameFromLabels)).
+		Watches(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, labelsToRequest(k8sutils.RedisNameFromLabels)).
 		Complete(r)
 }
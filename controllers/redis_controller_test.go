@@ -0,0 +1,127 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"redis-operator/k8sutils"
+)
+
+// createTestNamespace creates a namespace with a generated name, envtest never deletes namespaces
+// so every spec gets its own one
+func createTestNamespace() string {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "redis-test-"}}
+	Expect(k8sClient.Create(context.TODO(), ns)).To(Succeed())
+	return ns.Name
+}
+
+// getter returns a function for Eventually which reports the error of fetching the object
+func getter(namespace, name string, obj client.Object) func() error {
+	return func() error {
+		return k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, obj)
+	}
+}
+
+var _ = Describe("Redis controller", func() {
+	var namespace string
+
+	BeforeEach(func() {
+		namespace = createTestNamespace()
+	})
+
+	It("creates the statefulset and services and cleans them up on deletion", func() {
+		redis := &redisv1beta1.Redis{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: namespace},
+			Spec: redisv1beta1.RedisSpec{
+				KubernetesConfig: redisv1beta1.KubernetesConfig{Image: "quay.io/opstree/redis:v7.0.5"},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), redis)).To(Succeed())
+
+		By("creating the statefulset and services")
+		Eventually(getter(namespace, "redis", &appsv1.StatefulSet{}), timeout, interval).Should(Succeed())
+		Eventually(getter(namespace, "redis", &corev1.Service{}), timeout, interval).Should(Succeed())
+		Eventually(getter(namespace, "redis-headless", &corev1.Service{}), timeout, interval).Should(Succeed())
+		Eventually(func() bool {
+			stored := &redisv1beta1.Redis{}
+			if err := getter(namespace, "redis", stored)(); err != nil {
+				return false
+			}
+			return controllerutil.ContainsFinalizer(stored, k8sutils.RedisFinalizer)
+		}, timeout, interval).Should(BeTrue())
+
+		By("recreating a deleted service")
+		Expect(k8sClient.Delete(context.TODO(), &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: namespace}})).To(Succeed())
+		Eventually(getter(namespace, "redis", &corev1.Service{}), timeout, interval).Should(Succeed())
+
+		By("running the finalizer on deletion")
+		Expect(k8sClient.Delete(context.TODO(), redis)).To(Succeed())
+		Eventually(func() bool {
+			return errors.IsNotFound(getter(namespace, "redis", &redisv1beta1.Redis{})())
+		}, timeout, interval).Should(BeTrue())
+		Expect(errors.IsNotFound(getter(namespace, "redis", &appsv1.StatefulSet{})())).To(BeTrue())
+		Expect(errors.IsNotFound(getter(namespace, "redis", &corev1.Service{})())).To(BeTrue())
+		Expect(errors.IsNotFound(getter(namespace, "redis-headless", &corev1.Service{})())).To(BeTrue())
+	})
+
+	It("configures maxmemory and reports the memory usage", func() {
+		limit := resource.MustParse("1Gi")
+		percent := int32(50)
+		redis := &redisv1beta1.Redis{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: namespace},
+			Spec: redisv1beta1.RedisSpec{
+				KubernetesConfig: redisv1beta1.KubernetesConfig{
+					Image:     "quay.io/opstree/redis:v7.0.5",
+					Resources: &corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: limit}},
+				},
+				Memory: &redisv1beta1.RedisMemory{MaxMemoryPercent: &percent, EvictionPolicy: "allkeys-lru"},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), redis)).To(Succeed())
+
+		By("setting maxmemory to half of the container limit")
+		Eventually(func() map[string]string {
+			node, _ := fakeRedis.node(namespace, "redis-0")
+			return node.config
+		}, timeout, interval).Should(And(HaveKeyWithValue("maxmemory", "536870912"), HaveKeyWithValue("maxmemory-policy", "allkeys-lru")))
+
+		By("reporting a pod near its maxmemory")
+		fakeRedis.update(namespace, func(node *fakeRedisNode) {
+			node.usedMemory = 500 * 1024 * 1024
+		})
+		Eventually(func() []redisv1beta1.RedisMemoryStatus {
+			stored := &redisv1beta1.Redis{}
+			if err := getter(namespace, "redis", stored)(); err != nil {
+				return nil
+			}
+			return stored.Status.Memory
+		}, timeout, interval).Should(ConsistOf(redisv1beta1.RedisMemoryStatus{PodName: "redis-0", MaxMemory: 536870912, UsedPercent: 97, NearLimit: true}))
+	})
+})
@@ -24,10 +24,16 @@ import (
 	"redis-operator/k8sutils"
 
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	redisv1beta1 "redis-operator/api/v1beta1"
 )
@@ -35,10 +41,26 @@ import (
 // RedisClusterReconciler reconciles a RedisCluster object
 type RedisClusterReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// PodExecutor runs the redis-cli cluster commands inside the redis pods
+	PodExecutor k8sutils.PodExecutor
+	// ResyncPeriod is the interval of the periodic health check reconcile
+	ResyncPeriod time.Duration
 }
 
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=redisclusters,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=redisclusters/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=redisclusters/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=pods;pods/exec;services;configmaps;secrets;persistentvolumes;persistentvolumeclaims;events,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=statefulsets;deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors;prometheusrules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
+
 // Reconcile is part of the main kubernetes reconciliation loop
 func (r *RedisClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reqLogger := r.Log.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
@@ -55,9 +77,9 @@ func (r *RedisClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
-	if _, found := instance.ObjectMeta.GetAnnotations()["rediscluster.opstreelabs.in/skip-reconcile"]; found {
+	if _, found := instance.ObjectMeta.GetAnnotations()[k8sutils.RedisClusterSkipReconcileAnnotation]; found {
 		reqLogger.Info("Found annotations rediscluster.opstreelabs.in/skip-reconcile, so skipping reconcile")
-		return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		return resyncAfter(r.ResyncPeriod), nil
 	}
 
 	// 获得副本数量的指针
@@ -67,82 +89,104 @@ func (r *RedisClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	// 如果当前实例被标记了删除，那么删除该实例关联的service和pvc
 	if err := k8sutils.HandleRedisClusterFinalizer(instance, r.Client); err != nil {
-		return ctrl.Result{RequeueAfter: time.Second * 60}, err
+		return ctrl.Result{}, err
 	}
 
 	// 如果没有的话，给实例添加一个 Finalizer
 	if err := k8sutils.AddRedisClusterFinalizer(instance, r.Client); err != nil {
-		return ctrl.Result{RequeueAfter: time.Second * 60}, err
+		return ctrl.Result{}, err
 	}
 
 	// 创建所有的主节点
-	err = k8sutils.CreateRedisLeader(instance)
+	err = k8sutils.CreateRedisLeader(instance, r.Client, r.Recorder)
 	if err != nil {
-		return ctrl.Result{RequeueAfter: time.Second * 60}, err
+		return ctrl.Result{}, err
 	}
 	if leaderReplicas != 0 {
-		err = k8sutils.CreateRedisLeaderService(instance)
+		err = k8sutils.CreateRedisLeaderService(instance, r.Client)
 		if err != nil {
-			return ctrl.Result{RequeueAfter: time.Second * 60}, err
+			return ctrl.Result{}, err
 		}
 	}
 
-	err = k8sutils.ReconcileRedisPodDisruptionBudget(instance, "leader", instance.Spec.RedisLeader.PodDisruptionBudget)
+	err = k8sutils.ReconcileRedisPodDisruptionBudget(instance, r.Client, "leader", instance.Spec.RedisLeader.PodDisruptionBudget)
 	if err != nil {
-		return ctrl.Result{RequeueAfter: time.Second * 60}, err
+		return ctrl.Result{}, err
 	}
 
-	redisLeaderInfo, err := k8sutils.GetStatefulSet(instance.Namespace, instance.ObjectMeta.Name+"-leader")
+	redisLeaderInfo, err := k8sutils.GetStatefulSet(r.Client, instance.Namespace, instance.ObjectMeta.Name+"-leader")
 	if err != nil {
-		return ctrl.Result{RequeueAfter: time.Second * 60}, err
+		return ctrl.Result{}, err
 	}
 
 	if int32(redisLeaderInfo.Status.ReadyReplicas) == leaderReplicas {
-		err = k8sutils.CreateRedisFollower(instance)
+		err = k8sutils.CreateRedisFollower(instance, r.Client, r.Recorder)
 		if err != nil {
-			return ctrl.Result{RequeueAfter: time.Second * 60}, err
+			return ctrl.Result{}, err
 		}
 		// if we have followers create their service.
 		if followerReplicas != 0 {
-			err = k8sutils.CreateRedisFollowerService(instance)
+			err = k8sutils.CreateRedisFollowerService(instance, r.Client)
 			if err != nil {
-				return ctrl.Result{RequeueAfter: time.Second * 60}, err
+				return ctrl.Result{}, err
 			}
 		}
-		err = k8sutils.ReconcileRedisPodDisruptionBudget(instance, "follower", instance.Spec.RedisFollower.PodDisruptionBudget)
+		err = k8sutils.ReconcileRedisPodDisruptionBudget(instance, r.Client, "follower", instance.Spec.RedisFollower.PodDisruptionBudget)
 		if err != nil {
-			return ctrl.Result{RequeueAfter: time.Second * 60}, err
+			return ctrl.Result{}, err
 		}
 	}
-	redisFollowerInfo, err := k8sutils.GetStatefulSet(instance.Namespace, instance.ObjectMeta.Name+"-follower")
+	err = k8sutils.ReconcileRedisClusterMonitoring(instance, r.Client)
 	if err != nil {
-		return ctrl.Result{RequeueAfter: time.Second * 60}, err
+		return ctrl.Result{}, err
+	}
+
+	redisFollowerInfo, err := k8sutils.GetStatefulSet(r.Client, instance.Namespace, instance.ObjectMeta.Name+"-follower")
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := k8sutils.DeleteScaledDownRedisClusterPVCs(instance, r.Client, r.Recorder); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := k8sutils.CollectRedisClusterGarbage(instance, r.Client, r.Recorder); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// the PVCs are expanded one at a time and the statefulsets are recreated once all of them are resized
+	storageCondition := k8sutils.ReconcileRedisClusterStorage(instance, r.Client, r.Recorder)
+	if setStatusCondition(&instance.Status.Conditions, redisv1beta1.ConditionStorageResized, storageCondition) {
+		if err := r.Client.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
 	if leaderReplicas == 0 {
 		reqLogger.Info("Redis leaders Cannot be 0", "Ready.Replicas", strconv.Itoa(int(redisLeaderInfo.Status.ReadyReplicas)), "Expected.Replicas", leaderReplicas)
-		return ctrl.Result{RequeueAfter: time.Second * 120}, nil
+		return resyncAfter(r.ResyncPeriod), nil
 	}
 
 	if int32(redisLeaderInfo.Status.ReadyReplicas) != leaderReplicas && int32(redisFollowerInfo.Status.ReadyReplicas) != followerReplicas {
+		// the statefulset watches trigger the next reconcile once the pods become ready
 		reqLogger.Info("Redis leader and follower nodes are not ready yet", "Ready.Replicas", strconv.Itoa(int(redisLeaderInfo.Status.ReadyReplicas)), "Expected.Replicas", leaderReplicas)
-		return ctrl.Result{RequeueAfter: time.Second * 120}, nil
+		return resyncAfter(r.ResyncPeriod), nil
 	}
 
 	// 前面已经确保了pod数量是够的，剩下的就是实际的redis cluster集群的节点数量
 	reqLogger.Info("Creating redis cluster by executing cluster creation commands", "Leaders.Ready", strconv.Itoa(int(redisLeaderInfo.Status.ReadyReplicas)), "Followers.Ready", strconv.Itoa(int(redisFollowerInfo.Status.ReadyReplicas)))
-	if k8sutils.CheckRedisNodeCount(instance, "") != totalReplicas {
+	if k8sutils.CheckRedisNodeCount(instance, r.Client, r.Recorder, "") != totalReplicas {
 		// todo 这个地方通过cluster nodes获取节点数量，但只检查节点数量，不检查节点状态，失败的节点也算
-		leaderCount := k8sutils.CheckRedisNodeCount(instance, "leader")
+		leaderCount := k8sutils.CheckRedisNodeCount(instance, r.Client, r.Recorder, "leader")
 		if leaderCount != leaderReplicas {
 			// 主节点数量不够，有可能是第一次集群创建，或者是有redis中的node莫名的离开了集群（只能手动断开，因为网络分区等不会导致cluster nodes的输出减少）
 			// 接着排除手动断开的话，这个分支99%的可能是集群第一次创建的时候
 			reqLogger.Info("Not all leader are part of the cluster...", "Leaders.Count", leaderCount, "Instance.Size", leaderReplicas)
-			k8sutils.ExecuteRedisClusterCommand(instance)
+			k8sutils.ExecuteRedisClusterCommand(instance, r.Client, r.PodExecutor, r.Recorder)
 		} else {
 			if followerReplicas > 0 {
 				reqLogger.Info("All leader are part of the cluster, adding follower/replicas", "Leaders.Count", leaderCount, "Instance.Size", leaderReplicas, "Follower.Replicas", followerReplicas)
-				k8sutils.ExecuteRedisReplicationCommand(instance)
+				k8sutils.ExecuteRedisReplicationCommand(instance, r.Client, r.PodExecutor, r.Recorder)
 			} else {
 				reqLogger.Info("no follower/replicas configured, skipping replication configuration", "Leaders.Count", leaderCount, "Leader.Size", leaderReplicas, "Follower.Replicas", followerReplicas)
 			}
@@ -150,24 +194,57 @@ func (r *RedisClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	} else {
 		reqLogger.Info("Redis leader count is desired")
 		// 检查是否有flag是fail或者连接状态是disconnected
-		if k8sutils.CheckRedisClusterState(instance) >= int(totalReplicas)-1 {
+		if k8sutils.CheckRedisClusterState(instance, r.Client, r.Recorder) >= int(totalReplicas)-1 {
 			reqLogger.Info("Redis leader is not desired, executing failover operation")
 			//  这个地方判断至少是整个集群中所有节点状态都不对的情况下，才把集群铲掉重建
-			err = k8sutils.ExecuteFailoverOperation(instance)
+			err = k8sutils.ExecuteFailoverOperation(instance, r.Client, r.Recorder)
 			if err != nil {
-				return ctrl.Result{RequeueAfter: time.Second * 10}, err
+				return ctrl.Result{}, err
 			}
+		} else if followerReplicas > 0 {
+			// 节点数量正确时，也要保证每个leader都有期望数量的健康副本
+			k8sutils.ExecuteRedisReplicationCommand(instance, r.Client, r.PodExecutor, r.Recorder)
+		}
+		// the proxy is seeded by the headless service of the leaders, envoy follows failovers and resharding itself
+		if err := k8sutils.ReconcileRedisClusterProxy(instance, r.Client, r.Recorder); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.updateStatus(ctx, instance); err != nil {
+			return ctrl.Result{}, err
 		}
-		// 否则的话，等待集群调谐 2分钟一次
-		return ctrl.Result{RequeueAfter: time.Second * 120}, nil
+		// 否则的话，等待下一次周期性的健康检查
+		return resyncAfter(r.ResyncPeriod), nil
+	}
+	reqLogger.Info("Redis cluster is still converging, will reconcile again shortly")
+	return convergeAfter(r.ResyncPeriod), nil
+}
+
+// updateStatus writes the replication state of every leader, the memory usage and the loaded modules of the
+// pods into the status
+func (r *RedisClusterReconciler) updateStatus(ctx context.Context, instance *redisv1beta1.RedisCluster) error {
+	shards := k8sutils.GetRedisClusterShards(instance, r.Client, r.Recorder)
+	memory := k8sutils.ReconcileRedisClusterMemory(instance, r.Client, r.Recorder)
+	modules := k8sutils.ReconcileRedisClusterModules(instance, r.Client, r.Recorder)
+	if apiequality.Semantic.DeepEqual(shards, instance.Status.Shards) && apiequality.Semantic.DeepEqual(memory, instance.Status.Memory) &&
+		apiequality.Semantic.DeepEqual(modules, instance.Status.Modules) {
+		return nil
 	}
-	reqLogger.Info("Will reconcile redis cluster operator in again 10 seconds")
-	return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+	instance.Status.Shards = shards
+	instance.Status.Memory = memory
+	instance.Status.Modules = modules
+	return r.Client.Status().Update(ctx, instance)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RedisClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&redisv1beta1.RedisCluster{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, labelsToRequest(k8sutils.RedisClusterNameFromLabels)).
+		Watches(&source.Kind{Type: &corev1.PersistentVolumeClaim{}}, labelsToRequest(k8sutils.RedisClusterNameFromLabels)).
 		Complete(r)
 }
@@ -0,0 +1,242 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"redis-operator/k8sutils"
+)
+
+// clusterMembers returns a function for Eventually which reports the masters and replicas of the fake cluster
+func clusterMembers(namespace string) func() []int {
+	return func() []int {
+		masters, replicas := fakeRedis.members(namespace)
+		return []int{masters, replicas}
+	}
+}
+
+// shardReplicas returns a function for Eventually which reports the healthy replicas of every shard from the status
+func shardReplicas(namespace string) func() []int32 {
+	return func() []int32 {
+		cluster := &redisv1beta1.RedisCluster{}
+		if err := getter(namespace, "redis-cluster", cluster)(); err != nil {
+			return nil
+		}
+		var replicas []int32
+		for _, shard := range cluster.Status.Shards {
+			replicas = append(replicas, shard.HealthyReplicas)
+		}
+		return replicas
+	}
+}
+
+var _ = Describe("RedisCluster controller", func() {
+	var (
+		namespace string
+		cluster   *redisv1beta1.RedisCluster
+	)
+
+	BeforeEach(func() {
+		namespace = createTestNamespace()
+		size := int32(3)
+		cluster = &redisv1beta1.RedisCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: namespace},
+			Spec: redisv1beta1.RedisClusterSpec{
+				Size:             &size,
+				KubernetesConfig: redisv1beta1.KubernetesConfig{Image: "quay.io/opstree/redis:v7.0.5"},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), cluster)).To(Succeed())
+
+		By("forming the cluster out of the leaders and attaching the followers")
+		Eventually(clusterMembers(namespace), timeout, interval).Should(Equal([]int{3, 3}))
+	})
+
+	It("attaches the followers to distinct leaders", func() {
+		leaders := map[string]bool{}
+		for _, pod := range []string{"redis-cluster-follower-0", "redis-cluster-follower-1", "redis-cluster-follower-2"} {
+			node, found := fakeRedis.node(namespace, pod)
+			Expect(found).To(BeTrue())
+			leaders[node.masterID] = true
+		}
+		Expect(leaders).To(HaveLen(3))
+	})
+
+	It("attaches new followers when the followers are scaled up", func() {
+		Expect(getter(namespace, "redis-cluster", cluster)()).To(Succeed())
+		followers := int32(4)
+		cluster.Spec.RedisFollower.Replicas = &followers
+		Expect(k8sClient.Update(context.TODO(), cluster)).To(Succeed())
+
+		Eventually(func() int32 {
+			sts := &appsv1.StatefulSet{}
+			if err := getter(namespace, "redis-cluster-follower", sts)(); err != nil {
+				return 0
+			}
+			return *sts.Spec.Replicas
+		}, timeout, interval).Should(Equal(followers))
+		Eventually(clusterMembers(namespace), timeout, interval).Should(Equal([]int{3, 4}))
+	})
+
+	It("keeps replicasPerLeader healthy followers on every leader", func() {
+		Expect(getter(namespace, "redis-cluster", cluster)()).To(Succeed())
+		replicasPerLeader := int32(2)
+		cluster.Spec.ReplicasPerLeader = &replicasPerLeader
+		Expect(k8sClient.Update(context.TODO(), cluster)).To(Succeed())
+
+		Eventually(clusterMembers(namespace), timeout, interval).Should(Equal([]int{3, 6}))
+		Eventually(shardReplicas(namespace), timeout, interval).Should(Equal([]int32{2, 2, 2}))
+	})
+
+	It("reassigns orphaned followers with CLUSTER REPLICATE", func() {
+		Eventually(shardReplicas(namespace), timeout, interval).Should(Equal([]int32{1, 1, 1}))
+		fakeRedis.update(namespace, func(node *fakeRedisNode) {
+			if node.podName == "redis-cluster-follower-0" {
+				node.masterID = "0000000000000000000000000000000000000000"
+			}
+		})
+
+		Eventually(func() bool {
+			node, _ := fakeRedis.node(namespace, "redis-cluster-follower-0")
+			return node.masterID != "0000000000000000000000000000000000000000"
+		}, timeout, interval).Should(BeTrue())
+		Eventually(shardReplicas(namespace), timeout, interval).Should(Equal([]int32{1, 1, 1}))
+	})
+
+	It("keeps the shard of a follower promoted by a failover", func() {
+		Eventually(shardReplicas(namespace), timeout, interval).Should(Equal([]int32{1, 1, 1}))
+		follower, _ := fakeRedis.node(namespace, "redis-cluster-follower-0")
+		leaderID := follower.masterID
+		fakeRedis.update(namespace, func(node *fakeRedisNode) {
+			switch {
+			case node.id == leaderID:
+				node.masterID, node.slots = follower.id, ""
+			case node.id == follower.id:
+				node.masterID, node.slots = "", "0-5460"
+			}
+		})
+
+		Eventually(func() []string {
+			cluster := &redisv1beta1.RedisCluster{}
+			if err := getter(namespace, "redis-cluster", cluster)(); err != nil {
+				return nil
+			}
+			var leaders []string
+			for _, shard := range cluster.Status.Shards {
+				leaders = append(leaders, shard.Leader)
+			}
+			return leaders
+		}, timeout, interval).Should(ContainElement("redis-cluster-follower-0"))
+		Eventually(shardReplicas(namespace), timeout, interval).Should(Equal([]int32{1, 1, 1}))
+		Consistently(func() string {
+			node, _ := fakeRedis.node(namespace, "redis-cluster-follower-0")
+			return node.masterID
+		}, "2s", interval).Should(BeEmpty())
+	})
+
+	It("moves the followers out of the zone of their leader in topology aware mode", func() {
+		// follower N shares the node and zone of leader N, which is where the flat placement puts it
+		for i := 0; i < 3; i++ {
+			leader, _ := fakeRedis.node(namespace, "redis-cluster-leader-"+strconv.Itoa(i))
+			follower, _ := fakeRedis.node(namespace, "redis-cluster-follower-"+strconv.Itoa(i))
+			Expect(follower.masterID).To(Equal(leader.id))
+		}
+
+		Expect(getter(namespace, "redis-cluster", cluster)()).To(Succeed())
+		cluster.Spec.TopologyAware = &redisv1beta1.TopologyAware{Enabled: true}
+		Expect(k8sClient.Update(context.TODO(), cluster)).To(Succeed())
+
+		Eventually(func() bool {
+			for i := 0; i < 3; i++ {
+				leader, _ := fakeRedis.node(namespace, "redis-cluster-leader-"+strconv.Itoa(i))
+				follower, _ := fakeRedis.node(namespace, "redis-cluster-follower-"+strconv.Itoa(i))
+				if follower.masterID == leader.id {
+					return false
+				}
+			}
+			return true
+		}, timeout, interval).Should(BeTrue())
+		Eventually(shardReplicas(namespace), timeout, interval).Should(Equal([]int32{1, 1, 1}))
+
+		sts := &appsv1.StatefulSet{}
+		Expect(getter(namespace, "redis-cluster-follower", sts)()).To(Succeed())
+		Expect(sts.Spec.Template.Spec.TopologySpreadConstraints).To(HaveLen(2))
+	})
+
+	It("resets and recreates the cluster when the nodes failed", func() {
+		fakeRedis.update(namespace, func(node *fakeRedisNode) {
+			node.failed = true
+			// the leaders still hold data, so the operator has to flush them before the reset
+			if node.masterID == "" {
+				node.keys = 10
+			}
+		})
+
+		Eventually(func() int {
+			node, _ := fakeRedis.node(namespace, "redis-cluster-leader-0")
+			return node.resets
+		}, timeout, interval).Should(BeNumerically(">", 0))
+		Eventually(clusterMembers(namespace), timeout, interval).Should(Equal([]int{3, 3}))
+		node, _ := fakeRedis.node(namespace, "redis-cluster-leader-0")
+		Expect(node.failed).To(BeFalse())
+		Expect(node.keys).To(BeZero())
+	})
+
+	It("reports the slot layout and the pods owning the keys", func() {
+		Eventually(shardReplicas(namespace), timeout, interval).Should(Equal([]int32{1, 1, 1}))
+		handler := k8sutils.NewSlotsHandler(k8sClient)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slots?namespace="+namespace+"&cluster=redis-cluster&key=user:{42}:cart&key=user:{42}:orders", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		report := k8sutils.SlotsReport{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &report)).To(Succeed())
+		Expect(report.Slots).To(HaveLen(3))
+		Expect(report.Slots[0].Start).To(Equal(0))
+		Expect(report.Slots[2].End).To(Equal(16383))
+		for _, slots := range report.Slots {
+			Expect(slots.Master.PodName).To(HavePrefix("redis-cluster-leader-"))
+			Expect(slots.Replicas).To(HaveLen(1))
+			Expect(slots.Replicas[0].PodName).To(HavePrefix("redis-cluster-follower-"))
+		}
+		Expect(*report.SameSlot).To(BeTrue())
+		Expect(report.Keys[0].HashTag).To(Equal("42"))
+		Expect(report.Keys[0].Master).NotTo(BeNil())
+		Expect(report.Keys[0].Master.PodName).To(HavePrefix("redis-cluster-leader-"))
+	})
+
+	It("removes the statefulsets on deletion", func() {
+		Expect(k8sClient.Delete(context.TODO(), cluster)).To(Succeed())
+		Eventually(func() bool {
+			return errors.IsNotFound(getter(namespace, "redis-cluster", &redisv1beta1.RedisCluster{})())
+		}, timeout, interval).Should(BeTrue())
+		Expect(errors.IsNotFound(getter(namespace, "redis-cluster-leader", &appsv1.StatefulSet{})())).To(BeTrue())
+		Expect(errors.IsNotFound(getter(namespace, "redis-cluster-follower", &appsv1.StatefulSet{})())).To(BeTrue())
+	})
+})
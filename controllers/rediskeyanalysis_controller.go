@@ -0,0 +1,129 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"redis-operator/k8sutils"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+)
+
+// RedisKeyAnalysisReconciler reconciles a RedisKeyAnalysis object
+type RedisKeyAnalysisReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=rediskeyanalyses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=rediskeyanalyses/status,verbs=get;update;patch
+
+// Reconcile scans a batch of keys on every master per call, the progress is kept in the status so that
+// the analysis continues where it stopped after a restart of the operator
+func (r *RedisKeyAnalysisReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	reqLogger := r.Log.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
+	instance := &redisv1beta1.RedisKeyAnalysis{}
+
+	err := r.Client.Get(context.TODO(), req.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	restart := instance.Status.ObservedGeneration != instance.Generation
+	if !restart && (instance.Status.Phase == redisv1beta1.KeyAnalysisCompleted || instance.Status.Phase == redisv1beta1.KeyAnalysisFailed) {
+		return ctrl.Result{}, nil
+	}
+
+	cluster := &redisv1beta1.RedisCluster{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Namespace: req.Namespace, Name: instance.Spec.RedisCluster}, cluster)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		instance.Status = redisv1beta1.RedisKeyAnalysisStatus{
+			Phase:              redisv1beta1.KeyAnalysisFailed,
+			Message:            fmt.Sprintf("redis cluster %s not found", instance.Spec.RedisCluster),
+			ObservedGeneration: instance.Generation,
+		}
+		return ctrl.Result{}, r.Client.Status().Update(ctx, instance)
+	}
+
+	if restart || instance.Status.Phase != redisv1beta1.KeyAnalysisRunning {
+		reqLogger.Info("Starting the key analysis", "RedisCluster", cluster.Name)
+		if err := k8sutils.StartRedisKeyAnalysis(instance, cluster, r.Client, r.Recorder); err != nil {
+			// the cluster is still being formed, try again once it serves slots
+			instance.Status = redisv1beta1.RedisKeyAnalysisStatus{
+				Phase:              redisv1beta1.KeyAnalysisPending,
+				Message:            err.Error(),
+				ObservedGeneration: instance.Generation,
+			}
+			if err := r.Client.Status().Update(ctx, instance); err != nil {
+				return ctrl.Result{}, err
+			}
+			return resyncAfter(convergePeriod), nil
+		}
+		if err := r.Client.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: k8sutils.KeyScanBatchPeriod}, nil
+	}
+
+	done, scanErr := k8sutils.ScanRedisKeyAnalysis(instance, cluster, r.Client, r.Recorder)
+	if done {
+		reqLogger.Info("Scanned all masters, counting the keys of the slots")
+		scanErr = k8sutils.CompleteRedisKeyAnalysis(instance, cluster, r.Client, r.Recorder)
+	}
+	instance.Status.Message = ""
+	if scanErr != nil {
+		instance.Status.Message = scanErr.Error()
+	}
+	// the samples of the batch are kept even if a master failed, its cursor was not advanced
+	if err := r.Client.Status().Update(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if scanErr != nil {
+		return ctrl.Result{}, scanErr
+	}
+	if instance.Status.Phase == redisv1beta1.KeyAnalysisCompleted {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: k8sutils.KeyScanBatchPeriod}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Status updates are filtered out, the
+// batches are paced by the requeue period alone.
+func (r *RedisKeyAnalysisReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&redisv1beta1.RedisKeyAnalysis{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"redis-operator/k8sutils"
+)
+
+// seedFakeKeys stores the keys on the masters of the namespace which serve their slots
+func seedFakeKeys(namespace string, keys map[string]fakeRedisKey) {
+	fakeRedis.update(namespace, func(node *fakeRedisNode) {
+		bounds := strings.SplitN(node.slots, "-", 2)
+		if node.masterID != "" || len(bounds) != 2 {
+			return
+		}
+		start, _ := strconv.Atoi(bounds[0])
+		end, _ := strconv.Atoi(bounds[1])
+		node.data = map[string]fakeRedisKey{}
+		for name, key := range keys {
+			if slot := k8sutils.Slot(name); slot >= start && slot <= end {
+				node.data[name] = key
+			}
+		}
+	})
+}
+
+var _ = Describe("RedisKeyAnalysis controller", func() {
+	var namespace string
+
+	BeforeEach(func() {
+		namespace = createTestNamespace()
+		size := int32(3)
+		cluster := &redisv1beta1.RedisCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: namespace},
+			Spec: redisv1beta1.RedisClusterSpec{
+				Size:             &size,
+				KubernetesConfig: redisv1beta1.KubernetesConfig{Image: "quay.io/opstree/redis:v7.0.5"},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), cluster)).To(Succeed())
+		Eventually(clusterMembers(namespace), timeout, interval).Should(Equal([]int{3, 3}))
+	})
+
+	It("reports the biggest keys, prefixes, TTLs and slots of all masters", func() {
+		keys := map[string]fakeRedisKey{"snrs:big": {keyType: "hash", bytes: 1 << 20, ttl: -1}}
+		for i := 0; i < 40; i++ {
+			keys[fmt.Sprintf("snrs:user:%d", i)] = fakeRedisKey{keyType: "string", bytes: 100, ttl: -1}
+			keys[fmt.Sprintf("session:%d", i)] = fakeRedisKey{keyType: "string", bytes: 50, ttl: 600}
+		}
+		seedFakeKeys(namespace, keys)
+
+		keysPerSecond, topKeys := int32(10), int32(3)
+		analysis := &redisv1beta1.RedisKeyAnalysis{
+			ObjectMeta: metav1.ObjectMeta{Name: "analysis", Namespace: namespace},
+			Spec: redisv1beta1.RedisKeyAnalysisSpec{
+				RedisCluster:    "redis-cluster",
+				KeysPerSecond:   &keysPerSecond,
+				TopKeys:         &topKeys,
+				OutputConfigMap: "analysis-report",
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), analysis)).To(Succeed())
+
+		Eventually(func() string {
+			if err := getter(namespace, "analysis", analysis)(); err != nil {
+				return ""
+			}
+			return analysis.Status.Phase
+		}, timeout, interval).Should(Equal(redisv1beta1.KeyAnalysisCompleted))
+
+		Expect(analysis.Status.Masters).To(HaveLen(3))
+		Expect(analysis.Status.ScannedKeys).To(Equal(int64(81)))
+		Expect(analysis.Status.TopKeys).To(HaveLen(3))
+		Expect(analysis.Status.TopKeys[0].Key).To(Equal("snrs:big"))
+		Expect(analysis.Status.TopKeys[0].Slot).To(Equal(int32(k8sutils.Slot("snrs:big"))))
+		Expect(analysis.Status.Prefixes).To(Equal([]redisv1beta1.RedisKeyPrefixStats{
+			{Prefix: "snrs", Keys: 41, Bytes: 1<<20 + 4000},
+			{Prefix: "session", Keys: 40, Bytes: 2000},
+		}))
+		Expect(analysis.Status.TTLHistogram).To(ContainElements(
+			redisv1beta1.RedisKeyTTLBucket{Bucket: "persistent", Keys: 41},
+			redisv1beta1.RedisKeyTTLBucket{Bucket: "<1h", Keys: 40},
+		))
+		var slotKeys int64
+		for _, slot := range analysis.Status.HotSlots {
+			slotKeys += slot.Keys
+		}
+		Expect(analysis.Status.HotSlots).To(HaveLen(3))
+		Expect(slotKeys).To(BeNumerically(">=", 3))
+
+		report := &corev1.ConfigMap{}
+		Expect(getter(namespace, "analysis-report", report)()).To(Succeed())
+		Expect(report.Data).To(HaveKey("report.json"))
+		Expect(report.Data).To(HaveKey("slots.txt"))
+		Expect(report.OwnerReferences).To(HaveLen(1))
+	})
+
+	It("fails for an unknown redis cluster", func() {
+		analysis := &redisv1beta1.RedisKeyAnalysis{
+			ObjectMeta: metav1.ObjectMeta{Name: "analysis", Namespace: namespace},
+			Spec:       redisv1beta1.RedisKeyAnalysisSpec{RedisCluster: "missing"},
+		}
+		Expect(k8sClient.Create(context.TODO(), analysis)).To(Succeed())
+
+		Eventually(func() string {
+			if err := getter(namespace, "analysis", analysis)(); err != nil {
+				return ""
+			}
+			return analysis.Status.Phase
+		}, timeout, interval).Should(Equal(redisv1beta1.KeyAnalysisFailed))
+	})
+})
@@ -0,0 +1,126 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"redis-operator/k8sutils"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+)
+
+// RedisKeyCleanupReconciler reconciles a RedisKeyCleanup object
+type RedisKeyCleanupReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=rediskeycleanups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=redis.redis.opstreelabs.in,resources=rediskeycleanups/status,verbs=get;update;patch
+
+// Reconcile scans and deletes a batch of keys on every master per call, the progress is kept in the
+// status so that the cleanup continues where it stopped after a restart of the operator
+func (r *RedisKeyCleanupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	reqLogger := r.Log.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name)
+	instance := &redisv1beta1.RedisKeyCleanup{}
+
+	err := r.Client.Get(context.TODO(), req.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	restart := instance.Status.ObservedGeneration != instance.Generation
+	if !restart && (instance.Status.Phase == redisv1beta1.KeyCleanupCompleted || instance.Status.Phase == redisv1beta1.KeyCleanupFailed) {
+		return ctrl.Result{}, nil
+	}
+
+	cluster := &redisv1beta1.RedisCluster{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Namespace: req.Namespace, Name: instance.Spec.RedisCluster}, cluster)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		instance.Status = redisv1beta1.RedisKeyCleanupStatus{
+			Phase:              redisv1beta1.KeyCleanupFailed,
+			Message:            fmt.Sprintf("redis cluster %s not found", instance.Spec.RedisCluster),
+			ObservedGeneration: instance.Generation,
+		}
+		return ctrl.Result{}, r.Client.Status().Update(ctx, instance)
+	}
+
+	if restart || instance.Status.Phase != redisv1beta1.KeyCleanupRunning {
+		reqLogger.Info("Starting the key cleanup", "Pattern", instance.Spec.Pattern, "RedisCluster", cluster.Name)
+		if err := k8sutils.StartRedisKeyCleanup(instance, cluster, r.Client, r.Recorder); err != nil {
+			// the cluster is still being formed, try again once it serves slots
+			instance.Status = redisv1beta1.RedisKeyCleanupStatus{
+				Phase:              redisv1beta1.KeyCleanupPending,
+				Message:            err.Error(),
+				ObservedGeneration: instance.Generation,
+			}
+			if err := r.Client.Status().Update(ctx, instance); err != nil {
+				return ctrl.Result{}, err
+			}
+			return resyncAfter(convergePeriod), nil
+		}
+		if err := r.Client.Status().Update(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: k8sutils.KeyScanBatchPeriod}, nil
+	}
+
+	scanErr := k8sutils.ScanRedisKeyCleanup(instance, cluster, r.Client, r.Recorder)
+	instance.Status.Message = ""
+	if scanErr != nil {
+		instance.Status.Message = scanErr.Error()
+	}
+	// the counts of the batch are kept even if a master failed, its cursor was not advanced
+	if err := r.Client.Status().Update(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if scanErr != nil {
+		return ctrl.Result{}, scanErr
+	}
+	if instance.Status.Phase == redisv1beta1.KeyCleanupCompleted {
+		reqLogger.Info("Cleaned up all masters", "Matched", instance.Status.MatchedKeys, "Deleted", instance.Status.DeletedKeys)
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: k8sutils.KeyScanBatchPeriod}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Status updates are filtered out, the
+// batches are paced by the requeue period alone.
+func (r *RedisKeyCleanupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&redisv1beta1.RedisKeyCleanup{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
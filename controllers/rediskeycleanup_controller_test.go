@@ -0,0 +1,100 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+)
+
+// fakeKeyCount returns the number of keys stored on the leaders of the namespace
+func fakeKeyCount(namespace string) int {
+	count := 0
+	for i := 0; i < 3; i++ {
+		node, _ := fakeRedis.node(namespace, "redis-cluster-leader-"+strconv.Itoa(i))
+		count += len(node.data)
+	}
+	return count
+}
+
+var _ = Describe("RedisKeyCleanup controller", func() {
+	var namespace string
+
+	BeforeEach(func() {
+		namespace = createTestNamespace()
+		size := int32(3)
+		cluster := &redisv1beta1.RedisCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: namespace},
+			Spec: redisv1beta1.RedisClusterSpec{
+				Size:             &size,
+				KubernetesConfig: redisv1beta1.KubernetesConfig{Image: "quay.io/opstree/redis:v7.0.5"},
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), cluster)).To(Succeed())
+		Eventually(clusterMembers(namespace), timeout, interval).Should(Equal([]int{3, 3}))
+	})
+
+	It("counts the keys in dry run mode and deletes them afterwards", func() {
+		keys := map[string]fakeRedisKey{}
+		for i := 0; i < 50; i++ {
+			keys[fmt.Sprintf("snrs:%d", i)] = fakeRedisKey{keyType: "string", bytes: 100, ttl: -1}
+			keys[fmt.Sprintf("{snrs}:%d", i)] = fakeRedisKey{keyType: "string", bytes: 100, ttl: -1}
+			keys[fmt.Sprintf("keep:%d", i)] = fakeRedisKey{keyType: "string", bytes: 100, ttl: -1}
+		}
+		seedFakeKeys(namespace, keys)
+
+		batchSize, keysPerSecond := int32(20), int32(40)
+		cleanup := &redisv1beta1.RedisKeyCleanup{
+			ObjectMeta: metav1.ObjectMeta{Name: "cleanup", Namespace: namespace},
+			Spec: redisv1beta1.RedisKeyCleanupSpec{
+				RedisCluster:  "redis-cluster",
+				Pattern:       "*snrs*",
+				BatchSize:     &batchSize,
+				KeysPerSecond: &keysPerSecond,
+				DryRun:        true,
+			},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleanup)).To(Succeed())
+		completed := func() bool {
+			if err := getter(namespace, "cleanup", cleanup)(); err != nil {
+				return false
+			}
+			return cleanup.Status.Phase == redisv1beta1.KeyCleanupCompleted && cleanup.Status.ObservedGeneration == cleanup.Generation
+		}
+
+		By("counting the matching keys without deleting them")
+		Eventually(completed, timeout, interval).Should(BeTrue())
+		Expect(cleanup.Status.MatchedKeys).To(Equal(int64(100)))
+		Expect(cleanup.Status.DeletedKeys).To(BeZero())
+		Expect(cleanup.Status.SampleKeys).To(HaveLen(10))
+		Expect(fakeKeyCount(namespace)).To(Equal(150))
+
+		By("deleting the keys once the dry run is switched off")
+		cleanup.Spec.DryRun = false
+		Expect(k8sClient.Update(context.TODO(), cleanup)).To(Succeed())
+		Eventually(completed, timeout, interval).Should(BeTrue())
+		Expect(cleanup.Status.DeletedKeys).To(Equal(int64(100)))
+		Expect(fakeKeyCount(namespace)).To(Equal(50))
+	})
+})
@@ -0,0 +1,66 @@
+/*
+Copyright 2020 Opstree Solutions.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// DefaultResyncPeriod is the health check interval used when no resync period is configured
+	DefaultResyncPeriod = 120 * time.Second
+	// resyncJitterFactor spreads the periodic reconciles of many instances over time
+	resyncJitterFactor = 0.2
+	// convergePeriod is used while the redis cluster is still being formed, cluster membership
+	// changes are not visible to the watches so they have to be polled
+	convergePeriod = 10 * time.Second
+)
+
+// resyncAfter returns a result which requeues the request after the jittered period
+func resyncAfter(period time.Duration) ctrl.Result {
+	if period <= 0 {
+		period = DefaultResyncPeriod
+	}
+	return ctrl.Result{RequeueAfter: wait.Jitter(period, resyncJitterFactor)}
+}
+
+// convergeAfter returns the requeue result while the cluster is being formed, it never waits
+// longer than the resync period
+func convergeAfter(period time.Duration) ctrl.Result {
+	if period > 0 && period < convergePeriod {
+		return resyncAfter(period)
+	}
+	return resyncAfter(convergePeriod)
+}
+
+// labelsToRequest maps the pods and PVCs of a redis setup back to the custom resource which owns them
+func labelsToRequest(nameFromLabels func(map[string]string) (string, bool)) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		name, found := nameFromLabels(obj.GetLabels())
+		if !found {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+	})
+}
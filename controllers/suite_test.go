@@ -17,14 +17,16 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/kubernetes/scheme"
-
-	// "k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	"sigs.k8s.io/controller-runtime/pkg/envtest/printer"
@@ -37,12 +39,26 @@ import (
 
 // These tests use Ginkgo (BDD-style Go testing framework). Refer to
 // http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
+//
+// The suite runs both reconcilers against a real API server from envtest. The redis pods are
+// simulated by fakeStatefulSetReconciler and the redis servers by fakeRedisCluster, which listens
+// on 127.0.x.y loopback addresses, so the suite needs a Linux host and the envtest binaries
+// (KUBEBUILDER_ASSETS, see `make test`).
 
-// var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
+var fakeRedis *fakeRedisCluster
+var cancelManager context.CancelFunc
+
+const (
+	timeout  = time.Second * 60
+	interval = time.Millisecond * 250
+)
 
 func TestAPIs(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" && os.Getenv("USE_EXISTING_CLUSTER") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set, skipping the envtest suite")
+	}
 	RegisterFailHandler(Fail)
 
 	RunSpecsWithDefaultAndCustomReporters(t,
@@ -55,7 +71,8 @@ var _ = BeforeSuite(func() {
 
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths: []string{filepath.Join("..", "config", "crd", "bases")},
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
 	}
 
 	cfg, err := testEnv.Start()
@@ -65,19 +82,73 @@ var _ = BeforeSuite(func() {
 	err = redisv1beta1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
-	err = redisv1beta1.AddToScheme(scheme.Scheme)
-	Expect(err).NotTo(HaveOccurred())
-
 	// +kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
+	Expect(createFakeNodes(context.Background(), k8sClient)).To(Succeed())
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme.Scheme,
+		MetricsBindAddress: "0",
+	})
+	Expect(err).NotTo(HaveOccurred())
 
+	fakeRedis = newFakeRedisCluster()
+	err = (&RedisReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("Redis"),
+		Scheme:       mgr.GetScheme(),
+		Recorder:     mgr.GetEventRecorderFor("redis-controller"),
+		ResyncPeriod: time.Second * 2,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	err = (&RedisClusterReconciler{
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("RedisCluster"),
+		Scheme:       mgr.GetScheme(),
+		Recorder:     mgr.GetEventRecorderFor("rediscluster-controller"),
+		PodExecutor:  fakeRedis,
+		ResyncPeriod: time.Second * 2,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	err = (&RedisKeyAnalysisReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("RedisKeyAnalysis"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("rediskeyanalysis-controller"),
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	err = (&RedisKeyCleanupReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("RedisKeyCleanup"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("rediskeycleanup-controller"),
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+	err = (&fakeStatefulSetReconciler{
+		Client: mgr.GetClient(),
+		Redis:  fakeRedis,
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	var ctx context.Context
+	ctx, cancelManager = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(ctx)).To(Succeed())
+	}()
 }, 60)
 
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
+	if cancelManager != nil {
+		cancelManager()
+	}
+	if fakeRedis != nil {
+		fakeRedis.close()
+	}
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
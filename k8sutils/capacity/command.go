@@ -0,0 +1,120 @@
+package capacity
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// workloadFile is the format of the --file argument
+type workloadFile struct {
+	Workloads []Workload `json:"workloads"`
+}
+
+// RunCommand implements the capacity subcommand of the operator binary. It estimates the
+// workloads given by flags or a YAML file and prints the matching RedisCluster spec fields.
+func RunCommand(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("capacity", flag.ContinueOnError)
+	fs.SetOutput(out)
+	var (
+		w        Workload
+		dataType string
+		encoding string
+		file     string
+		options  Options
+	)
+	fs.StringVar(&dataType, "type", string(TypeString), "Data type of the keys: string, hash, list, set or zset.")
+	fs.StringVar(&encoding, "encoding", "", "Encoding of the values, derived from the sizes if empty.")
+	fs.Int64Var(&w.Keys, "keys", 0, "Number of keys.")
+	fs.Int64Var(&w.KeySize, "key-size", 0, "Average key length in bytes.")
+	fs.Int64Var(&w.ValueSize, "value-size", 0, "Average length of values, elements or members in bytes.")
+	fs.Int64Var(&w.FieldSize, "field-size", 0, "Average length of hash fields in bytes.")
+	fs.Int64Var(&w.Elements, "elements", 0, "Average number of elements per hash, list, set or sorted set.")
+	fs.BoolVar(&w.Expires, "expires", false, "The keys have a TTL.")
+	fs.StringVar(&file, "file", "", "YAML file with a workloads list, replaces the workload flags.")
+	fs.Var(int32Value{&options.Shards}, "shards", "Number of leaders, 1 for a standalone redis.")
+	fs.Var(int32Value{&options.FragmentationPercent}, "fragmentation-percent", "Memory added for fragmentation in percent of the data.")
+	fs.Var(int32Value{&options.MaxMemoryPercent}, "max-memory-percent", "Share of the container memory limit used for data.")
+	fs.Var(int32Value{&options.StoragePercent}, "storage-percent", "Volume size in percent of maxmemory.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	workloads := []Workload{}
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		parsed := workloadFile{}
+		if err := yaml.UnmarshalStrict(content, &parsed); err != nil {
+			return fmt.Errorf("parsing %s: %w", file, err)
+		}
+		workloads = parsed.Workloads
+	} else {
+		w.Type = DataType(dataType)
+		w.Encoding = Encoding(encoding)
+		workloads = append(workloads, w)
+	}
+
+	recommendation, err := Recommend(workloads, options)
+	if err != nil {
+		return err
+	}
+	return printRecommendation(out, workloads, recommendation)
+}
+
+// printRecommendation writes the estimates as comments followed by the spec fields
+func printRecommendation(out io.Writer, workloads []Workload, r Recommendation) error {
+	for _, w := range workloads {
+		estimate, err := EstimateWorkload(w)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "# %d %s keys (%s): %s\n", w.Keys, w.Type, estimate.Encoding, formatBytes(estimate.Bytes))
+	}
+	fmt.Fprintf(out, "# estimated data per shard: %s, maxmemory: %s\n", formatBytes(r.DataBytes), r.MaxMemory.String())
+
+	spec := map[string]interface{}{
+		"kubernetesConfig": map[string]interface{}{"resources": r.Resources},
+		"memory":           r.Memory,
+		"storage":          map[string]interface{}{"volumeClaimTemplate": map[string]interface{}{"spec": r.Storage().VolumeClaimTemplate.Spec}},
+	}
+	if r.Shards > 1 {
+		spec["clusterSize"] = r.Shards
+	}
+	content, err := yaml.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(content)
+	return err
+}
+
+func formatBytes(bytes int64) string {
+	return fmt.Sprintf("%.1fMi", float64(bytes)/mebibyte)
+}
+
+// int32Value is a flag.Value for the int32 options
+type int32Value struct {
+	p *int32
+}
+
+func (v int32Value) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return fmt.Sprint(*v.p)
+}
+
+func (v int32Value) Set(s string) error {
+	var n int32
+	if _, err := fmt.Sscan(s, &n); err != nil {
+		return err
+	}
+	*v.p = n
+	return nil
+}
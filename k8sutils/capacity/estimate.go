@@ -0,0 +1,260 @@
+// Package capacity estimates the memory a redis dataset needs and recommends the resources and
+// storage of the Redis and RedisCluster objects which hold it. The formulas follow the redis
+// 6/7 data structures on 64 bit builds with jemalloc and were checked against live servers.
+package capacity
+
+import (
+	"fmt"
+)
+
+// DataType is the redis type of the keys of a workload
+type DataType string
+
+const (
+	TypeString    DataType = "string"
+	TypeHash      DataType = "hash"
+	TypeList      DataType = "list"
+	TypeSet       DataType = "set"
+	TypeSortedSet DataType = "zset"
+)
+
+// Encoding is the internal encoding of the values, see OBJECT ENCODING
+type Encoding string
+
+const (
+	// EncodingAuto picks the encoding redis would use with its default thresholds
+	EncodingAuto Encoding = ""
+	// EncodingListpack is the compact encoding of small collections (ziplist before redis 7)
+	EncodingListpack  Encoding = "listpack"
+	EncodingHashtable Encoding = "hashtable"
+	EncodingSkiplist  Encoding = "skiplist"
+	EncodingQuicklist Encoding = "quicklist"
+	EncodingRaw       Encoding = "raw"
+)
+
+// structure sizes after the jemalloc size class rounding
+const (
+	dictEntrySize     = 32
+	redisObjectSize   = 16
+	pointerSize       = 8
+	dictSize          = 96
+	zsetSize          = 16
+	zskiplistSize     = 32
+	zskiplistNodeSize = 16
+	zskiplistLevel    = 16
+	quicklistSize     = 48
+	quicklistNodeSize = 32
+	// packHeaderSize is the header and end byte of a ziplist
+	packHeaderSize = 11
+	// maxPackSize is the list-max-ziplist-size of -2, the 8kb limit of a quicklist node
+	maxPackSize = 8192
+	// scoreEntrySize is the average size of a sorted set score in a listpack
+	scoreEntrySize = 10
+	// compactMaxEntries and compactMaxValue are the *-max-listpack-entries and *-max-listpack-value defaults
+	compactMaxEntries = 128
+	compactMaxValue   = 64
+)
+
+// Workload describes a group of keys of the same type and similar size
+type Workload struct {
+	Type     DataType `json:"type"`
+	Encoding Encoding `json:"encoding,omitempty"`
+	// Keys is the number of keys
+	Keys int64 `json:"keys"`
+	// KeySize is the average length of the key names in bytes
+	KeySize int64 `json:"keySize"`
+	// ValueSize is the average length of the string values, list elements, set and sorted set members and hash values
+	ValueSize int64 `json:"valueSize"`
+	// FieldSize is the average length of the hash fields
+	FieldSize int64 `json:"fieldSize,omitempty"`
+	// Elements is the average number of elements of a hash, list, set or sorted set
+	Elements int64 `json:"elements,omitempty"`
+	// Expires is set if the keys have a TTL
+	Expires bool `json:"expires,omitempty"`
+}
+
+// validEncodings are the encodings redis supports per type
+var validEncodings = map[DataType][]Encoding{
+	TypeString:    {EncodingRaw},
+	TypeHash:      {EncodingListpack, EncodingHashtable},
+	TypeList:      {EncodingQuicklist},
+	TypeSet:       {EncodingListpack, EncodingHashtable},
+	TypeSortedSet: {EncodingListpack, EncodingSkiplist},
+}
+
+// resolveEncoding validates the workload and returns the encoding its values use
+func resolveEncoding(w Workload) (Encoding, error) {
+	encodings, ok := validEncodings[w.Type]
+	if !ok {
+		return "", fmt.Errorf("unknown data type %q", w.Type)
+	}
+	if w.Keys < 0 || w.KeySize < 0 || w.ValueSize < 0 || w.FieldSize < 0 || w.Elements < 0 {
+		return "", fmt.Errorf("the sizes and counts of a %s workload must not be negative", w.Type)
+	}
+	if w.Encoding != EncodingAuto {
+		for _, encoding := range encodings {
+			if encoding == w.Encoding {
+				return encoding, nil
+			}
+		}
+		return "", fmt.Errorf("encoding %q is not supported for %s, use one of %v", w.Encoding, w.Type, encodings)
+	}
+	if w.Type == TypeString || w.Type == TypeList {
+		return encodings[0], nil
+	}
+	if w.Elements <= compactMaxEntries && w.ValueSize <= compactMaxValue && w.FieldSize <= compactMaxValue {
+		return EncodingListpack, nil
+	}
+	return encodings[1], nil
+}
+
+// sdsSize is the allocation of a simple dynamic string, the header grows with the length
+func sdsSize(length int64) int64 {
+	switch {
+	case length < 1<<8:
+		return length + 4
+	case length < 1<<16:
+		return length + 6
+	case length < 1<<32:
+		return length + 10
+	}
+	return length + 18
+}
+
+// bucketCount is the size of the hash table holding n entries, a power of two
+func bucketCount(n int64) int64 {
+	buckets := int64(1)
+	for buckets < n {
+		buckets <<= 1
+	}
+	return buckets
+}
+
+// packEntrySize is the size of an element in a ziplist or listpack
+func packEntrySize(length int64) int64 {
+	prevLength := int64(1)
+	if length >= 254 {
+		prevLength = 5
+	}
+	encoding := int64(1)
+	if length > 63 {
+		encoding = 2
+	}
+	if length > 16383 {
+		encoding = 5
+	}
+	return prevLength + encoding + length
+}
+
+// quicklistNodes returns the number of full nodes and the size of the last node of a list
+func quicklistNodes(elements, elementSize int64) (full int64, last int64) {
+	entry := packEntrySize(elementSize)
+	perNode := (maxPackSize - packHeaderSize) / entry
+	if perNode < 1 {
+		// a single element larger than a node gets a node of its own
+		return elements, 0
+	}
+	full = elements / perNode
+	if rest := elements % perNode; rest > 0 {
+		last = packHeaderSize + rest*entry
+	}
+	return full, last
+}
+
+// keyOverhead is the memory every key pays in the keyspace: its dict entry, name and value object
+func keyOverhead(w Workload) int64 {
+	overhead := dictEntrySize + sdsSize(w.KeySize) + redisObjectSize
+	if w.Expires {
+		overhead += dictEntrySize
+	}
+	return overhead
+}
+
+// valueSize is the memory of a single value of the workload without the key overhead
+func valueSize(w Workload, encoding Encoding) int64 {
+	n := w.Elements
+	switch w.Type {
+	case TypeString:
+		return sdsSize(w.ValueSize)
+	case TypeList:
+		full, last := quicklistNodes(n, w.ValueSize)
+		nodes := full
+		if last > 0 {
+			nodes++
+		}
+		return quicklistSize + nodes*quicklistNodeSize + full*maxPackSize + last
+	case TypeHash:
+		if encoding == EncodingListpack {
+			return packHeaderSize + n*(packEntrySize(w.FieldSize)+packEntrySize(w.ValueSize))
+		}
+		return dictSize + n*(dictEntrySize+sdsSize(w.FieldSize)+sdsSize(w.ValueSize)) + bucketCount(n)*pointerSize
+	case TypeSet:
+		if encoding == EncodingListpack {
+			return packHeaderSize + n*packEntrySize(w.ValueSize)
+		}
+		return dictSize + n*(dictEntrySize+sdsSize(w.ValueSize)) + bucketCount(n)*pointerSize
+	case TypeSortedSet:
+		if encoding == EncodingListpack {
+			return packHeaderSize + n*(packEntrySize(w.ValueSize)+scoreEntrySize)
+		}
+		size := int64(zsetSize + dictSize + zskiplistSize)
+		size += n*(dictEntrySize+sdsSize(w.ValueSize)+zskiplistNodeSize) + bucketCount(n)*pointerSize
+		// every node has a level, a quarter of them a second one and so on
+		for level := n; level >= 1; level /= 4 {
+			size += level * zskiplistLevel
+		}
+		return size
+	}
+	return 0
+}
+
+// Estimate is the memory a workload needs
+type Estimate struct {
+	Workload Workload `json:"workload"`
+	Encoding Encoding `json:"encoding"`
+	// Bytes is the memory of the keys and their values, the buckets of the keyspace are
+	// shared by all workloads and not included
+	Bytes int64 `json:"bytes"`
+}
+
+// EstimateWorkload returns the memory of the keys of a workload
+func EstimateWorkload(w Workload) (Estimate, error) {
+	encoding, err := resolveEncoding(w)
+	if err != nil {
+		return Estimate{}, err
+	}
+	return Estimate{
+		Workload: w,
+		Encoding: encoding,
+		Bytes:    w.Keys * (keyOverhead(w) + valueSize(w, encoding)),
+	}, nil
+}
+
+// EstimateMemory returns the memory a single shard needs when the workloads are spread evenly
+// over the shards, including the hash tables of the keyspace and of the expiring keys
+func EstimateMemory(workloads []Workload, shards int32) (int64, error) {
+	if shards < 1 {
+		return 0, fmt.Errorf("the number of shards must be at least 1, got %d", shards)
+	}
+	var bytes, keys, expiring int64
+	for _, w := range workloads {
+		estimate, err := EstimateWorkload(w)
+		if err != nil {
+			return 0, err
+		}
+		bytes += estimate.Bytes
+		keys += w.Keys
+		if w.Expires {
+			expiring += w.Keys
+		}
+	}
+	perShard := ceilDiv(bytes, int64(shards)) + bucketCount(ceilDiv(keys, int64(shards)))*pointerSize
+	if expiring > 0 {
+		perShard += bucketCount(ceilDiv(expiring, int64(shards))) * pointerSize
+	}
+	return perShard, nil
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,88 @@
+package capacity
+
+import (
+	"testing"
+)
+
+func TestEstimateMemory(t *testing.T) {
+	var tests = []struct {
+		name     string
+		workload Workload
+		want     int64
+	}{
+		// (32 + 16 + key_SDS + val_SDS) * keys + key_buckets * 8
+		{"strings", Workload{Type: TypeString, Keys: 3000000, KeySize: 12, ValueSize: 70}, 447554432},
+		// [32 + key_SDS + 16 + 96 + (32 + field_SDS + val_SDS) * fields + field_buckets * 8] * keys + key_buckets * 8
+		{"hashes", Workload{Type: TypeHash, Keys: 1000, KeySize: 15, FieldSize: 18, ValueSize: 70, Elements: 30000}, 4102315192},
+		// 32 + key_SDS + 16 + 96 + (32 + val_SDS) * members + member_buckets * 8 + key_buckets * 8
+		{"set", Workload{Type: TypeSet, Keys: 1, KeySize: 6, ValueSize: 70, Elements: 30000000}, 3448435618},
+		// dictEntry + key_SDS + robj + zset + dict + zskiplist + (dictEntry + member_SDS + node) * n + buckets + levels * 16
+		{"sorted set", Workload{Type: TypeSortedSet, Keys: 1, KeySize: 6, ValueSize: 70, Elements: 30000000}, 4568435586},
+		// 32 + key_SDS + 16 + 48 + quicklist.len * 32 + full nodes * 8192 + last node
+		{"list", Workload{Type: TypeList, Keys: 1, KeySize: 6, ValueSize: 70, Elements: 30000000}, 2202857293},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EstimateMemory([]Workload{tt.workload}, 1)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateMemoryShards(t *testing.T) {
+	workloads := []Workload{
+		{Type: TypeString, Keys: 3000000, KeySize: 12, ValueSize: 70, Expires: true},
+		{Type: TypeHash, Keys: 1000, KeySize: 15, FieldSize: 10, ValueSize: 10, Elements: 20},
+	}
+	single, err := EstimateMemory(workloads, 1)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	sharded, err := EstimateMemory(workloads, 3)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if sharded >= single/2 || sharded <= single/4 {
+		t.Errorf("got %d per shard for %d in total", sharded, single)
+	}
+	if _, err := EstimateMemory(workloads, 0); err == nil {
+		t.Errorf("expected an error for 0 shards")
+	}
+}
+
+func TestResolveEncoding(t *testing.T) {
+	var tests = []struct {
+		workload Workload
+		want     Encoding
+		wantErr  bool
+	}{
+		{Workload{Type: TypeString}, EncodingRaw, false},
+		{Workload{Type: TypeList, Elements: 10}, EncodingQuicklist, false},
+		{Workload{Type: TypeHash, Elements: 100, FieldSize: 10, ValueSize: 60}, EncodingListpack, false},
+		{Workload{Type: TypeHash, Elements: 200, FieldSize: 10, ValueSize: 60}, EncodingHashtable, false},
+		{Workload{Type: TypeSet, Elements: 10, ValueSize: 100}, EncodingHashtable, false},
+		{Workload{Type: TypeSortedSet, Elements: 1000}, EncodingSkiplist, false},
+		{Workload{Type: TypeSortedSet, Elements: 1000, Encoding: EncodingListpack}, EncodingListpack, false},
+		{Workload{Type: TypeSortedSet, Encoding: EncodingHashtable}, "", true},
+		{Workload{Type: "stream"}, "", true},
+		{Workload{Type: TypeString, Keys: -1}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.workload.Type)+"/"+string(tt.want), func(t *testing.T) {
+			got, err := resolveEncoding(tt.workload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
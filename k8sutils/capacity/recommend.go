@@ -0,0 +1,117 @@
+package capacity
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	redisv1beta1 "redis-operator/api/v1beta1"
+)
+
+const (
+	mebibyte = 1 << 20
+	gibibyte = 1 << 30
+
+	defaultFragmentationPercent = 20
+	// defaultMaxMemoryPercent matches the MEMORY_RATIO the redis images use without spec.memory
+	defaultMaxMemoryPercent = 60
+	defaultStoragePercent   = 200
+)
+
+// Options tune the recommendation, zero values use the defaults
+type Options struct {
+	// Shards is the number of leaders the data is spread over, 1 for a standalone redis
+	Shards int32
+	// FragmentationPercent is added to the estimated data for allocator fragmentation, 20 by default
+	FragmentationPercent int32
+	// MaxMemoryPercent is the share of the container memory limit redis may use for data, the rest
+	// is left for replication buffers, clients and copy on write during persistence, 60 by default
+	MaxMemoryPercent int32
+	// StoragePercent is the volume size relative to maxmemory, 200 by default so that a new
+	// snapshot or rewritten AOF fits next to the old one
+	StoragePercent int32
+}
+
+func (o Options) withDefaults() Options {
+	if o.Shards == 0 {
+		o.Shards = 1
+	}
+	if o.FragmentationPercent == 0 {
+		o.FragmentationPercent = defaultFragmentationPercent
+	}
+	if o.MaxMemoryPercent == 0 {
+		o.MaxMemoryPercent = defaultMaxMemoryPercent
+	}
+	if o.StoragePercent == 0 {
+		o.StoragePercent = defaultStoragePercent
+	}
+	return o
+}
+
+// Recommendation are the resources every redis pod of a shard should get
+type Recommendation struct {
+	Shards int32 `json:"shards"`
+	// DataBytes is the estimated memory of the dataset of a single shard
+	DataBytes int64                       `json:"dataBytes"`
+	MaxMemory resource.Quantity           `json:"maxMemory"`
+	Resources corev1.ResourceRequirements `json:"resources"`
+	Memory    redisv1beta1.RedisMemory    `json:"memory"`
+	// StorageSize is the requested size of the volume claim template
+	StorageSize resource.Quantity `json:"storageSize"`
+}
+
+// Storage returns the storage section of the recommendation
+func (r Recommendation) Storage() redisv1beta1.Storage {
+	return redisv1beta1.Storage{
+		VolumeClaimTemplate: corev1.PersistentVolumeClaim{
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: r.StorageSize},
+				},
+			},
+		},
+	}
+}
+
+// Recommend sizes the pods for the workloads, memory is rounded up to MiB and storage to GiB
+func Recommend(workloads []Workload, options Options) (Recommendation, error) {
+	options = options.withDefaults()
+	if options.FragmentationPercent < 0 {
+		return Recommendation{}, fmt.Errorf("fragmentationPercent must not be negative, got %d", options.FragmentationPercent)
+	}
+	if options.MaxMemoryPercent < 1 || options.MaxMemoryPercent > 100 {
+		return Recommendation{}, fmt.Errorf("maxMemoryPercent must be between 1 and 100, got %d", options.MaxMemoryPercent)
+	}
+	if options.StoragePercent < 0 {
+		return Recommendation{}, fmt.Errorf("storagePercent must not be negative, got %d", options.StoragePercent)
+	}
+	data, err := EstimateMemory(workloads, options.Shards)
+	if err != nil {
+		return Recommendation{}, err
+	}
+
+	maxMemory := roundUp(ceilDiv(data*int64(100+options.FragmentationPercent), 100), mebibyte)
+	limit := roundUp(ceilDiv(maxMemory*100, int64(options.MaxMemoryPercent)), mebibyte)
+	storage := roundUp(ceilDiv(maxMemory*int64(options.StoragePercent), 100), gibibyte)
+	maxMemoryPercent := options.MaxMemoryPercent
+	return Recommendation{
+		Shards:    options.Shards,
+		DataBytes: data,
+		MaxMemory: *resource.NewQuantity(maxMemory, resource.BinarySI),
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: *resource.NewQuantity(limit, resource.BinarySI)},
+			Limits:   corev1.ResourceList{corev1.ResourceMemory: *resource.NewQuantity(limit, resource.BinarySI)},
+		},
+		Memory:      redisv1beta1.RedisMemory{MaxMemoryPercent: &maxMemoryPercent},
+		StorageSize: *resource.NewQuantity(storage, resource.BinarySI),
+	}, nil
+}
+
+// roundUp rounds the bytes up to a multiple of the unit, at least one unit
+func roundUp(bytes, unit int64) int64 {
+	if bytes < unit {
+		return unit
+	}
+	return ceilDiv(bytes, unit) * unit
+}
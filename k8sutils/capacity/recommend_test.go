@@ -0,0 +1,65 @@
+package capacity
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRecommend(t *testing.T) {
+	workloads := []Workload{{Type: TypeString, Keys: 3000000, KeySize: 12, ValueSize: 70}}
+	var tests = []struct {
+		name        string
+		options     Options
+		wantMax     string
+		wantLimit   string
+		wantStorage string
+		wantErr     bool
+	}{
+		// 447554432 bytes + 20% = 512.2Mi, rounded up
+		{"defaults", Options{}, "513Mi", "855Mi", "2Gi", false},
+		// a third of the keys and a hash table of 2^20 buckets per shard
+		{"three shards", Options{Shards: 3}, "168Mi", "280Mi", "1Gi", false},
+		{"no headroom", Options{FragmentationPercent: -1}, "", "", "", true},
+		{"custom ratio", Options{MaxMemoryPercent: 75, StoragePercent: 400}, "513Mi", "684Mi", "3Gi", false},
+		{"invalid ratio", Options{MaxMemoryPercent: 101}, "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Recommend(workloads, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			limit := got.Resources.Limits[corev1.ResourceMemory]
+			if got.MaxMemory.String() != tt.wantMax || limit.String() != tt.wantLimit || got.StorageSize.String() != tt.wantStorage {
+				t.Errorf("got maxmemory %s, limit %s, storage %s", got.MaxMemory.String(), limit.String(), got.StorageSize.String())
+			}
+			if got.Memory.MaxMemoryPercent == nil || *got.Memory.MaxMemoryPercent != tt.options.withDefaults().MaxMemoryPercent {
+				t.Errorf("memory spec does not match the limit: %+v", got.Memory)
+			}
+		})
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := RunCommand([]string{"--type", "hash", "--keys", "1000", "--key-size", "15", "--field-size", "18", "--value-size", "70", "--elements", "30000", "--shards", "3"}, out)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, want := range []string{"# 1000 hash keys (hashtable)", "clusterSize: 3", "memory: 2609Mi", "maxMemoryPercent: 60", "storage: 4Gi"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := RunCommand([]string{"--type", "stream"}, &bytes.Buffer{}); err == nil {
+		t.Errorf("expected an error for an unknown type")
+	}
+}
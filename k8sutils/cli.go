@@ -0,0 +1,67 @@
+package k8sutils
+
+import (
+	"encoding/csv"
+	"strings"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Annotations which make the controllers leave a Redis or RedisCluster alone, e.g. during manual maintenance
+const (
+	RedisSkipReconcileAnnotation        = "redis.opstreelabs.in/skip-reconcile"
+	RedisClusterSkipReconcileAnnotation = "rediscluster.opstreelabs.in/skip-reconcile"
+)
+
+// RedisClusterNode is a node of the CLUSTER NODES output and the pod running it
+type RedisClusterNode struct {
+	// PodName is empty if no pod of the cluster has the IP of the node
+	PodName  string
+	ID       string
+	IP       string
+	MasterID string
+	Master   bool
+	Healthy  bool
+	Slots    string
+}
+
+// ParseRedisClusterNodes parses the CLUSTER NODES output, the nodes are matched against the pod IPs
+func ParseRedisClusterNodes(output string, podsByIP map[string]string) []RedisClusterNode {
+	csvOutput := csv.NewReader(strings.NewReader(strings.TrimSpace(output)))
+	csvOutput.Comma = ' '
+	csvOutput.FieldsPerRecord = -1
+	records, err := csvOutput.ReadAll()
+	if err != nil {
+		return nil
+	}
+	var nodes []RedisClusterNode
+	for _, node := range parseClusterNodes(records) {
+		nodes = append(nodes, RedisClusterNode{
+			PodName:  podsByIP[strings.Trim(node.IP, "[]")],
+			ID:       node.ID,
+			IP:       node.IP,
+			MasterID: node.MasterID,
+			Master:   node.Master,
+			Healthy:  node.Healthy,
+			Slots:    node.Slots,
+		})
+	}
+	return nodes
+}
+
+// GenerateRedisCLICommand returns the redis-cli command which runs the args inside the pod, authenticated
+// with the password of the ExistingPasswordSecret and with the TLS arguments of the setup
+func GenerateRedisCLICommand(cl client.Client, namespace string, kubernetesConfig redisv1beta1.KubernetesConfig, tlsConfig *redisv1beta1.TLSConfig, podName string, args ...string) ([]string, error) {
+	cmd := []string{"redis-cli"}
+	if kubernetesConfig.ExistingPasswordSecret != nil {
+		pass, err := getRedisPassword(cl, namespace, *kubernetesConfig.ExistingPasswordSecret.Name, *kubernetesConfig.ExistingPasswordSecret.Key)
+		if err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, "-a", pass, "--no-auth-warning")
+	}
+	cmd = append(cmd, getRedisTLSArgs(tlsConfig, podName)...)
+	return append(cmd, args...), nil
+}
@@ -0,0 +1,68 @@
+package k8sutils
+
+import (
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseRedisClusterNodes(t *testing.T) {
+	output := `e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 10.0.0.1:6379@16379 myself,master - 0 0 1 connected 0-8191
+67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 10.0.0.2:6379@16379 master - 0 1426238316232 2 connected 8192-16383
+292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f 10.0.0.3:6379@16379 slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 1 connected
+6ec23923021cf3ffec47632106199cb7f496ce01 10.0.0.9:6379@16379 slave,fail 67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 0 1426238316232 2 disconnected
+`
+	podsByIP := map[string]string{"10.0.0.1": "redis-cluster-leader-0", "10.0.0.2": "redis-cluster-leader-1", "10.0.0.3": "redis-cluster-follower-0"}
+	want := []RedisClusterNode{
+		{PodName: "redis-cluster-leader-0", ID: "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca", IP: "10.0.0.1", Master: true, Healthy: true, Slots: "0-8191"},
+		{PodName: "redis-cluster-leader-1", ID: "67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1", IP: "10.0.0.2", Master: true, Healthy: true, Slots: "8192-16383"},
+		{PodName: "redis-cluster-follower-0", ID: "292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f", IP: "10.0.0.3", MasterID: "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca", Healthy: true},
+		{ID: "6ec23923021cf3ffec47632106199cb7f496ce01", IP: "10.0.0.9", MasterID: "67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1"},
+	}
+	if got := ParseRedisClusterNodes(output, podsByIP); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRedisClusterNodes() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGenerateRedisCLICommand(t *testing.T) {
+	secretName, secretKey := "redis-secret", "password"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: "default"},
+		Data:       map[string][]byte{secretKey: []byte("s3cret\n")},
+	}
+	cl := newFakeClient(secret)
+	withPassword := redisv1beta1.KubernetesConfig{
+		ExistingPasswordSecret: &redisv1beta1.ExistingPasswordSecret{Name: &secretName, Key: &secretKey},
+	}
+
+	var tests = []struct {
+		name             string
+		kubernetesConfig redisv1beta1.KubernetesConfig
+		tlsConfig        *redisv1beta1.TLSConfig
+		want             []string
+	}{
+		{"plain", redisv1beta1.KubernetesConfig{}, nil, []string{"redis-cli", "CLUSTER", "NODES"}},
+		{"password", withPassword, nil, []string{"redis-cli", "-a", "s3cret", "--no-auth-warning", "CLUSTER", "NODES"}},
+		{"tls", redisv1beta1.KubernetesConfig{}, &redisv1beta1.TLSConfig{}, []string{"redis-cli", "--tls", "--cacert", "/tls/ca.crt", "-h", "redis-0", "CLUSTER", "NODES"}},
+	}
+
+	for _, tt := range tests {
+		got, err := GenerateRedisCLICommand(cl, "default", tt.kubernetesConfig, tt.tlsConfig, "redis-0", "CLUSTER", "NODES")
+		if err != nil {
+			t.Errorf("%s: GenerateRedisCLICommand() returned %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: GenerateRedisCLICommand() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	missing := "missing"
+	withPassword.ExistingPasswordSecret.Name = &missing
+	if _, err := GenerateRedisCLICommand(cl, "default", withPassword, nil, "redis-0"); err == nil {
+		t.Errorf("GenerateRedisCLICommand() with a missing secret returned no error")
+	}
+}
@@ -1,30 +1,52 @@
 package k8sutils
 
 import (
+	"bytes"
+
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
 )
 
-// generateK8sClient create client for kubernetes
-func generateK8sClient() *kubernetes.Clientset {
-	config, err := generateK8sConfig()
-	if err != nil {
-		panic(err.Error())
-	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
-	}
-	return clientset
+// PodExecutor runs commands inside the containers of redis pods
+type PodExecutor interface {
+	Exec(namespace, podName, container string, cmd []string) (stdout string, stderr string, err error)
 }
 
-// generateK8sConfig will load the kube config file
-func generateK8sConfig() (*rest.Config, error) {
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	// if you want to change the loading rules (which files in which order), you can do so here
-	configOverrides := &clientcmd.ConfigOverrides{}
-	// if you want to change override values or bind them to flags, there are methods to help you
-	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
-	return kubeConfig.ClientConfig()
+// spdyPodExecutor opens exec streams into the pods through the API server
+type spdyPodExecutor struct {
+	config *rest.Config
+	kc     kubernetes.Interface
+}
+
+// NewPodExecutor returns a PodExecutor which uses the exec subresource of the pods
+func NewPodExecutor(config *rest.Config, kc kubernetes.Interface) PodExecutor {
+	return &spdyPodExecutor{config: config, kc: kc}
+}
+
+// Exec will execute the command in the container and return its output
+func (e *spdyPodExecutor) Exec(namespace, podName, container string, cmd []string) (string, string, error) {
+	var (
+		execOut bytes.Buffer
+		execErr bytes.Buffer
+	)
+	req := e.kc.CoreV1().RESTClient().Post().Resource("pods").Name(podName).Namespace(namespace).SubResource("exec")
+	req.VersionedParams(&corev1.PodExecOptions{
+		Container: container,
+		Command:   cmd,
+		Stdout:    true,
+		Stderr:    true,
+	}, scheme.ParameterCodec)
+	exec, err := remotecommand.NewSPDYExecutor(e.config, "POST", req.URL())
+	if err != nil {
+		return "", "", err
+	}
+	err = exec.Stream(remotecommand.StreamOptions{
+		Stdout: &execOut,
+		Stderr: &execErr,
+		Tty:    false,
+	})
+	return execOut.String(), execErr.String(), err
 }
@@ -0,0 +1,46 @@
+package k8sutils
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Event reasons emitted on the custom resources of the operator
+const (
+	EventReasonClusterCreated       = "ClusterCreated"
+	EventReasonClusterCreateFailed  = "ClusterCreateFailed"
+	EventReasonReplicaAttached      = "ReplicaAttached"
+	EventReasonReplicaAttachFailed  = "ReplicaAttachFailed"
+	EventReasonFailoverExecuted     = "FailoverExecuted"
+	EventReasonFailoverFailed       = "FailoverFailed"
+	EventReasonEvictionDenied       = "EvictionDenied"
+	EventReasonPVCResized           = "PVCResized"
+	EventReasonPVCResizeFailed      = "PVCResizeFailed"
+	EventReasonPVCDeleted           = "PVCDeleted"
+	EventReasonPVCResizeStarted     = "PVCResizeStarted"
+	EventReasonStatefulSetRecreated = "StatefulSetRecreated"
+	EventReasonStaleObjectDeleted   = "StaleObjectDeleted"
+	EventReasonPasswordLookupFailed = "PasswordLookupFailed"
+	EventReasonTLSLookupFailed      = "TLSLookupFailed"
+	EventReasonMemoryConfigured     = "MemoryConfigured"
+	EventReasonMemoryConfigFailed   = "MemoryConfigFailed"
+	EventReasonMemoryConfigInvalid  = "MemoryConfigInvalid"
+	EventReasonMemoryNearLimit      = "MemoryNearLimit"
+	EventReasonModuleMissing        = "ModuleMissing"
+	EventReasonModuleConfigInvalid  = "ModuleConfigInvalid"
+	EventReasonProxyConfigured      = "ProxyConfigured"
+	EventReasonKeyAnalysisStarted   = "KeyAnalysisStarted"
+	EventReasonKeyAnalysisCompleted = "KeyAnalysisCompleted"
+	EventReasonKeyAnalysisFailed    = "KeyAnalysisFailed"
+	EventReasonKeyCleanupStarted    = "KeyCleanupStarted"
+	EventReasonKeyCleanupCompleted  = "KeyCleanupCompleted"
+	EventReasonKeyCleanupFailed     = "KeyCleanupFailed"
+)
+
+// recordEvent will emit an event on the object if a recorder is configured
+func recordEvent(recorder record.EventRecorder, obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if recorder == nil || obj == nil {
+		return
+	}
+	recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
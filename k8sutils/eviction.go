@@ -0,0 +1,205 @@
+package k8sutils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// EvictionWebhookPath is the path of the pods/eviction webhook on the webhook server of the manager
+	EvictionWebhookPath = "/validate-redis-eviction"
+	// RedisClusterAllowLastReplicaEvictionAnnotation set to "true" lets the webhook evict the last healthy
+	// replica of a shard, so that clusters with one follower per leader can be drained
+	RedisClusterAllowLastReplicaEvictionAnnotation = "rediscluster.opstreelabs.in/allow-last-replica-eviction"
+)
+
+// evictionDecision is the verdict on the eviction of a pod of a cluster
+type evictionDecision struct {
+	allowed bool
+	message string
+	// failoverPod is the replica which takes over the slots of the evicted master before it may go
+	failoverPod string
+}
+
+// decideEviction checks whether the shard of the pod keeps a healthy master and a healthy replica without it.
+// A master is only evicted after one of its replicas took over with CLUSTER FAILOVER, so that the eviction
+// does not wait for the cluster to detect the failure. With allowLastReplica the shard only has to keep a
+// healthy master or replica, so a master with a single replica is failed over and its last replica may go.
+func decideEviction(nodes []RedisClusterNode, podName string, allowLastReplica bool) evictionDecision {
+	var evicted *RedisClusterNode
+	for i := range nodes {
+		if nodes[i].PodName == podName {
+			evicted = &nodes[i]
+			break
+		}
+	}
+	if evicted == nil || !evicted.Healthy {
+		return evictionDecision{allowed: true, message: fmt.Sprintf("pod %s is not a healthy node of the cluster", podName)}
+	}
+
+	masterID := evicted.MasterID
+	if evicted.Master {
+		if evicted.Slots == "" {
+			return evictionDecision{allowed: true, message: fmt.Sprintf("pod %s is a master without slots", podName)}
+		}
+		masterID = evicted.ID
+	}
+	var master *RedisClusterNode
+	var replicas []RedisClusterNode
+	for i := range nodes {
+		if nodes[i].ID == masterID {
+			master = &nodes[i]
+		} else if nodes[i].MasterID == masterID && nodes[i].Healthy && nodes[i].PodName != podName {
+			replicas = append(replicas, nodes[i])
+		}
+	}
+
+	if !evicted.Master {
+		masterHealthy := master != nil && master.Healthy
+		switch {
+		case len(replicas) > 0 && (masterHealthy || allowLastReplica):
+			return evictionDecision{allowed: true, message: fmt.Sprintf("the shard of pod %s keeps %d healthy replicas", podName, len(replicas))}
+		case masterHealthy && allowLastReplica:
+			return evictionDecision{allowed: true, message: fmt.Sprintf("the master of replica %s stays healthy", podName)}
+		case !masterHealthy:
+			return evictionDecision{message: fmt.Sprintf("the master of replica %s is not healthy", podName)}
+		}
+		return evictionDecision{message: fmt.Sprintf("pod %s is the last healthy replica of its shard", podName)}
+	}
+	switch {
+	case len(replicas) == 0:
+		return evictionDecision{message: fmt.Sprintf("master %s has no healthy replica to take over its slots", podName)}
+	case len(replicas) == 1 && !allowLastReplica:
+		// the replica which takes over would leave the shard without a replica once the old master is evicted
+		return evictionDecision{message: fmt.Sprintf("master %s has a single healthy replica, the shard would be left without a replica", podName)}
+	}
+	if replicas[0].PodName == "" {
+		return evictionDecision{message: fmt.Sprintf("the replica %s of master %s is not a pod of the cluster", replicas[0].IP, podName)}
+	}
+	return evictionDecision{
+		message:     fmt.Sprintf("master %s is failed over to %s, retry the eviction once it became a replica", podName, replicas[0].PodName),
+		failoverPod: replicas[0].PodName,
+	}
+}
+
+// EvictionValidator is the admission handler of pods/eviction for the pods of the clusters
+type EvictionValidator struct {
+	cl       client.Client
+	recorder record.EventRecorder
+}
+
+// NewEvictionValidator returns the admission handler of the pods/eviction webhook
+func NewEvictionValidator(cl client.Client, recorder record.EventRecorder) *EvictionValidator {
+	return &EvictionValidator{cl: cl, recorder: recorder}
+}
+
+// evictionLogger will generate logging interface for the eviction webhook
+func evictionLogger(namespace string, name string) logr.Logger {
+	reqLogger := log.WithValues("Request.Eviction.Namespace", namespace, "Request.Eviction.Pod", name)
+	return reqLogger
+}
+
+// Handle rejects the eviction of a cluster pod if its shard would be left without a healthy master or replica.
+// The denials carry 429 like the ones of PodDisruptionBudgets, so that kubectl drain retries them.
+func (v *EvictionValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := evictionLogger(req.Namespace, req.Name)
+	if req.SubResource != "eviction" {
+		return admission.Allowed("not an eviction")
+	}
+	pod := &corev1.Pod{}
+	if err := v.cl.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: req.Name}, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return admission.Allowed("pod not found")
+		}
+		logger.Error(err, "Could not get the pod of the eviction")
+		return admission.Errored(http.StatusTooManyRequests, err)
+	}
+	name, ok := RedisClusterNameFromLabels(pod.Labels)
+	if !ok {
+		return admission.Allowed("not a redis cluster pod")
+	}
+	cr := &redisv1beta1.RedisCluster{}
+	if err := v.cl.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: name}, cr); err != nil {
+		if apierrors.IsNotFound(err) {
+			return admission.Allowed("redis cluster not found")
+		}
+		logger.Error(err, "Could not get the redis cluster of the pod")
+		return admission.Errored(http.StatusTooManyRequests, err)
+	}
+	if _, found := cr.GetAnnotations()[RedisClusterSkipReconcileAnnotation]; found {
+		return admission.Allowed("redis cluster is in maintenance")
+	}
+
+	nodes, err := getRedisClusterNodes(cr, v.cl, pod.Name)
+	if err != nil {
+		// the PodDisruptionBudgets still apply when the topology cannot be read
+		logger.Error(err, "Could not read the cluster topology, allowing the eviction")
+		return admission.Allowed("").WithWarnings(fmt.Sprintf("could not read the topology of redis cluster %s: %v", name, err))
+	}
+	decision := decideEviction(nodes, pod.Name, cr.GetAnnotations()[RedisClusterAllowLastReplicaEvictionAnnotation] == "true")
+	if decision.allowed {
+		logger.Info("Allowing the eviction", "Reason", decision.message)
+		return admission.Allowed(decision.message)
+	}
+	if decision.failoverPod != "" && (req.DryRun == nil || !*req.DryRun) {
+		rc := configureRedisClient(cr, v.cl, decision.failoverPod, v.recorder)
+		defer rc.Close()
+		if err := rc.ClusterFailover(ctx).Err(); err != nil {
+			logger.Error(err, "Could not fail over the master before its eviction", "Replica", decision.failoverPod)
+			recordEvent(v.recorder, cr, corev1.EventTypeWarning, EventReasonFailoverFailed, "Could not fail over %s to %s before its eviction: %v", pod.Name, decision.failoverPod, err)
+			return admission.Errored(http.StatusTooManyRequests, fmt.Errorf("could not fail over %s to %s: %v", pod.Name, decision.failoverPod, err))
+		}
+		recordEvent(v.recorder, cr, corev1.EventTypeNormal, EventReasonFailoverExecuted, "Failed over %s to %s before its eviction", pod.Name, decision.failoverPod)
+	}
+	logger.Info("Denying the eviction", "Reason", decision.message)
+	recordEvent(v.recorder, cr, corev1.EventTypeWarning, EventReasonEvictionDenied, "Eviction of %s denied: %s", pod.Name, decision.message)
+	return admission.Errored(http.StatusTooManyRequests, fmt.Errorf("%s", decision.message))
+}
+
+// getRedisClusterNodes reads CLUSTER NODES from the pod, or from the leaders if it does not answer, and
+// matches the nodes against the pods of the cluster
+func getRedisClusterNodes(cr *redisv1beta1.RedisCluster, cl client.Client, podName string) ([]RedisClusterNode, error) {
+	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
+	podsByIP := map[string]string{}
+	candidates := []string{podName}
+	for _, role := range []string{"leader", "follower"} {
+		for i := 0; i < int(cr.Spec.GetReplicaCounts(role)); i++ {
+			name := cr.ObjectMeta.Name + "-" + role + "-" + strconv.Itoa(i)
+			ip := getRedisServerIP(cl, RedisDetails{PodName: name, Namespace: cr.Namespace})
+			if ip == "" {
+				continue
+			}
+			podsByIP[strings.Trim(ip, "[]")] = name
+			if role == "leader" && name != podName {
+				candidates = append(candidates, name)
+			}
+		}
+	}
+
+	err := fmt.Errorf("redis cluster %s has no running leader", cr.ObjectMeta.Name)
+	for _, name := range candidates {
+		rc := configureRedisClient(cr, cl, name, nil)
+		var output string
+		output, err = rc.ClusterNodes(ctx).Result()
+		rc.Close()
+		if err != nil {
+			logger.Error(err, "Could not read the cluster nodes", "Pod", name)
+			continue
+		}
+		return ParseRedisClusterNodes(output, podsByIP), nil
+	}
+	return nil, err
+}
@@ -0,0 +1,113 @@
+package k8sutils
+
+import (
+	"context"
+	"net/http"
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestDecideEviction(t *testing.T) {
+	// leader-0 has two replicas, leader-1 a single one
+	nodes := []RedisClusterNode{
+		{PodName: "leader-0", ID: "a", Master: true, Healthy: true, Slots: "0-8191"},
+		{PodName: "leader-1", ID: "b", Master: true, Healthy: true, Slots: "8192-16383"},
+		{PodName: "follower-0", ID: "c", MasterID: "a", Healthy: true},
+		{PodName: "follower-1", ID: "d", MasterID: "a", Healthy: true},
+		{PodName: "follower-2", ID: "e", MasterID: "b", Healthy: true},
+		{PodName: "follower-3", ID: "f", MasterID: "b"},
+		{PodName: "leader-2", ID: "g", Master: true, Healthy: true},
+	}
+
+	var tests = []struct {
+		pod              string
+		allowLastReplica bool
+		allowed          bool
+		failoverPod      string
+	}{
+		{"follower-0", false, true, ""},
+		// the other replica of leader-1 failed
+		{"follower-2", false, false, ""},
+		{"follower-2", true, true, ""},
+		{"follower-3", false, true, ""},
+		{"leader-0", false, false, "follower-0"},
+		{"leader-1", false, false, ""},
+		{"leader-1", true, false, "follower-2"},
+		{"leader-2", false, true, ""},
+		{"unknown", false, true, ""},
+	}
+
+	for _, tt := range tests {
+		got := decideEviction(nodes, tt.pod, tt.allowLastReplica)
+		if got.allowed != tt.allowed || got.failoverPod != tt.failoverPod {
+			t.Errorf("decideEviction(%s, %t) = %+v, want allowed %t and failover to %q", tt.pod, tt.allowLastReplica, got, tt.allowed, tt.failoverPod)
+		}
+	}
+
+	unhealthyMaster := []RedisClusterNode{
+		{PodName: "leader-0", ID: "a", Master: true, Slots: "0-16383"},
+		{PodName: "follower-0", ID: "c", MasterID: "a", Healthy: true},
+		{PodName: "follower-1", ID: "d", MasterID: "a", Healthy: true},
+	}
+	if got := decideEviction(unhealthyMaster, "follower-0", false); got.allowed {
+		t.Errorf("decideEviction() allowed the eviction of a replica of a failed master")
+	}
+	if got := decideEviction(unhealthyMaster, "follower-0", true); !got.allowed {
+		t.Errorf("decideEviction() denied the eviction of a replica of a failed master with another healthy replica")
+	}
+	if got := decideEviction(unhealthyMaster[:2], "follower-0", true); got.allowed {
+		t.Errorf("decideEviction() allowed the eviction of the last healthy node of a shard")
+	}
+	lonelyMaster := []RedisClusterNode{{PodName: "leader-0", ID: "a", Master: true, Healthy: true, Slots: "0-16383"}}
+	if got := decideEviction(lonelyMaster, "leader-0", true); got.allowed || got.failoverPod != "" {
+		t.Errorf("decideEviction() = %+v for a master without replicas, want a denial", got)
+	}
+}
+
+func TestEvictionValidatorHandle(t *testing.T) {
+	size := int32(3)
+	cluster := &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "redis-cluster",
+			Namespace:   "default",
+			Annotations: map[string]string{RedisClusterSkipReconcileAnnotation: "true"},
+		},
+		Spec: redisv1beta1.RedisClusterSpec{Size: &size},
+	}
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "default", Labels: map[string]string{"app": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster-leader-0", Namespace: "default", Labels: getRedisLabels("redis-cluster-leader", "cluster", "leader", nil)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-leader-0", Namespace: "default", Labels: getRedisLabels("other-leader", "cluster", "leader", nil)}},
+	}
+	validator := NewEvictionValidator(newFakeClient(cluster, pods[0], pods[1], pods[2]), nil)
+
+	var tests = []struct {
+		pod         string
+		subResource string
+		reason      string
+	}{
+		{"web-0", "eviction", "not a redis cluster pod"},
+		{"redis-cluster-leader-0", "eviction", "redis cluster is in maintenance"},
+		{"other-leader-0", "eviction", "redis cluster not found"},
+		{"missing-0", "eviction", "pod not found"},
+		{"redis-cluster-leader-0", "", "not an eviction"},
+	}
+
+	for _, tt := range tests {
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Name:        tt.pod,
+			Namespace:   "default",
+			SubResource: tt.subResource,
+			Operation:   admissionv1.Create,
+		}}
+		resp := validator.Handle(context.TODO(), req)
+		if !resp.Allowed || resp.Result.Code != http.StatusOK || string(resp.Result.Reason) != tt.reason {
+			t.Errorf("Handle(%s) = %+v, want allowed with %q", tt.pod, resp.Result, tt.reason)
+		}
+	}
+}
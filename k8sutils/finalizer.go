@@ -3,9 +3,10 @@ package k8sutils
 import (
 	"context"
 	redisv1beta1 "redis-operator/api/v1beta1"
-	"strconv"
 
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -28,13 +29,13 @@ func HandleRedisFinalizer(cr *redisv1beta1.Redis, cl client.Client) error {
 	logger := finalizerLogger(cr.Namespace, RedisFinalizer)
 	if cr.GetDeletionTimestamp() != nil {
 		if controllerutil.ContainsFinalizer(cr, RedisFinalizer) {
-			if err := finalizeRedisServices(cr); err != nil {
+			if err := finalizeRedisServices(cr, cl); err != nil {
 				return err
 			}
-			if err := finalizeRedisPVC(cr); err != nil {
+			if err := finalizeRedisPVC(cr, cl); err != nil {
 				return err
 			}
-			if err := finalizeRedisStatefulSet(cr); err != nil {
+			if err := finalizeRedisStatefulSet(cr, cl); err != nil {
 				return err
 			}
 			controllerutil.RemoveFinalizer(cr, RedisFinalizer)
@@ -52,13 +53,13 @@ func HandleRedisClusterFinalizer(cr *redisv1beta1.RedisCluster, cl client.Client
 	logger := finalizerLogger(cr.Namespace, RedisClusterFinalizer)
 	if cr.GetDeletionTimestamp() != nil {
 		if controllerutil.ContainsFinalizer(cr, RedisClusterFinalizer) {
-			if err := finalizeRedisClusterServices(cr); err != nil {
+			if err := finalizeRedisClusterServices(cr, cl); err != nil {
 				return err
 			}
-			if err := finalizeRedisClusterPVC(cr); err != nil {
+			if err := finalizeRedisClusterPVC(cr, cl); err != nil {
 				return err
 			}
-			if err := finalizeRedisClusterStatefulSets(cr); err != nil {
+			if err := finalizeRedisClusterStatefulSets(cr, cl); err != nil {
 				return err
 			}
 			controllerutil.RemoveFinalizer(cr, RedisClusterFinalizer)
@@ -90,11 +91,11 @@ func AddRedisClusterFinalizer(cr *redisv1beta1.RedisCluster, cl client.Client) e
 }
 
 // finalizeRedisServices delete Services
-func finalizeRedisServices(cr *redisv1beta1.Redis) error {
+func finalizeRedisServices(cr *redisv1beta1.Redis, cl client.Client) error {
 	logger := finalizerLogger(cr.Namespace, RedisFinalizer)
 	serviceName, headlessServiceName := cr.Name, cr.Name+"-headless"
 	for _, svc := range []string{serviceName, headlessServiceName} {
-		err := generateK8sClient().CoreV1().Services(cr.Namespace).Delete(context.TODO(), svc, metav1.DeleteOptions{})
+		err := cl.Delete(context.TODO(), &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: svc, Namespace: cr.Namespace}})
 		if err != nil && !errors.IsNotFound(err) {
 			logger.Error(err, "Could not delete service "+svc)
 			return err
@@ -103,41 +104,42 @@ func finalizeRedisServices(cr *redisv1beta1.Redis) error {
 	return nil
 }
 
-// finalizeRedisClusterServices delete Services
-func finalizeRedisClusterServices(cr *redisv1beta1.RedisCluster) error {
-	logger := finalizerLogger(cr.Namespace, RedisClusterFinalizer)
-	serviceName, headlessServiceName := cr.Name, cr.Name+"-headless"
-	for _, svc := range []string{serviceName, headlessServiceName} {
-		err := generateK8sClient().CoreV1().Services(cr.Namespace).Delete(context.TODO(), svc, metav1.DeleteOptions{})
-		if err != nil && !errors.IsNotFound(err) {
-			logger.Error(err, "Could not delete service "+svc)
-			return err
-		}
-	}
-	return nil
+// finalizeRedisClusterServices delete the Services and PodDisruptionBudgets of the leaders and followers,
+// they are found by their labels and owner reference as their names depend on the roles
+func finalizeRedisClusterServices(cr *redisv1beta1.RedisCluster, cl client.Client) error {
+	return deleteUnwantedObjects(cr, cl, nil, "cluster", []ownedKind{serviceKind, podDisruptionBudgetKind}, nil)
 }
 
-// finalizeRedisPVC delete PVC
-func finalizeRedisPVC(cr *redisv1beta1.Redis) error {
-	logger := finalizerLogger(cr.Namespace, RedisFinalizer)
-	PVCName := cr.Name + "-" + cr.Name + "-0"
-	err := generateK8sClient().CoreV1().PersistentVolumeClaims(cr.Namespace).Delete(context.TODO(), PVCName, metav1.DeleteOptions{})
-	if err != nil && !errors.IsNotFound(err) {
-		logger.Error(err, "Could not delete Persistent Volume Claim "+PVCName)
+// finalizeRedisPVC delete PVC unless the retention policy keeps it
+func finalizeRedisPVC(cr *redisv1beta1.Redis, cl client.Client) error {
+	if !cr.Spec.Storage.DeletePVCsWhenDeleted() {
+		return nil
+	}
+	pvcs, err := listRedisPVCs(cl, cr.Namespace, cr.Name, "standalone", "standalone", cr.ObjectMeta.Labels)
+	if err != nil {
 		return err
 	}
+	for i := range pvcs {
+		if err := deleteRedisPVC(cl, &pvcs[i]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// finalizeRedisClusterPVC delete PVCs
-func finalizeRedisClusterPVC(cr *redisv1beta1.RedisCluster) error {
-	logger := finalizerLogger(cr.Namespace, RedisClusterFinalizer)
+// finalizeRedisClusterPVC delete the PVCs of all leader and follower pods, including the ones of pods
+// which were scaled down before, unless the retention policy keeps them
+func finalizeRedisClusterPVC(cr *redisv1beta1.RedisCluster, cl client.Client) error {
+	if !cr.Spec.Storage.DeletePVCsWhenDeleted() {
+		return nil
+	}
 	for _, role := range []string{"leader", "follower"} {
-		for i := 0; i < int(cr.Spec.GetReplicaCounts(role)); i++ {
-			PVCName := cr.Name + "-" + role + "-" + cr.Name + "-" + role + "-" + strconv.Itoa(i)
-			err := generateK8sClient().CoreV1().PersistentVolumeClaims(cr.Namespace).Delete(context.TODO(), PVCName, metav1.DeleteOptions{})
-			if err != nil && !errors.IsNotFound(err) {
-				logger.Error(err, "Could not delete Persistent Volume Claim "+PVCName)
+		pvcs, err := listRedisPVCs(cl, cr.Namespace, cr.Name+"-"+role, "cluster", role, cr.ObjectMeta.Labels)
+		if err != nil {
+			return err
+		}
+		for i := range pvcs {
+			if err := deleteRedisPVC(cl, &pvcs[i]); err != nil {
 				return err
 			}
 		}
@@ -146,9 +148,9 @@ func finalizeRedisClusterPVC(cr *redisv1beta1.RedisCluster) error {
 }
 
 // finalizeRedisStatefulSet delete statefulset for Redis
-func finalizeRedisStatefulSet(cr *redisv1beta1.Redis) error {
+func finalizeRedisStatefulSet(cr *redisv1beta1.Redis, cl client.Client) error {
 	logger := finalizerLogger(cr.Namespace, RedisFinalizer)
-	err := generateK8sClient().AppsV1().StatefulSets(cr.Namespace).Delete(context.TODO(), cr.Name, metav1.DeleteOptions{})
+	err := cl.Delete(context.TODO(), &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: cr.Name, Namespace: cr.Namespace}})
 	if err != nil && !errors.IsNotFound(err) {
 		logger.Error(err, "Could not delete StatefulSets "+cr.Name)
 		return err
@@ -157,10 +159,10 @@ func finalizeRedisStatefulSet(cr *redisv1beta1.Redis) error {
 }
 
 // finalizeRedisClusterStatefulSets delete statefulset for Redis Cluster
-func finalizeRedisClusterStatefulSets(cr *redisv1beta1.RedisCluster) error {
+func finalizeRedisClusterStatefulSets(cr *redisv1beta1.RedisCluster, cl client.Client) error {
 	logger := finalizerLogger(cr.Namespace, RedisClusterFinalizer)
 	for _, sts := range []string{cr.Name + "-leader", cr.Name + "-follower"} {
-		err := generateK8sClient().AppsV1().StatefulSets(cr.Namespace).Delete(context.TODO(), sts, metav1.DeleteOptions{})
+		err := cl.Delete(context.TODO(), &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: sts, Namespace: cr.Namespace}})
 		if err != nil && !errors.IsNotFound(err) {
 			logger.Error(err, "Could not delete statefulset "+sts)
 			return err
@@ -0,0 +1,70 @@
+package k8sutils
+
+import (
+	"context"
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// newFakeClient returns a controller-runtime fake client which knows the redis types
+func newFakeClient(objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = redisv1beta1.AddToScheme(scheme)
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func TestAddRedisClusterFinalizer(t *testing.T) {
+	cr := &redisv1beta1.RedisCluster{ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default"}}
+	cl := newFakeClient(cr)
+
+	if err := AddRedisClusterFinalizer(cr, cl); err != nil {
+		t.Fatalf("AddRedisClusterFinalizer() returned %v", err)
+	}
+	stored := &redisv1beta1.RedisCluster{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "redis-cluster"}, stored); err != nil {
+		t.Fatalf("could not get cluster: %v", err)
+	}
+	if !controllerutil.ContainsFinalizer(stored, RedisClusterFinalizer) {
+		t.Errorf("got finalizers %v, want %s", stored.Finalizers, RedisClusterFinalizer)
+	}
+}
+
+func TestHandleRedisFinalizer(t *testing.T) {
+	now := metav1.Now()
+	cr := &redisv1beta1.Redis{ObjectMeta: metav1.ObjectMeta{
+		Name:              "redis",
+		Namespace:         "default",
+		DeletionTimestamp: &now,
+		Finalizers:        []string{RedisFinalizer},
+	}}
+	services := []string{"redis", "redis-headless"}
+	objs := []client.Object{cr}
+	for _, name := range services {
+		objs = append(objs, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}})
+	}
+	cl := newFakeClient(objs...)
+
+	if err := HandleRedisFinalizer(cr, cl); err != nil {
+		t.Fatalf("HandleRedisFinalizer() returned %v", err)
+	}
+	for _, name := range services {
+		err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: name}, &corev1.Service{})
+		if !errors.IsNotFound(err) {
+			t.Errorf("service %s: got %v, want not found", name, err)
+		}
+	}
+	if controllerutil.ContainsFinalizer(cr, RedisFinalizer) {
+		t.Errorf("finalizer %s was not removed", RedisFinalizer)
+	}
+}
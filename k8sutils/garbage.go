@@ -0,0 +1,132 @@
+package k8sutils
+
+import (
+	"context"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ownedKind is a kind of object the operator creates for a custom resource
+type ownedKind struct {
+	kind    string
+	newList func() client.ObjectList
+}
+
+// the kinds are listed by the redis_setup_type label, the owner reference tells the custom resources apart
+var (
+	serviceKind             = ownedKind{"Service", func() client.ObjectList { return &corev1.ServiceList{} }}
+	podDisruptionBudgetKind = ownedKind{"PodDisruptionBudget", func() client.ObjectList { return &policyv1.PodDisruptionBudgetList{} }}
+	statefulSetKind         = ownedKind{"StatefulSet", func() client.ObjectList { return &appsv1.StatefulSetList{} }}
+	deploymentKind          = ownedKind{"Deployment", func() client.ObjectList { return &appsv1.DeploymentList{} }}
+	configMapKind           = ownedKind{"ConfigMap", func() client.ObjectList { return &corev1.ConfigMapList{} }}
+)
+
+// garbageLogger will generate logging interface for the garbage collection
+func garbageLogger(namespace string, name string) logr.Logger {
+	reqLogger := log.WithValues("Request.GarbageCollection.Namespace", namespace, "Request.GarbageCollection.Name", name)
+	return reqLogger
+}
+
+// isOwnedBy returns true if the object has an owner reference to the uid
+func isOwnedBy(obj client.Object, uid types.UID) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// listOwnedObjects lists the objects of the kind carrying the labels of the setup type which are owned by the uid
+func listOwnedObjects(cl client.Client, namespace, setupType string, uid types.UID, kind ownedKind) ([]client.Object, error) {
+	list := kind.newList()
+	if err := cl.List(context.TODO(), list, client.InNamespace(namespace), client.MatchingLabels{"redis_setup_type": setupType}); err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	var owned []client.Object
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if ok && isOwnedBy(obj, uid) {
+			owned = append(owned, obj)
+		}
+	}
+	return owned, nil
+}
+
+// wantedRedisClusterObjects returns the names of the objects per kind which the spec of the cluster wants
+func wantedRedisClusterObjects(cr *redisv1beta1.RedisCluster) map[string]map[string]bool {
+	wanted := map[string]map[string]bool{"Service": {}, "PodDisruptionBudget": {}, "StatefulSet": {}, "Deployment": {}, "ConfigMap": {}}
+	roles := []struct {
+		name string
+		pdb  *redisv1beta1.RedisPodDisruptionBudget
+	}{
+		{"leader", cr.Spec.RedisLeader.PodDisruptionBudget},
+		{"follower", cr.Spec.RedisFollower.PodDisruptionBudget},
+	}
+	for _, role := range roles {
+		name := cr.ObjectMeta.Name + "-" + role.name
+		wanted["StatefulSet"][name] = true
+		if cr.Spec.GetReplicaCounts(role.name) != 0 {
+			wanted["Service"][name] = true
+			wanted["Service"][name+"-headless"] = true
+		}
+		if role.pdb != nil && role.pdb.Enabled {
+			wanted["PodDisruptionBudget"][name] = true
+		}
+		if cr.Spec.KubernetesConfig.IsUpstreamImage() || len(cr.Spec.Modules) != 0 {
+			wanted["ConfigMap"][redisConfigName(name)] = true
+		}
+	}
+	if isProxyEnabled(cr) {
+		name := cr.ObjectMeta.Name + "-proxy"
+		wanted["Service"][name] = true
+		wanted["Deployment"][name] = true
+		wanted["ConfigMap"][name] = true
+	}
+	return wanted
+}
+
+// deleteUnwantedObjects deletes the objects owned by the custom resource whose names are not wanted for their kind
+func deleteUnwantedObjects(cr client.Object, cl client.Client, recorder record.EventRecorder, setupType string, kinds []ownedKind, wanted map[string]map[string]bool) error {
+	logger := garbageLogger(cr.GetNamespace(), cr.GetName())
+	for _, kind := range kinds {
+		objs, err := listOwnedObjects(cl, cr.GetNamespace(), setupType, cr.GetUID(), kind)
+		if err != nil {
+			logger.Error(err, "Could not list the owned objects", "Kind", kind.kind)
+			return err
+		}
+		for _, obj := range objs {
+			if wanted[kind.kind][obj.GetName()] || obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			if err := cl.Delete(context.TODO(), obj); err != nil && !errors.IsNotFound(err) {
+				logger.Error(err, "Could not delete the stale object", "Kind", kind.kind, "Name", obj.GetName())
+				return err
+			}
+			logger.Info("Deleted the stale object", "Kind", kind.kind, "Name", obj.GetName())
+			recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonStaleObjectDeleted, "Deleted %s %s which the spec no longer wants", kind.kind, obj.GetName())
+		}
+	}
+	return nil
+}
+
+// CollectRedisClusterGarbage deletes the services, PodDisruptionBudgets, statefulsets, deployments and ConfigMaps
+// owned by the cluster which the current spec no longer wants, e.g. the follower services after the followers are
+// scaled to 0 or the proxy after it is disabled
+func CollectRedisClusterGarbage(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
+	return deleteUnwantedObjects(cr, cl, recorder, "cluster", []ownedKind{serviceKind, podDisruptionBudgetKind, statefulSetKind, deploymentKind, configMapKind}, wantedRedisClusterObjects(cr))
+}
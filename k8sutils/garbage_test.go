@@ -0,0 +1,163 @@
+package k8sutils
+
+import (
+	"context"
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"reflect"
+	"sort"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newOwnedObjectMeta returns the metadata of an object of the cluster role owned by the uid
+func newOwnedObjectMeta(name, role string, uid types.UID) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "default",
+		Labels:          getRedisLabels(name, "cluster", role, nil),
+		OwnerReferences: []metav1.OwnerReference{{Name: "redis-cluster", UID: uid}},
+	}
+}
+
+// objectNames returns the sorted names of the objects of the list in the namespace
+func objectNames(t *testing.T, cl client.Client, list client.ObjectList) []string {
+	if err := cl.List(context.TODO(), list, client.InNamespace("default")); err != nil {
+		t.Fatalf("could not list objects: %v", err)
+	}
+	var names []string
+	switch l := list.(type) {
+	case *corev1.ServiceList:
+		for _, item := range l.Items {
+			names = append(names, item.Name)
+		}
+	case *policyv1.PodDisruptionBudgetList:
+		for _, item := range l.Items {
+			names = append(names, item.Name)
+		}
+	case *appsv1.StatefulSetList:
+		for _, item := range l.Items {
+			names = append(names, item.Name)
+		}
+	case *appsv1.DeploymentList:
+		for _, item := range l.Items {
+			names = append(names, item.Name)
+		}
+	case *corev1.ConfigMapList:
+		for _, item := range l.Items {
+			names = append(names, item.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newGarbageClient returns a client with the objects of a cluster which had followers and PDBs for both roles
+func newGarbageClient() client.Client {
+	var objs []client.Object
+	for _, role := range []string{"leader", "follower"} {
+		name := "redis-cluster-" + role
+		objs = append(objs,
+			&corev1.Service{ObjectMeta: newOwnedObjectMeta(name, role, "uid")},
+			&corev1.Service{ObjectMeta: newOwnedObjectMeta(name+"-headless", role, "uid")},
+			&policyv1.PodDisruptionBudget{ObjectMeta: newOwnedObjectMeta(name, role, "uid")},
+			&appsv1.StatefulSet{ObjectMeta: newOwnedObjectMeta(name, role, "uid")},
+		)
+	}
+	objs = append(objs,
+		// owned by another cluster
+		&corev1.Service{ObjectMeta: newOwnedObjectMeta("other-follower", "follower", "other-uid")},
+		// created by the user
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster-custom", Namespace: "default"}},
+	)
+	return newFakeClient(objs...)
+}
+
+func TestCollectRedisClusterGarbage(t *testing.T) {
+	leaders, followers := int32(3), int32(0)
+	cr := &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default", UID: "uid"},
+		Spec: redisv1beta1.RedisClusterSpec{
+			Size:          &leaders,
+			RedisLeader:   redisv1beta1.RedisLeader{PodDisruptionBudget: &redisv1beta1.RedisPodDisruptionBudget{Enabled: true}},
+			RedisFollower: redisv1beta1.RedisFollower{Replicas: &followers, PodDisruptionBudget: &redisv1beta1.RedisPodDisruptionBudget{Enabled: false}},
+		},
+	}
+	cl := newGarbageClient()
+	if err := CollectRedisClusterGarbage(cr, cl, nil); err != nil {
+		t.Fatalf("CollectRedisClusterGarbage() returned %v", err)
+	}
+
+	var tests = []struct {
+		list client.ObjectList
+		want []string
+	}{
+		{&corev1.ServiceList{}, []string{"other-follower", "redis-cluster-custom", "redis-cluster-leader", "redis-cluster-leader-headless"}},
+		{&policyv1.PodDisruptionBudgetList{}, []string{"redis-cluster-leader"}},
+		{&appsv1.StatefulSetList{}, []string{"redis-cluster-follower", "redis-cluster-leader"}},
+	}
+	for _, tt := range tests {
+		if got := objectNames(t, cl, tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("got %T %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestCollectRedisClusterGarbageProxy(t *testing.T) {
+	size := int32(3)
+	cr := &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default", UID: "uid"},
+		Spec: redisv1beta1.RedisClusterSpec{
+			Size:  &size,
+			Proxy: &redisv1beta1.RedisProxy{Enabled: true},
+		},
+	}
+	cl := newFakeClient(
+		&corev1.Service{ObjectMeta: newOwnedObjectMeta("redis-cluster-proxy", "proxy", "uid")},
+		&appsv1.Deployment{ObjectMeta: newOwnedObjectMeta("redis-cluster-proxy", "proxy", "uid")},
+		&corev1.ConfigMap{ObjectMeta: newOwnedObjectMeta("redis-cluster-proxy", "proxy", "uid")},
+		&corev1.ConfigMap{ObjectMeta: newOwnedObjectMeta("redis-cluster-leader-config", "leader", "uid")},
+	)
+	if err := CollectRedisClusterGarbage(cr, cl, nil); err != nil {
+		t.Fatalf("CollectRedisClusterGarbage() returned %v", err)
+	}
+	if got, want := objectNames(t, cl, &corev1.ConfigMapList{}), []string{"redis-cluster-proxy"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got ConfigMaps %v, want %v", got, want)
+	}
+	if got := objectNames(t, cl, &appsv1.DeploymentList{}); len(got) != 1 {
+		t.Errorf("got deployments %v, want the proxy", got)
+	}
+
+	cr.Spec.Proxy.Enabled = false
+	if err := CollectRedisClusterGarbage(cr, cl, nil); err != nil {
+		t.Fatalf("CollectRedisClusterGarbage() returned %v", err)
+	}
+	for _, list := range []client.ObjectList{&corev1.ServiceList{}, &appsv1.DeploymentList{}, &corev1.ConfigMapList{}} {
+		if got := objectNames(t, cl, list); len(got) != 0 {
+			t.Errorf("got %T %v after the proxy was disabled, want none", list, got)
+		}
+	}
+}
+
+func TestFinalizeRedisClusterServices(t *testing.T) {
+	size := int32(3)
+	cr := &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default", UID: "uid"},
+		Spec:       redisv1beta1.RedisClusterSpec{Size: &size},
+	}
+	cl := newGarbageClient()
+	if err := finalizeRedisClusterServices(cr, cl); err != nil {
+		t.Fatalf("finalizeRedisClusterServices() returned %v", err)
+	}
+	if got, want := objectNames(t, cl, &corev1.ServiceList{}), []string{"other-follower", "redis-cluster-custom"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got services %v, want %v", got, want)
+	}
+	if got := objectNames(t, cl, &policyv1.PodDisruptionBudgetList{}); len(got) != 0 {
+		t.Errorf("got PodDisruptionBudgets %v, want none", got)
+	}
+}
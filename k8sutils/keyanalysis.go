@@ -0,0 +1,462 @@
+package k8sutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-redis/redis/v8"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// KeyScanBatchPeriod is the interval of the scan batches of the key analysis and cleanup, every batch
+	// handles keysPerSecond keys per master
+	KeyScanBatchPeriod = time.Second
+
+	keyAnalysisNoPrefix    = "(none)"
+	keyAnalysisOtherPrefix = "(other)"
+	// keyAnalysisReportKey is the ConfigMap key holding the report, keyAnalysisSlotsKey has a "<slot> <keys>"
+	// line for every slot with keys
+	keyAnalysisReportKey = "report.json"
+	keyAnalysisSlotsKey  = "slots.txt"
+	persistentTTLBucket  = "persistent"
+	// maxConfigMapDataSize is the limit of the API server for the data of a ConfigMap
+	maxConfigMapDataSize = 1024 * 1024
+)
+
+// errKeyAnalysisReportTooLarge is returned if the report does not fit into a ConfigMap
+var errKeyAnalysisReportTooLarge = errors.New("the report exceeds the 1MiB limit of a ConfigMap")
+
+// ttlBuckets are the upper bounds of the TTL histogram buckets in seconds
+var ttlBuckets = []struct {
+	name  string
+	below int64
+}{
+	{"<1m", 60},
+	{"<1h", 3600},
+	{"<1d", 86400},
+	{"<7d", 7 * 86400},
+	{">=7d", math.MaxInt64},
+}
+
+// keyAnalysisOptions are the settings of the analysis with the defaults applied
+type keyAnalysisOptions struct {
+	keysPerSecond int64
+	scanCount     int64
+	maxKeys       int64
+	memorySamples int
+	topKeys       int
+	delimiter     string
+	prefixDepth   int
+	maxPrefixes   int
+}
+
+func getKeyAnalysisOptions(spec *redisv1beta1.RedisKeyAnalysisSpec) keyAnalysisOptions {
+	opts := keyAnalysisOptions{
+		keysPerSecond: 1000,
+		scanCount:     100,
+		maxKeys:       math.MaxInt64,
+		memorySamples: 5,
+		topKeys:       20,
+		delimiter:     spec.PrefixDelimiter,
+		prefixDepth:   1,
+		maxPrefixes:   100,
+	}
+	if spec.KeysPerSecond != nil && *spec.KeysPerSecond > 0 {
+		opts.keysPerSecond = int64(*spec.KeysPerSecond)
+	}
+	if spec.ScanCount != nil && *spec.ScanCount > 0 {
+		opts.scanCount = int64(*spec.ScanCount)
+	}
+	if spec.MaxKeys != nil && *spec.MaxKeys > 0 {
+		opts.maxKeys = *spec.MaxKeys
+	}
+	if spec.MemorySamples != nil && *spec.MemorySamples >= 0 {
+		opts.memorySamples = int(*spec.MemorySamples)
+	}
+	if spec.TopKeys != nil && *spec.TopKeys > 0 {
+		opts.topKeys = int(*spec.TopKeys)
+	}
+	if opts.delimiter == "" {
+		opts.delimiter = ":"
+	}
+	if spec.PrefixDepth != nil && *spec.PrefixDepth > 0 {
+		opts.prefixDepth = int(*spec.PrefixDepth)
+	}
+	if spec.MaxPrefixes != nil && *spec.MaxPrefixes > 0 {
+		opts.maxPrefixes = int(*spec.MaxPrefixes)
+	}
+	return opts
+}
+
+// keyPrefix returns the first depth segments of the key, keys with fewer segments lose their last one
+func keyPrefix(key, delimiter string, depth int) string {
+	parts := strings.SplitN(key, delimiter, depth+1)
+	if len(parts) == 1 {
+		return keyAnalysisNoPrefix
+	}
+	if len(parts) <= depth {
+		depth = len(parts) - 1
+	}
+	return strings.Join(parts[:depth], delimiter)
+}
+
+// ttlBucket returns the histogram bucket of a TTL in seconds, negative values have no expiry
+func ttlBucket(ttl int64) string {
+	if ttl < 0 {
+		return persistentTTLBucket
+	}
+	for _, bucket := range ttlBuckets {
+		if ttl < bucket.below {
+			return bucket.name
+		}
+	}
+	return ttlBuckets[len(ttlBuckets)-1].name
+}
+
+// newTTLHistogram returns the empty histogram with all buckets in order
+func newTTLHistogram() []redisv1beta1.RedisKeyTTLBucket {
+	histogram := []redisv1beta1.RedisKeyTTLBucket{{Bucket: persistentTTLBucket}}
+	for _, bucket := range ttlBuckets {
+		histogram = append(histogram, redisv1beta1.RedisKeyTTLBucket{Bucket: bucket.name})
+	}
+	return histogram
+}
+
+// addKeySamples merges the sampled keys into the report of the status
+func addKeySamples(status *redisv1beta1.RedisKeyAnalysisStatus, samples []redisv1beta1.RedisKeyInfo, opts keyAnalysisOptions) {
+	prefixes := map[string]int{}
+	tracked := 0
+	for i, stats := range status.Prefixes {
+		prefixes[stats.Prefix] = i
+		if stats.Prefix != keyAnalysisOtherPrefix {
+			tracked++
+		}
+	}
+	if len(status.TTLHistogram) == 0 {
+		status.TTLHistogram = newTTLHistogram()
+	}
+	buckets := map[string]int{}
+	for i, bucket := range status.TTLHistogram {
+		buckets[bucket.Bucket] = i
+	}
+
+	for _, sample := range samples {
+		status.ScannedKeys++
+		status.ScannedBytes += sample.Bytes
+		status.TopKeys = addTopKey(status.TopKeys, sample, opts.topKeys)
+
+		prefix := keyPrefix(sample.Key, opts.delimiter, opts.prefixDepth)
+		idx, found := prefixes[prefix]
+		if !found && tracked >= opts.maxPrefixes {
+			prefix = keyAnalysisOtherPrefix
+			idx, found = prefixes[prefix]
+		}
+		if !found {
+			status.Prefixes = append(status.Prefixes, redisv1beta1.RedisKeyPrefixStats{Prefix: prefix})
+			idx = len(status.Prefixes) - 1
+			prefixes[prefix] = idx
+			if prefix != keyAnalysisOtherPrefix {
+				tracked++
+			}
+		}
+		status.Prefixes[idx].Keys++
+		status.Prefixes[idx].Bytes += sample.Bytes
+
+		status.TTLHistogram[buckets[ttlBucket(sample.TTLSeconds)]].Keys++
+	}
+	sort.SliceStable(status.Prefixes, func(i, j int) bool {
+		return status.Prefixes[i].Bytes > status.Prefixes[j].Bytes
+	})
+}
+
+// addTopKey inserts the key into the list ordered by size and keeps the n biggest keys
+func addTopKey(top []redisv1beta1.RedisKeyInfo, key redisv1beta1.RedisKeyInfo, n int) []redisv1beta1.RedisKeyInfo {
+	if len(top) >= n && key.Bytes <= top[len(top)-1].Bytes {
+		return top
+	}
+	idx := sort.Search(len(top), func(i int) bool { return top[i].Bytes < key.Bytes })
+	top = append(top, redisv1beta1.RedisKeyInfo{})
+	copy(top[idx+1:], top[idx:])
+	top[idx] = key
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// topSlots returns the n slots with the most keys, ties are ordered by slot number
+func topSlots(slots []redisv1beta1.RedisSlotKeys, n int) []redisv1beta1.RedisSlotKeys {
+	sorted := append([]redisv1beta1.RedisSlotKeys{}, slots...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Keys != sorted[j].Keys {
+			return sorted[i].Keys > sorted[j].Keys
+		}
+		return sorted[i].Slot < sorted[j].Slot
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// parseSlotRanges returns the slots of the CLUSTER NODES slot field, migrating and importing slots are left out
+func parseSlotRanges(slots string) []int {
+	var result []int
+	for _, field := range strings.Fields(slots) {
+		if strings.HasPrefix(field, "[") {
+			continue
+		}
+		bounds := strings.SplitN(field, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				continue
+			}
+		}
+		for slot := start; slot <= end; slot++ {
+			result = append(result, slot)
+		}
+	}
+	return result
+}
+
+// sampleRedisKeys scans from the cursor until about limit keys were visited or the scan is complete and
+// reads the type, memory usage and TTL of every matching key. Keys which were deleted in the meantime are skipped.
+func sampleRedisKeys(rc *redis.Client, podName string, cursor uint64, match string, limit int64, opts keyAnalysisOptions) ([]redisv1beta1.RedisKeyInfo, uint64, error) {
+	var samples []redisv1beta1.RedisKeyInfo
+	// SCAN visits about COUNT keys per call whether they match or not, so the calls are limited rather than the matches
+	for visited := int64(0); visited < limit; visited += opts.scanCount {
+		keys, next, err := rc.Scan(ctx, cursor, match, opts.scanCount).Result()
+		if err != nil {
+			return samples, cursor, err
+		}
+		if len(keys) > 0 {
+			pipe := rc.Pipeline()
+			keyTypes := make([]*redis.StatusCmd, len(keys))
+			usages := make([]*redis.IntCmd, len(keys))
+			ttls := make([]*redis.Cmd, len(keys))
+			for i, key := range keys {
+				keyTypes[i] = pipe.Type(ctx, key)
+				usages[i] = pipe.MemoryUsage(ctx, key, opts.memorySamples)
+				ttls[i] = pipe.Do(ctx, "ttl", key)
+			}
+			if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+				return samples, cursor, err
+			}
+			for i, key := range keys {
+				keyType := keyTypes[i].Val()
+				if keyType == "" || keyType == "none" {
+					continue
+				}
+				ttl, err := ttls[i].Int64()
+				if err != nil || ttl == -2 {
+					continue
+				}
+				samples = append(samples, redisv1beta1.RedisKeyInfo{
+					Key:        key,
+					Type:       keyType,
+					Bytes:      usages[i].Val(),
+					TTLSeconds: ttl,
+					Slot:       int32(Slot(key)),
+					PodName:    podName,
+				})
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return samples, cursor, nil
+}
+
+// countRedisSlotKeys counts the keys of every slot of the master with CLUSTER COUNTKEYSINSLOT, empty slots are left out
+func countRedisSlotKeys(rc *redis.Client, podName string, slots []int) ([]redisv1beta1.RedisSlotKeys, error) {
+	pipe := rc.Pipeline()
+	counts := make([]*redis.IntCmd, len(slots))
+	for i, slot := range slots {
+		counts[i] = pipe.ClusterCountKeysInSlot(ctx, slot)
+	}
+	if len(slots) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var result []redisv1beta1.RedisSlotKeys
+	for i, slot := range slots {
+		if keys := counts[i].Val(); keys > 0 {
+			result = append(result, redisv1beta1.RedisSlotKeys{Slot: int32(slot), Keys: keys, PodName: podName})
+		}
+	}
+	return result, nil
+}
+
+// StartRedisKeyAnalysis resets the status and records the masters of the cluster to scan
+func StartRedisKeyAnalysis(analysis *redisv1beta1.RedisKeyAnalysis, cluster *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
+	now := metav1.Now()
+	analysis.Status = redisv1beta1.RedisKeyAnalysisStatus{
+		Phase:              redisv1beta1.KeyAnalysisRunning,
+		ObservedGeneration: analysis.Generation,
+		StartTime:          &now,
+		TTLHistogram:       newTTLHistogram(),
+	}
+	for _, master := range getRedisClusterMasters(cluster, cl, recorder) {
+		analysis.Status.Masters = append(analysis.Status.Masters, redisv1beta1.RedisKeyScanProgress{
+			PodName: master.PodName,
+			NodeID:  master.Node.ID,
+			Slots:   master.Node.Slots,
+		})
+	}
+	if len(analysis.Status.Masters) == 0 {
+		return fmt.Errorf("redis cluster %s has no masters serving slots", cluster.Name)
+	}
+	recordEvent(recorder, analysis, corev1.EventTypeNormal, EventReasonKeyAnalysisStarted, "Scanning %d masters of %s", len(analysis.Status.Masters), cluster.Name)
+	return nil
+}
+
+// ScanRedisKeyAnalysis samples the keys of a single batch on every master which is not completed yet
+// and returns true once all masters were scanned
+func ScanRedisKeyAnalysis(analysis *redisv1beta1.RedisKeyAnalysis, cluster *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) (bool, error) {
+	logger := generateRedisManagerLogger(analysis.Namespace, analysis.Name)
+	opts := getKeyAnalysisOptions(&analysis.Spec)
+	batch := opts.keysPerSecond * int64(KeyScanBatchPeriod/time.Second)
+	done := true
+	for i := range analysis.Status.Masters {
+		progress := &analysis.Status.Masters[i]
+		if progress.Completed {
+			continue
+		}
+		limit := batch
+		if remaining := opts.maxKeys - progress.ScannedKeys; remaining < limit {
+			limit = remaining
+		}
+		rc := configureRedisClient(cluster, cl, progress.PodName, recorder)
+		samples, cursor, err := sampleRedisKeys(rc, progress.PodName, progress.Cursor, analysis.Spec.Match, limit, opts)
+		rc.Close()
+		addKeySamples(&analysis.Status, samples, opts)
+		progress.ScannedKeys += int64(len(samples))
+		progress.Cursor = cursor
+		if err != nil {
+			logger.Error(err, "Could not scan the keys of the redis master", "Pod", progress.PodName)
+			recordEvent(recorder, analysis, corev1.EventTypeWarning, EventReasonKeyAnalysisFailed, "Could not scan the keys of %s: %v", progress.PodName, err)
+			return false, err
+		}
+		progress.Completed = cursor == 0 || progress.ScannedKeys >= opts.maxKeys
+		if !progress.Completed {
+			done = false
+		}
+	}
+	return done, nil
+}
+
+// CompleteRedisKeyAnalysis counts the keys of the slots of every master, reports the busiest slots
+// and writes the full report to the output ConfigMap
+func CompleteRedisKeyAnalysis(analysis *redisv1beta1.RedisKeyAnalysis, cluster *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
+	logger := generateRedisManagerLogger(analysis.Namespace, analysis.Name)
+	opts := getKeyAnalysisOptions(&analysis.Spec)
+	var slots []redisv1beta1.RedisSlotKeys
+	for _, progress := range analysis.Status.Masters {
+		rc := configureRedisClient(cluster, cl, progress.PodName, recorder)
+		counts, err := countRedisSlotKeys(rc, progress.PodName, parseSlotRanges(progress.Slots))
+		rc.Close()
+		if err != nil {
+			logger.Error(err, "Could not count the keys of the slots", "Pod", progress.PodName)
+			recordEvent(recorder, analysis, corev1.EventTypeWarning, EventReasonKeyAnalysisFailed, "Could not count the keys of the slots of %s: %v", progress.PodName, err)
+			return err
+		}
+		slots = append(slots, counts...)
+	}
+	analysis.Status.HotSlots = topSlots(slots, opts.topKeys)
+
+	if analysis.Spec.OutputConfigMap != "" {
+		if err := writeKeyAnalysisReport(analysis, slots, cl); err != nil {
+			logger.Error(err, "Could not write the key analysis report", "ConfigMap", analysis.Spec.OutputConfigMap)
+			recordEvent(recorder, analysis, corev1.EventTypeWarning, EventReasonKeyAnalysisFailed, "Could not write the report to ConfigMap %s: %v", analysis.Spec.OutputConfigMap, err)
+			if !isKeyAnalysisReportRejected(err) {
+				return err
+			}
+			// retrying does not make the report any smaller
+			now := metav1.Now()
+			analysis.Status.Phase = redisv1beta1.KeyAnalysisFailed
+			analysis.Status.CompletionTime = &now
+			analysis.Status.Message = fmt.Sprintf("could not write the report to ConfigMap %s: %v", analysis.Spec.OutputConfigMap, err)
+			return nil
+		}
+	}
+	now := metav1.Now()
+	analysis.Status.Phase = redisv1beta1.KeyAnalysisCompleted
+	analysis.Status.CompletionTime = &now
+	analysis.Status.Message = ""
+	recordEvent(recorder, analysis, corev1.EventTypeNormal, EventReasonKeyAnalysisCompleted, "Sampled %d keys using %d bytes", analysis.Status.ScannedKeys, analysis.Status.ScannedBytes)
+	return nil
+}
+
+// isKeyAnalysisReportRejected returns true if the report can never be written because of its size
+func isKeyAnalysisReportRejected(err error) bool {
+	return errors.Is(err, errKeyAnalysisReportTooLarge) || apierrors.IsRequestEntityTooLargeError(err) || apierrors.IsInvalid(err)
+}
+
+// generateKeyAnalysisSlots returns a "<slot> <keys>" line for every slot, ordered by slot. A populated
+// cluster has keys in all 16384 slots, JSON would not fit into the ConfigMap next to the status.
+func generateKeyAnalysisSlots(slots []redisv1beta1.RedisSlotKeys) string {
+	sorted := append([]redisv1beta1.RedisSlotKeys{}, slots...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Slot < sorted[j].Slot })
+	var lines strings.Builder
+	for _, slot := range sorted {
+		lines.WriteString(strconv.Itoa(int(slot.Slot)) + " " + strconv.FormatInt(slot.Keys, 10) + "\n")
+	}
+	return lines.String()
+}
+
+// writeKeyAnalysisReport creates or updates the output ConfigMap, it is owned by the analysis
+func writeKeyAnalysisReport(analysis *redisv1beta1.RedisKeyAnalysis, slots []redisv1beta1.RedisSlotKeys, cl client.Client) error {
+	report, err := json.Marshal(analysis.Status)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{keyAnalysisReportKey: string(report), keyAnalysisSlotsKey: generateKeyAnalysisSlots(slots)}
+	if len(data[keyAnalysisReportKey])+len(data[keyAnalysisSlotsKey]) > maxConfigMapDataSize {
+		return errKeyAnalysisReportTooLarge
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err = cl.Get(context.TODO(), types.NamespacedName{Namespace: analysis.Namespace, Name: analysis.Spec.OutputConfigMap}, configMap)
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			TypeMeta:   generateMetaInformation("ConfigMap", "v1"),
+			ObjectMeta: generateObjectMetaInformation(analysis.Spec.OutputConfigMap, analysis.Namespace, map[string]string{"redis-key-analysis": analysis.Name}, nil),
+			Data:       data,
+		}
+		AddOwnerRefToObject(configMap, redisKeyAnalysisAsOwner(analysis))
+		return cl.Create(context.TODO(), configMap)
+	}
+	if err != nil {
+		return err
+	}
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	for key, value := range data {
+		configMap.Data[key] = value
+	}
+	return cl.Update(context.TODO(), configMap)
+}
@@ -0,0 +1,217 @@
+package k8sutils
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-redis/redis/v8"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	var tests = []struct {
+		key       string
+		delimiter string
+		depth     int
+		want      string
+	}{
+		{"snrs:user:1", ":", 1, "snrs"},
+		{"snrs:user:1", ":", 2, "snrs:user"},
+		{"snrs:1", ":", 2, "snrs"},
+		{"counter", ":", 1, keyAnalysisNoPrefix},
+		{"session/abc", "/", 1, "session"},
+	}
+
+	for _, tt := range tests {
+		if got := keyPrefix(tt.key, tt.delimiter, tt.depth); got != tt.want {
+			t.Errorf("keyPrefix(%q, %q, %d) = %q, want %q", tt.key, tt.delimiter, tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestTTLBucket(t *testing.T) {
+	var tests = []struct {
+		ttl  int64
+		want string
+	}{
+		{-1, "persistent"},
+		{0, "<1m"},
+		{59, "<1m"},
+		{60, "<1h"},
+		{86399, "<1d"},
+		{86400, "<7d"},
+		{30 * 86400, ">=7d"},
+	}
+
+	for _, tt := range tests {
+		if got := ttlBucket(tt.ttl); got != tt.want {
+			t.Errorf("ttlBucket(%d) = %q, want %q", tt.ttl, got, tt.want)
+		}
+	}
+}
+
+func TestAddKeySamples(t *testing.T) {
+	opts := keyAnalysisOptions{topKeys: 2, delimiter: ":", prefixDepth: 1, maxPrefixes: 2}
+	status := &redisv1beta1.RedisKeyAnalysisStatus{}
+	addKeySamples(status, []redisv1beta1.RedisKeyInfo{
+		{Key: "a:1", Bytes: 100, TTLSeconds: -1},
+		{Key: "b:1", Bytes: 300, TTLSeconds: 30},
+		{Key: "a:2", Bytes: 200, TTLSeconds: 7200},
+	}, opts)
+	addKeySamples(status, []redisv1beta1.RedisKeyInfo{
+		{Key: "c:1", Bytes: 50, TTLSeconds: -1},
+		{Key: "b:2", Bytes: 250, TTLSeconds: -1},
+	}, opts)
+
+	if status.ScannedKeys != 5 || status.ScannedBytes != 900 {
+		t.Errorf("got %d keys with %d bytes, want 5 keys with 900 bytes", status.ScannedKeys, status.ScannedBytes)
+	}
+	var topKeys []string
+	for _, key := range status.TopKeys {
+		topKeys = append(topKeys, key.Key)
+	}
+	if !reflect.DeepEqual(topKeys, []string{"b:1", "b:2"}) {
+		t.Errorf("got top keys %v", topKeys)
+	}
+	wantPrefixes := []redisv1beta1.RedisKeyPrefixStats{
+		{Prefix: "b", Keys: 2, Bytes: 550},
+		{Prefix: "a", Keys: 2, Bytes: 300},
+		{Prefix: keyAnalysisOtherPrefix, Keys: 1, Bytes: 50},
+	}
+	if !reflect.DeepEqual(status.Prefixes, wantPrefixes) {
+		t.Errorf("got prefixes %v, want %v", status.Prefixes, wantPrefixes)
+	}
+	wantHistogram := []int64{3, 1, 0, 1, 0, 0}
+	for i, bucket := range status.TTLHistogram {
+		if bucket.Keys != wantHistogram[i] {
+			t.Errorf("got %d keys in bucket %s, want %d", bucket.Keys, bucket.Bucket, wantHistogram[i])
+		}
+	}
+}
+
+func TestParseSlotRanges(t *testing.T) {
+	got := parseSlotRanges("0-2 7 [8->-e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca] 10-11")
+	if want := []int{0, 1, 2, 7, 10, 11}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTopSlots(t *testing.T) {
+	slots := []redisv1beta1.RedisSlotKeys{{Slot: 3, Keys: 5}, {Slot: 1, Keys: 9}, {Slot: 2, Keys: 5}}
+	got := topSlots(slots, 2)
+	if want := []redisv1beta1.RedisSlotKeys{{Slot: 1, Keys: 9}, {Slot: 2, Keys: 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGenerateKeyAnalysisSlots(t *testing.T) {
+	slots := []redisv1beta1.RedisSlotKeys{{Slot: 16383, Keys: 7, PodName: "redis-cluster-leader-2"}, {Slot: 0, Keys: 12, PodName: "redis-cluster-leader-0"}}
+	if got, want := generateKeyAnalysisSlots(slots), "0 12\n16383 7\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriteKeyAnalysisReport(t *testing.T) {
+	analysis := &redisv1beta1.RedisKeyAnalysis{
+		ObjectMeta: metav1.ObjectMeta{Name: "analysis", Namespace: "default", UID: "uid"},
+		Spec:       redisv1beta1.RedisKeyAnalysisSpec{OutputConfigMap: "analysis-report"},
+	}
+	for i := 0; i < 1000; i++ {
+		analysis.Status.TopKeys = append(analysis.Status.TopKeys, redisv1beta1.RedisKeyInfo{
+			Key: fmt.Sprintf("session:%040d", i), Type: "hash", Bytes: 1 << 20, TTLSeconds: 3600, Slot: int32(i), PodName: "redis-cluster-leader-0",
+		})
+	}
+	var slots []redisv1beta1.RedisSlotKeys
+	for slot := 0; slot < 16384; slot++ {
+		slots = append(slots, redisv1beta1.RedisSlotKeys{Slot: int32(slot), Keys: 1000000, PodName: "redis-cluster-leader-0"})
+	}
+
+	cl := newFakeClient()
+	if err := writeKeyAnalysisReport(analysis, slots, cl); err != nil {
+		t.Fatalf("writeKeyAnalysisReport() of every slot returned %v", err)
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "analysis-report"}, configMap); err != nil {
+		t.Fatalf("could not get the report: %v", err)
+	}
+	if lines := strings.Count(configMap.Data[keyAnalysisSlotsKey], "\n"); lines != 16384 || configMap.Data[keyAnalysisReportKey] == "" {
+		t.Errorf("got %d slot lines and report %d bytes", lines, len(configMap.Data[keyAnalysisReportKey]))
+	}
+
+	analysis.Status.Message = strings.Repeat("x", maxConfigMapDataSize)
+	if err := writeKeyAnalysisReport(analysis, slots, cl); !errors.Is(err, errKeyAnalysisReportTooLarge) || !isKeyAnalysisReportRejected(err) {
+		t.Errorf("writeKeyAnalysisReport() of an oversized report returned %v", err)
+	}
+}
+
+// newScanCountingClient returns a client of an in-memory server which answers every SCAN with the next cursor
+// and no keys, like a match pattern which hits nothing, and counts the calls and their COUNT arguments. The scan
+// completes after 1000 calls.
+func newScanCountingClient(t *testing.T, counts *[]string) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Dialer: func(context.Context, string, string) (net.Conn, error) {
+			server, client := net.Pipe()
+			go func() {
+				defer server.Close()
+				reader := bufio.NewReader(server)
+				for {
+					var args []string
+					header, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					n, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+					for i := 0; i < n; i++ {
+						if _, err := reader.ReadString('\n'); err != nil {
+							return
+						}
+						arg, err := reader.ReadString('\n')
+						if err != nil {
+							return
+						}
+						args = append(args, strings.TrimSpace(arg))
+					}
+					if len(args) < 6 || !strings.EqualFold(args[0], "scan") {
+						t.Errorf("got command %v, want SCAN with MATCH and COUNT", args)
+						return
+					}
+					*counts = append(*counts, args[5])
+					next := strconv.Itoa(len(*counts) % 1000)
+					fmt.Fprintf(server, "*2\r\n$%d\r\n%s\r\n*0\r\n", len(next), next)
+				}
+			}()
+			return client, nil
+		},
+	})
+}
+
+func TestSampleRedisKeysLimitsScanCalls(t *testing.T) {
+	var counts []string
+	rc := newScanCountingClient(t, &counts)
+	defer rc.Close()
+	opts := getKeyAnalysisOptions(&redisv1beta1.RedisKeyAnalysisSpec{})
+
+	samples, cursor, err := sampleRedisKeys(rc, "redis-cluster-leader-0", 0, "nomatch:*", 1000, opts)
+	if err != nil || len(samples) != 0 {
+		t.Fatalf("sampleRedisKeys() = %v, %v", samples, err)
+	}
+	if len(counts) != 10 || cursor != 10 {
+		t.Errorf("got %d SCAN calls ending at cursor %d, want 10 calls of 100 keys for a batch of 1000", len(counts), cursor)
+	}
+	for _, count := range counts {
+		if count != "100" {
+			t.Errorf("got SCAN COUNT %s, want the scanCount 100", count)
+		}
+	}
+}
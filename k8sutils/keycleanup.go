@@ -0,0 +1,174 @@
+package k8sutils
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-redis/redis/v8"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxCleanupSampleKeys is the number of matching keys shown in the status
+const maxCleanupSampleKeys = 10
+
+// keyCleanupBatch is the result of a batch of SCAN and UNLINK calls on a master
+type keyCleanupBatch struct {
+	cursor  uint64
+	matched int64
+	deleted int64
+	samples []string
+}
+
+// groupKeysBySlot groups the keys by their hash slot in slot order, multi key commands only
+// accept keys of a single slot in a cluster
+func groupKeysBySlot(keys []string) [][]string {
+	bySlot := map[int][]string{}
+	var slots []int
+	for _, key := range keys {
+		slot := Slot(key)
+		if _, found := bySlot[slot]; !found {
+			slots = append(slots, slot)
+		}
+		bySlot[slot] = append(bySlot[slot], key)
+	}
+	sort.Ints(slots)
+	groups := make([][]string, 0, len(slots))
+	for _, slot := range slots {
+		groups = append(groups, bySlot[slot])
+	}
+	return groups
+}
+
+// unlinkRedisKeys removes the keys with one UNLINK per slot in a single pipeline and returns the number of removed keys
+func unlinkRedisKeys(rc *redis.Client, keys []string) (int64, error) {
+	pipe := rc.Pipeline()
+	var unlinks []*redis.IntCmd
+	for _, group := range groupKeysBySlot(keys) {
+		unlinks = append(unlinks, pipe.Unlink(ctx, group...))
+	}
+	_, err := pipe.Exec(ctx)
+	var deleted int64
+	for _, unlink := range unlinks {
+		deleted += unlink.Val()
+	}
+	return deleted, err
+}
+
+// cleanupRedisKeys runs SCAN from the cursor until about limit keys were visited or the scan is complete, and
+// deletes the matching keys of every SCAN call unless it is a dry run
+func cleanupRedisKeys(rc *redis.Client, cursor uint64, pattern string, batchSize, limit int64, dryRun bool) (keyCleanupBatch, error) {
+	batch := keyCleanupBatch{cursor: cursor}
+	// SCAN visits about COUNT keys per call whether they match or not, so the calls are limited rather than the matches
+	for visited := int64(0); visited < limit; visited += batchSize {
+		keys, next, err := rc.Scan(ctx, batch.cursor, pattern, batchSize).Result()
+		if err != nil {
+			return batch, err
+		}
+		if len(keys) > 0 && !dryRun {
+			deleted, err := unlinkRedisKeys(rc, keys)
+			batch.deleted += deleted
+			if err != nil {
+				// the keys of the call are scanned again on the next batch, the deleted ones are gone by then
+				return batch, err
+			}
+		}
+		batch.matched += int64(len(keys))
+		for _, key := range keys {
+			if len(batch.samples) < maxCleanupSampleKeys {
+				batch.samples = append(batch.samples, key)
+			}
+		}
+		batch.cursor = next
+		if next == 0 {
+			break
+		}
+	}
+	return batch, nil
+}
+
+// StartRedisKeyCleanup resets the status and records the masters of the cluster to clean up
+func StartRedisKeyCleanup(cleanup *redisv1beta1.RedisKeyCleanup, cluster *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
+	now := metav1.Now()
+	cleanup.Status = redisv1beta1.RedisKeyCleanupStatus{
+		Phase:              redisv1beta1.KeyCleanupRunning,
+		ObservedGeneration: cleanup.Generation,
+		StartTime:          &now,
+	}
+	for _, master := range getRedisClusterMasters(cluster, cl, recorder) {
+		cleanup.Status.Masters = append(cleanup.Status.Masters, redisv1beta1.RedisKeyCleanupProgress{
+			PodName: master.PodName,
+			NodeID:  master.Node.ID,
+			Slots:   master.Node.Slots,
+		})
+	}
+	if len(cleanup.Status.Masters) == 0 {
+		return fmt.Errorf("redis cluster %s has no masters serving slots", cluster.Name)
+	}
+	action := "Deleting"
+	if cleanup.Spec.DryRun {
+		action = "Counting"
+	}
+	recordEvent(recorder, cleanup, corev1.EventTypeNormal, EventReasonKeyCleanupStarted, "%s the keys matching %s on %d masters of %s", action, cleanup.Spec.Pattern, len(cleanup.Status.Masters), cluster.Name)
+	return nil
+}
+
+// ScanRedisKeyCleanup runs a single batch on every master which is not completed yet and completes
+// the cleanup once all masters were scanned
+func ScanRedisKeyCleanup(cleanup *redisv1beta1.RedisKeyCleanup, cluster *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
+	logger := generateRedisManagerLogger(cleanup.Namespace, cleanup.Name)
+	batchSize, keysPerSecond := int64(100), int64(1000)
+	if cleanup.Spec.BatchSize != nil && *cleanup.Spec.BatchSize > 0 {
+		batchSize = int64(*cleanup.Spec.BatchSize)
+	}
+	if cleanup.Spec.KeysPerSecond != nil && *cleanup.Spec.KeysPerSecond > 0 {
+		keysPerSecond = int64(*cleanup.Spec.KeysPerSecond)
+	}
+	if batchSize > keysPerSecond {
+		batchSize = keysPerSecond
+	}
+	limit := keysPerSecond * int64(KeyScanBatchPeriod/time.Second)
+
+	done := true
+	for i := range cleanup.Status.Masters {
+		progress := &cleanup.Status.Masters[i]
+		if progress.Completed {
+			continue
+		}
+		rc := configureRedisClient(cluster, cl, progress.PodName, recorder)
+		batch, err := cleanupRedisKeys(rc, progress.Cursor, cleanup.Spec.Pattern, batchSize, limit, cleanup.Spec.DryRun)
+		rc.Close()
+		progress.Cursor = batch.cursor
+		progress.MatchedKeys += batch.matched
+		progress.DeletedKeys += batch.deleted
+		cleanup.Status.MatchedKeys += batch.matched
+		cleanup.Status.DeletedKeys += batch.deleted
+		for _, key := range batch.samples {
+			if len(cleanup.Status.SampleKeys) < maxCleanupSampleKeys {
+				cleanup.Status.SampleKeys = append(cleanup.Status.SampleKeys, key)
+			}
+		}
+		if err != nil {
+			logger.Error(err, "Could not clean up the keys of the redis master", "Pod", progress.PodName)
+			recordEvent(recorder, cleanup, corev1.EventTypeWarning, EventReasonKeyCleanupFailed, "Could not clean up the keys of %s: %v", progress.PodName, err)
+			return err
+		}
+		progress.Completed = batch.cursor == 0
+		if !progress.Completed {
+			done = false
+		}
+	}
+
+	if done {
+		now := metav1.Now()
+		cleanup.Status.Phase = redisv1beta1.KeyCleanupCompleted
+		cleanup.Status.CompletionTime = &now
+		recordEvent(recorder, cleanup, corev1.EventTypeNormal, EventReasonKeyCleanupCompleted, "Matched %d keys and deleted %d keys", cleanup.Status.MatchedKeys, cleanup.Status.DeletedKeys)
+	}
+	return nil
+}
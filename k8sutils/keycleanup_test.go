@@ -0,0 +1,34 @@
+package k8sutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupKeysBySlot(t *testing.T) {
+	keys := []string{"{user1}:a", "snrs:1", "{user1}:b", "snrs:2", "{user1}:c"}
+	groups := groupKeysBySlot(keys)
+
+	seen := 0
+	for _, group := range groups {
+		for _, key := range group {
+			if Slot(key) != Slot(group[0]) {
+				t.Errorf("key %s in the group of slot %d", key, Slot(group[0]))
+			}
+			seen++
+		}
+	}
+	if seen != len(keys) {
+		t.Errorf("got %d keys in the groups, want %d", seen, len(keys))
+	}
+	for i := 1; i < len(groups); i++ {
+		if Slot(groups[i-1][0]) >= Slot(groups[i][0]) {
+			t.Errorf("groups are not ordered by slot: %v", groups)
+		}
+	}
+	for _, group := range groups {
+		if Slot(group[0]) == Slot("{user1}") && !reflect.DeepEqual(group, []string{"{user1}:a", "{user1}:b", "{user1}:c"}) {
+			t.Errorf("got group %v for the hash tag", group)
+		}
+	}
+}
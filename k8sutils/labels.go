@@ -2,6 +2,7 @@ package k8sutils
 
 import (
 	redisv1beta1 "redis-operator/api/v1beta1"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -53,6 +54,19 @@ func redisClusterAsOwner(cr *redisv1beta1.RedisCluster) metav1.OwnerReference {
 	}
 }
 
+// redisKeyAnalysisAsOwner generates and returns object refernece, the kind is set explicitly as
+// typed objects read through the client come without it
+func redisKeyAnalysisAsOwner(cr *redisv1beta1.RedisKeyAnalysis) metav1.OwnerReference {
+	trueVar := true
+	return metav1.OwnerReference{
+		APIVersion: redisv1beta1.GroupVersion.String(),
+		Kind:       "RedisKeyAnalysis",
+		Name:       cr.Name,
+		UID:        cr.UID,
+		Controller: &trueVar,
+	}
+}
+
 // generateStatefulSetsAnots generates and returns statefulsets annotations
 func generateStatefulSetsAnots(stsMeta metav1.ObjectMeta) map[string]string {
 	anots := map[string]string{
@@ -103,3 +117,24 @@ func getRedisLabels(name, setupType, role string, labels map[string]string) map[
 	}
 	return lbls
 }
+
+// RedisNameFromLabels returns the name of the standalone Redis which owns an object labelled by getRedisLabels
+func RedisNameFromLabels(labels map[string]string) (string, bool) {
+	if labels["redis_setup_type"] != "standalone" || labels["app"] == "" {
+		return "", false
+	}
+	return labels["app"], true
+}
+
+// RedisClusterNameFromLabels returns the name of the RedisCluster which owns an object labelled by getRedisLabels,
+// the cluster objects carry the "<cluster>-<role>" name in their app label
+func RedisClusterNameFromLabels(labels map[string]string) (string, bool) {
+	if labels["redis_setup_type"] != "cluster" || labels["role"] == "" {
+		return "", false
+	}
+	name := strings.TrimSuffix(labels["app"], "-"+labels["role"])
+	if name == "" || name == labels["app"] {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,52 @@
+package k8sutils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRedisClusterNameFromLabels(t *testing.T) {
+	var tests = []struct {
+		labels map[string]string
+		want   string
+		found  bool
+	}{
+		{getRedisLabels("redis-cluster-leader", "cluster", "leader", nil), "redis-cluster", true},
+		{getRedisLabels("redis-cluster-follower", "cluster", "follower", map[string]string{"team": "cache"}), "redis-cluster", true},
+		{getRedisLabels("redis", "standalone", "standalone", nil), "", false},
+		{map[string]string{"app": "leader", "redis_setup_type": "cluster", "role": "leader"}, "", false},
+		{map[string]string{"app": "nginx"}, "", false},
+	}
+
+	for _, tt := range tests {
+		testname := fmt.Sprintf("%v", tt.labels)
+		t.Run(testname, func(t *testing.T) {
+			name, found := RedisClusterNameFromLabels(tt.labels)
+			if name != tt.want || found != tt.found {
+				t.Errorf("got (%s, %t), want (%s, %t)", name, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestRedisNameFromLabels(t *testing.T) {
+	var tests = []struct {
+		labels map[string]string
+		want   string
+		found  bool
+	}{
+		{getRedisLabels("redis", "standalone", "standalone", nil), "redis", true},
+		{getRedisLabels("redis-cluster-leader", "cluster", "leader", nil), "", false},
+		{map[string]string{"app": "nginx"}, "", false},
+	}
+
+	for _, tt := range tests {
+		testname := fmt.Sprintf("%v", tt.labels)
+		t.Run(testname, func(t *testing.T) {
+			name, found := RedisNameFromLabels(tt.labels)
+			if name != tt.want || found != tt.found {
+				t.Errorf("got (%s, %t), want (%s, %t)", name, found, tt.want, tt.found)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package k8sutils
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// redisCLIShellFunction defines the shell function cli which runs redis-cli against the host with the password
+// and the TLS certificates from the environment of the redis container
+const redisCLIShellFunction = `
+cli() {
+  host=$1
+  shift
+  if [ "${TLS_MODE:-}" = "true" ]; then
+    set -- --tls --cacert "$REDIS_TLS_CA_KEY" --cert "$REDIS_TLS_CERT" --key "$REDIS_TLS_CERT_KEY" "$@"
+  fi
+  if [ -n "${REDIS_PASSWORD:-}" ]; then
+    set -- -a "$REDIS_PASSWORD" --no-auth-warning "$@"
+  fi
+  redis-cli -h "$host" "$@"
+}
+`
+
+// preStopFailoverTimeout is the number of seconds the preStop hook waits for the replica to take over, the
+// kubelet stops the hook earlier when the termination grace period of the pod is shorter
+const preStopFailoverTimeout = 30
+
+// preStopFailoverScript hands the slots of a master over to a healthy replica with CLUSTER FAILOVER and
+// waits until the node became a replica, so that the writes do not fail for the cluster-node-timeout
+// after the pod is gone. Replicas, masters without slots or without healthy replica exit right away.
+const preStopFailoverScript = redisCLIShellFunction + `
+nodes=$(cli 127.0.0.1 CLUSTER NODES) || exit 0
+id=$(echo "$nodes" | awk '$3 ~ /myself/ && $3 ~ /master/ && NF > 8 {print $1}')
+[ -n "$id" ] || exit 0
+replica=$(echo "$nodes" | awk -v id="$id" '$4 == id && $3 !~ /fail/ && $8 == "connected" {sub(/:[0-9]+@.*/, "", $2); print $2; exit}')
+[ -n "$replica" ] || exit 0
+echo "failing over to replica $replica"
+cli "$replica" CLUSTER FAILOVER || exit 0
+i=0
+while [ "$i" -lt %d ]; do
+  [ "$(cli 127.0.0.1 ROLE | head -n 1)" = "slave" ] && exit 0
+  sleep 1
+  i=$((i + 1))
+done
+`
+
+// getRedisLifecycle returns the preStop hook of the redis container, only the nodes of a cluster fail over
+func getRedisLifecycle(role string) *corev1.Lifecycle {
+	if role != "cluster" {
+		return nil
+	}
+	return &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"/bin/sh", "-c", fmt.Sprintf(preStopFailoverScript, preStopFailoverTimeout)},
+			},
+		},
+	}
+}
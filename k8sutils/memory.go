@@ -0,0 +1,201 @@
+package k8sutils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-redis/redis/v8"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultNearLimitPercent = 90
+	// defaultMemoryRatio is the share of the memory limit the entrypoint of the opstree images sets as maxmemory
+	defaultMemoryRatio = "0.6"
+)
+
+// getMemoryRatio returns the MEMORY_RATIO for the entrypoint of the opstree images, so that it does not reset
+// the maxmemory of spec.memory on every restart. ok is false if the entrypoint must leave maxmemory alone.
+func getMemoryRatio(memory *redisv1beta1.RedisMemory) (ratio string, ok bool) {
+	switch {
+	case memory == nil || memory.MaxMemory == nil && memory.MaxMemoryPercent == nil:
+		return defaultMemoryRatio, true
+	case memory.MaxMemory == nil:
+		return strconv.FormatFloat(float64(*memory.MaxMemoryPercent)/100, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// getMaxMemory validates the memory spec against the container resources and returns the
+// maxmemory in bytes, ok is false if the spec leaves the maxmemory of the servers alone
+func getMaxMemory(memory *redisv1beta1.RedisMemory, resources *corev1.ResourceRequirements) (maxMemory int64, ok bool, err error) {
+	var limit int64
+	if resources != nil {
+		if quantity, found := resources.Limits[corev1.ResourceMemory]; found {
+			limit = quantity.Value()
+		}
+	}
+	switch {
+	case memory.MaxMemory != nil && memory.MaxMemoryPercent != nil:
+		return 0, false, fmt.Errorf("maxMemory and maxMemoryPercent can not be set together")
+	case memory.MaxMemory != nil:
+		maxMemory = memory.MaxMemory.Value()
+		if maxMemory < 0 {
+			return 0, false, fmt.Errorf("maxMemory %s must not be negative", memory.MaxMemory.String())
+		}
+		if limit > 0 && maxMemory > limit {
+			return 0, false, fmt.Errorf("maxMemory %s exceeds the container memory limit of %d bytes", memory.MaxMemory.String(), limit)
+		}
+		return maxMemory, true, nil
+	case memory.MaxMemoryPercent != nil:
+		percent := *memory.MaxMemoryPercent
+		if percent < 1 || percent > 100 {
+			return 0, false, fmt.Errorf("maxMemoryPercent %d must be between 1 and 100", percent)
+		}
+		if limit == 0 {
+			return 0, false, fmt.Errorf("maxMemoryPercent requires a container memory limit")
+		}
+		return limit * int64(percent) / 100, true, nil
+	}
+	return 0, false, nil
+}
+
+// getRedisMemorySettings returns the CONFIG SET parameters of the memory spec in a stable order
+func getRedisMemorySettings(memory *redisv1beta1.RedisMemory, resources *corev1.ResourceRequirements) ([][2]string, error) {
+	maxMemory, ok, err := getMaxMemory(memory, resources)
+	if err != nil {
+		return nil, err
+	}
+	var settings [][2]string
+	if ok {
+		settings = append(settings, [2]string{"maxmemory", strconv.FormatInt(maxMemory, 10)})
+	}
+	if memory.EvictionPolicy != "" {
+		settings = append(settings, [2]string{"maxmemory-policy", memory.EvictionPolicy})
+	}
+	return settings, nil
+}
+
+// parseRedisMemoryInfo reads used_memory and maxmemory from the INFO memory output
+func parseRedisMemoryInfo(info string) (usedMemory int64, maxMemory int64) {
+	for _, line := range strings.Split(info, "\n") {
+		field := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(field) != 2 {
+			continue
+		}
+		switch field[0] {
+		case "used_memory":
+			usedMemory, _ = strconv.ParseInt(field[1], 10, 64)
+		case "maxmemory":
+			maxMemory, _ = strconv.ParseInt(field[1], 10, 64)
+		}
+	}
+	return usedMemory, maxMemory
+}
+
+// generateRedisMemoryStatus computes the memory status of a server from its usage
+func generateRedisMemoryStatus(podName string, usedMemory, maxMemory int64, nearLimitPercent int32) redisv1beta1.RedisMemoryStatus {
+	status := redisv1beta1.RedisMemoryStatus{PodName: podName, MaxMemory: maxMemory}
+	if maxMemory > 0 {
+		status.UsedPercent = int32(usedMemory * 100 / maxMemory)
+		status.NearLimit = status.UsedPercent >= nearLimitPercent
+	}
+	return status
+}
+
+// applyRedisMemorySettings sets the parameters which differ from the running configuration and
+// returns true if anything was changed
+func applyRedisMemorySettings(rc *redis.Client, settings [][2]string) (bool, error) {
+	changed := false
+	for _, setting := range settings {
+		current, err := rc.ConfigGet(ctx, setting[0]).Result()
+		if err != nil {
+			return changed, err
+		}
+		if len(current) == 2 && fmt.Sprint(current[1]) == setting[1] {
+			continue
+		}
+		if err := rc.ConfigSet(ctx, setting[0], setting[1]).Err(); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// reconcileRedisMemory applies the memory spec to the pods and reports their memory usage,
+// pods without an IP are skipped and picked up again once they are running
+func reconcileRedisMemory(cr client.Object, memory *redisv1beta1.RedisMemory, kubernetesConfig redisv1beta1.KubernetesConfig, tlsConfig *redisv1beta1.TLSConfig, cl client.Client, podNames []string, previous []redisv1beta1.RedisMemoryStatus, recorder record.EventRecorder) []redisv1beta1.RedisMemoryStatus {
+	logger := generateRedisManagerLogger(cr.GetNamespace(), cr.GetName())
+	settings, err := getRedisMemorySettings(memory, kubernetesConfig.Resources)
+	if err != nil {
+		logger.Error(err, "Invalid redis memory configuration")
+		recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonMemoryConfigInvalid, "Invalid memory configuration: %v", err)
+	}
+	nearLimitPercent := int32(defaultNearLimitPercent)
+	if memory.NearLimitPercent != nil {
+		nearLimitPercent = *memory.NearLimitPercent
+	}
+	wasNearLimit := map[string]bool{}
+	for _, status := range previous {
+		wasNearLimit[status.PodName] = status.NearLimit
+	}
+
+	var statuses []redisv1beta1.RedisMemoryStatus
+	for _, podName := range podNames {
+		pod := &corev1.Pod{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: cr.GetNamespace(), Name: podName}, pod); err != nil || pod.Status.PodIP == "" {
+			continue
+		}
+		rc := newRedisClient(cr, kubernetesConfig, tlsConfig, cl, podName, recorder)
+		changed, err := applyRedisMemorySettings(rc, settings)
+		if err != nil {
+			logger.Error(err, "Could not configure redis memory", "Pod", podName)
+			recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonMemoryConfigFailed, "Could not configure the memory of %s: %v", podName, err)
+		} else if changed {
+			recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonMemoryConfigured, "Configured the memory of %s", podName)
+		}
+		info, err := rc.Info(ctx, "memory").Result()
+		rc.Close()
+		if err != nil {
+			logger.Error(err, "Could not read redis memory usage", "Pod", podName)
+			continue
+		}
+		usedMemory, maxMemory := parseRedisMemoryInfo(info)
+		status := generateRedisMemoryStatus(podName, usedMemory, maxMemory, nearLimitPercent)
+		if status.NearLimit && !wasNearLimit[podName] {
+			recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonMemoryNearLimit, "%s uses %d%% of its maxmemory", podName, status.UsedPercent)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// ReconcileRedisMemory configures the memory of the standalone redis and returns its usage
+func ReconcileRedisMemory(cr *redisv1beta1.Redis, cl client.Client, recorder record.EventRecorder) []redisv1beta1.RedisMemoryStatus {
+	if cr.Spec.Memory == nil {
+		return nil
+	}
+	return reconcileRedisMemory(cr, cr.Spec.Memory, cr.Spec.KubernetesConfig, cr.Spec.TLS, cl, []string{cr.ObjectMeta.Name + "-0"}, cr.Status.Memory, recorder)
+}
+
+// ReconcileRedisClusterMemory configures the memory of the leaders and followers and returns their usage
+func ReconcileRedisClusterMemory(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) []redisv1beta1.RedisMemoryStatus {
+	if cr.Spec.Memory == nil {
+		return nil
+	}
+	var podNames []string
+	for _, role := range []string{"leader", "follower"} {
+		for i := 0; i < int(cr.Spec.GetReplicaCounts(role)); i++ {
+			podNames = append(podNames, cr.ObjectMeta.Name+"-"+role+"-"+strconv.Itoa(i))
+		}
+	}
+	return reconcileRedisMemory(cr, cr.Spec.Memory, cr.Spec.KubernetesConfig, cr.Spec.TLS, cl, podNames, cr.Status.Memory, recorder)
+}
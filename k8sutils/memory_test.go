@@ -0,0 +1,95 @@
+package k8sutils
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	redisv1beta1 "redis-operator/api/v1beta1"
+)
+
+func TestGetRedisMemorySettings(t *testing.T) {
+	gi := resource.MustParse("1Gi")
+	mi512 := resource.MustParse("512Mi")
+	percent := int32(75)
+	limited := &corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}}
+	unlimited := &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}}
+
+	var tests = []struct {
+		name      string
+		memory    redisv1beta1.RedisMemory
+		resources *corev1.ResourceRequirements
+		want      [][2]string
+		wantErr   bool
+	}{
+		{"absolute", redisv1beta1.RedisMemory{MaxMemory: &mi512, EvictionPolicy: "allkeys-lru"}, limited, [][2]string{{"maxmemory", "536870912"}, {"maxmemory-policy", "allkeys-lru"}}, false},
+		{"absolute without limit", redisv1beta1.RedisMemory{MaxMemory: &gi}, nil, [][2]string{{"maxmemory", "1073741824"}}, false},
+		{"ratio of the limit", redisv1beta1.RedisMemory{MaxMemoryPercent: &percent}, limited, [][2]string{{"maxmemory", "805306368"}}, false},
+		{"eviction policy only", redisv1beta1.RedisMemory{EvictionPolicy: "noeviction"}, nil, [][2]string{{"maxmemory-policy", "noeviction"}}, false},
+		{"absolute above the limit", redisv1beta1.RedisMemory{MaxMemory: &gi}, &corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: mi512}}, nil, true},
+		{"ratio without limit", redisv1beta1.RedisMemory{MaxMemoryPercent: &percent}, unlimited, nil, true},
+		{"absolute and ratio", redisv1beta1.RedisMemory{MaxMemory: &mi512, MaxMemoryPercent: &percent}, limited, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getRedisMemorySettings(&tt.memory, tt.resources)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMemoryRatio(t *testing.T) {
+	mi512 := resource.MustParse("512Mi")
+	percent := int32(75)
+	var tests = []struct {
+		name      string
+		memory    *redisv1beta1.RedisMemory
+		wantRatio string
+		wantOk    bool
+	}{
+		{"without memory spec", nil, "0.6", true},
+		{"eviction policy only", &redisv1beta1.RedisMemory{EvictionPolicy: "allkeys-lru"}, "0.6", true},
+		{"ratio of the limit", &redisv1beta1.RedisMemory{MaxMemoryPercent: &percent}, "0.75", true},
+		{"absolute", &redisv1beta1.RedisMemory{MaxMemory: &mi512}, "", false},
+	}
+
+	for _, tt := range tests {
+		if ratio, ok := getMemoryRatio(tt.memory); ratio != tt.wantRatio || ok != tt.wantOk {
+			t.Errorf("%s: got %q, %t, want %q, %t", tt.name, ratio, ok, tt.wantRatio, tt.wantOk)
+		}
+	}
+}
+
+func TestGenerateRedisMemoryStatus(t *testing.T) {
+	var tests = []struct {
+		info      string
+		nearLimit int32
+		want      redisv1beta1.RedisMemoryStatus
+	}{
+		{"# Memory\r\nused_memory:950\r\nused_memory_human:950B\r\nmaxmemory:1000\r\n", 90, redisv1beta1.RedisMemoryStatus{PodName: "redis-0", MaxMemory: 1000, UsedPercent: 95, NearLimit: true}},
+		{"# Memory\r\nused_memory:500\r\nmaxmemory:1000\r\n", 90, redisv1beta1.RedisMemoryStatus{PodName: "redis-0", MaxMemory: 1000, UsedPercent: 50}},
+		{"# Memory\r\nused_memory:500\r\nmaxmemory:1000\r\n", 50, redisv1beta1.RedisMemoryStatus{PodName: "redis-0", MaxMemory: 1000, UsedPercent: 50, NearLimit: true}},
+		{"# Memory\r\nused_memory:500\r\nmaxmemory:0\r\n", 90, redisv1beta1.RedisMemoryStatus{PodName: "redis-0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.info, func(t *testing.T) {
+			used, max := parseRedisMemoryInfo(tt.info)
+			got := generateRedisMemoryStatus("redis-0", used, max, tt.nearLimit)
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package k8sutils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// redisModulesVolume is the emptyDir into which the init containers copy the shared libraries
+	redisModulesVolume = "redis-modules"
+	redisModulesPath   = "/modules"
+)
+
+// generateRedisModuleConfig returns the loadmodule lines of the valid modules, redis would not start with a
+// library which no init container copies
+func generateRedisModuleConfig(modules []redisv1beta1.RedisModule) []string {
+	var lines []string
+	seen := map[string]bool{}
+	for _, module := range modules {
+		if validateRedisModule(module, seen) != nil {
+			continue
+		}
+		lines = append(lines, strings.Join(append([]string{"loadmodule", module.LoadModule}, module.Args...), " "))
+	}
+	return lines
+}
+
+// validateRedisModule checks the fields the CRD schema can not, an image needs the source of the library
+// and the names of the init containers have to be unique
+func validateRedisModule(module redisv1beta1.RedisModule, seen map[string]bool) error {
+	name := strings.ToLower(module.Name)
+	if seen[name] {
+		return fmt.Errorf("module %s is listed twice", module.Name)
+	}
+	seen[name] = true
+	if module.Image != "" && module.Source == "" {
+		return fmt.Errorf("module %s sets an image without a source", module.Name)
+	}
+	return nil
+}
+
+// validateRedisModules returns the errors of all invalid modules
+func validateRedisModules(modules []redisv1beta1.RedisModule) []error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, module := range modules {
+		if err := validateRedisModule(module, seen); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// addRedisModuleInitContainers adds an init container for every valid module with an image, they copy the
+// shared libraries into the volume which is mounted on /modules in the redis container. Invalid modules are
+// left out, they are reported by reconcileRedisModules
+func addRedisModuleInitContainers(podSpec *corev1.PodSpec, modules []redisv1beta1.RedisModule) {
+	mount := corev1.VolumeMount{Name: redisModulesVolume, MountPath: redisModulesPath}
+	seen := map[string]bool{}
+	for _, module := range modules {
+		if err := validateRedisModule(module, seen); err != nil || module.Image == "" {
+			continue
+		}
+		podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+			Name:            "module-" + strings.ToLower(module.Name),
+			Image:           module.Image,
+			ImagePullPolicy: module.ImagePullPolicy,
+			Command:         []string{"cp", module.Source, redisModulesPath + "/"},
+			VolumeMounts:    []corev1.VolumeMount{mount},
+		})
+	}
+	if len(podSpec.InitContainers) == 0 {
+		return
+	}
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         redisModulesVolume,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, mount)
+}
+
+// parseRedisModuleList reads the names and versions from the MODULE LIST reply
+func parseRedisModuleList(reply interface{}) []redisv1beta1.RedisLoadedModule {
+	entries, _ := reply.([]interface{})
+	var modules []redisv1beta1.RedisLoadedModule
+	for _, entry := range entries {
+		fields, _ := entry.([]interface{})
+		module := redisv1beta1.RedisLoadedModule{}
+		for i := 0; i+1 < len(fields); i += 2 {
+			switch fmt.Sprint(fields[i]) {
+			case "name":
+				module.Name = fmt.Sprint(fields[i+1])
+			case "ver":
+				module.Version, _ = fields[i+1].(int64)
+			}
+		}
+		if module.Name != "" {
+			modules = append(modules, module)
+		}
+	}
+	return modules
+}
+
+// generateRedisModuleStatus matches the modules of the spec case-insensitively against MODULE LIST
+func generateRedisModuleStatus(podName string, modules []redisv1beta1.RedisModule, listed []redisv1beta1.RedisLoadedModule) redisv1beta1.RedisModuleStatus {
+	status := redisv1beta1.RedisModuleStatus{PodName: podName}
+	for _, module := range modules {
+		found := false
+		for _, loaded := range listed {
+			if strings.EqualFold(loaded.Name, module.Name) {
+				status.Loaded = append(status.Loaded, loaded)
+				found = true
+				break
+			}
+		}
+		if !found {
+			status.Missing = append(status.Missing, module.Name)
+		}
+	}
+	sort.Strings(status.Missing)
+	return status
+}
+
+// reconcileRedisModules checks MODULE LIST of the pods after startup, pods without an IP are skipped and
+// picked up again once they are running
+func reconcileRedisModules(cr client.Object, modules []redisv1beta1.RedisModule, kubernetesConfig redisv1beta1.KubernetesConfig, tlsConfig *redisv1beta1.TLSConfig, cl client.Client, podNames []string, previous []redisv1beta1.RedisModuleStatus, recorder record.EventRecorder) []redisv1beta1.RedisModuleStatus {
+	logger := generateRedisManagerLogger(cr.GetNamespace(), cr.GetName())
+	for _, err := range validateRedisModules(modules) {
+		logger.Error(err, "Invalid redis module configuration")
+		recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonModuleConfigInvalid, "Invalid module configuration: %v", err)
+	}
+	wasMissing := map[string]string{}
+	for _, status := range previous {
+		wasMissing[status.PodName] = strings.Join(status.Missing, ",")
+	}
+
+	var statuses []redisv1beta1.RedisModuleStatus
+	for _, podName := range podNames {
+		pod := &corev1.Pod{}
+		if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: cr.GetNamespace(), Name: podName}, pod); err != nil || pod.Status.PodIP == "" {
+			continue
+		}
+		rc := newRedisClient(cr, kubernetesConfig, tlsConfig, cl, podName, recorder)
+		reply, err := rc.Do(ctx, "MODULE", "LIST").Result()
+		rc.Close()
+		if err != nil {
+			logger.Error(err, "Could not list the redis modules", "Pod", podName)
+			continue
+		}
+		status := generateRedisModuleStatus(podName, modules, parseRedisModuleList(reply))
+		if missing := strings.Join(status.Missing, ","); missing != "" && missing != wasMissing[podName] {
+			recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonModuleMissing, "%s has not loaded the modules %s", podName, missing)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// ReconcileRedisModules verifies the modules of the standalone redis
+func ReconcileRedisModules(cr *redisv1beta1.Redis, cl client.Client, recorder record.EventRecorder) []redisv1beta1.RedisModuleStatus {
+	if len(cr.Spec.Modules) == 0 {
+		return nil
+	}
+	return reconcileRedisModules(cr, cr.Spec.Modules, cr.Spec.KubernetesConfig, cr.Spec.TLS, cl, []string{cr.ObjectMeta.Name + "-0"}, cr.Status.Modules, recorder)
+}
+
+// ReconcileRedisClusterModules verifies the modules of the leaders and followers
+func ReconcileRedisClusterModules(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) []redisv1beta1.RedisModuleStatus {
+	if len(cr.Spec.Modules) == 0 {
+		return nil
+	}
+	var podNames []string
+	for _, role := range []string{"leader", "follower"} {
+		for i := 0; i < int(cr.Spec.GetReplicaCounts(role)); i++ {
+			podNames = append(podNames, fmt.Sprintf("%s-%s-%d", cr.ObjectMeta.Name, role, i))
+		}
+	}
+	return reconcileRedisModules(cr, cr.Spec.Modules, cr.Spec.KubernetesConfig, cr.Spec.TLS, cl, podNames, cr.Status.Modules, recorder)
+}
@@ -0,0 +1,121 @@
+package k8sutils
+
+import (
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseRedisModuleList(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{"name", "ReJSON", "ver", int64(20008), "path", "/modules/rejson.so", "args", []interface{}{}},
+		[]interface{}{"name", "search", "ver", int64(20606)},
+		[]interface{}{"ver", int64(1)},
+	}
+	want := []redisv1beta1.RedisLoadedModule{{Name: "ReJSON", Version: 20008}, {Name: "search", Version: 20606}}
+	if got := parseRedisModuleList(reply); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRedisModuleList() = %v, want %v", got, want)
+	}
+	if got := parseRedisModuleList([]interface{}{}); got != nil {
+		t.Errorf("parseRedisModuleList() of an empty list = %v", got)
+	}
+}
+
+func TestGenerateRedisModuleStatus(t *testing.T) {
+	modules := []redisv1beta1.RedisModule{{Name: "rejson"}, {Name: "bf"}, {Name: "search"}}
+	listed := []redisv1beta1.RedisLoadedModule{{Name: "ReJSON", Version: 20008}, {Name: "timeseries", Version: 10617}}
+	got := generateRedisModuleStatus("redis-0", modules, listed)
+	want := redisv1beta1.RedisModuleStatus{
+		PodName: "redis-0",
+		Loaded:  []redisv1beta1.RedisLoadedModule{{Name: "ReJSON", Version: 20008}},
+		Missing: []string{"bf", "search"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("generateRedisModuleStatus() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAddRedisModuleInitContainers(t *testing.T) {
+	podSpec := &corev1.PodSpec{Containers: []corev1.Container{{Name: "redis"}, {Name: redisExporterContainer}}}
+	addRedisModuleInitContainers(podSpec, []redisv1beta1.RedisModule{
+		{Name: "rejson", LoadModule: "/modules/rejson.so", Image: "redis/redis-stack-server:7.0.6-RC8", Source: "/opt/redis-stack/lib/rejson.so"},
+		{Name: "bf", LoadModule: "/usr/lib/redis/modules/redisbloom.so"},
+		{Name: "search", LoadModule: "/modules/redisearch.so", Image: "redis/redis-stack-server:7.0.6-RC8"},
+		{Name: "rejson", LoadModule: "/modules/rejson.so", Image: "redis/redis-stack-server:7.0.6-RC8", Source: "/opt/redis-stack/lib/rejson.so"},
+	})
+
+	if len(podSpec.InitContainers) != 1 {
+		t.Fatalf("got init containers %+v, want one for the module with an image", podSpec.InitContainers)
+	}
+	container := podSpec.InitContainers[0]
+	if container.Name != "module-rejson" || !reflect.DeepEqual(container.Command, []string{"cp", "/opt/redis-stack/lib/rejson.so", "/modules/"}) {
+		t.Errorf("got init container %+v", container)
+	}
+	if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].EmptyDir == nil {
+		t.Errorf("got volumes %+v, want the modules emptyDir", podSpec.Volumes)
+	}
+	if mounts := podSpec.Containers[0].VolumeMounts; len(mounts) != 1 || mounts[0].MountPath != redisModulesPath {
+		t.Errorf("got redis mounts %+v, want /modules", mounts)
+	}
+	if len(podSpec.Containers[1].VolumeMounts) != 0 {
+		t.Errorf("the modules are mounted into the exporter")
+	}
+
+	podSpec = &corev1.PodSpec{Containers: []corev1.Container{{Name: "redis"}}}
+	addRedisModuleInitContainers(podSpec, []redisv1beta1.RedisModule{{Name: "bf", LoadModule: "/usr/lib/redis/modules/redisbloom.so"}})
+	if len(podSpec.InitContainers) != 0 || len(podSpec.Volumes) != 0 || len(podSpec.Containers[0].VolumeMounts) != 0 {
+		t.Errorf("got pod spec %+v for modules of the redis image", podSpec)
+	}
+}
+
+func TestValidateRedisModules(t *testing.T) {
+	modules := []redisv1beta1.RedisModule{
+		{Name: "rejson", LoadModule: "/modules/rejson.so", Image: "redis/redis-stack-server:7.0.6-RC8", Source: "/opt/redis-stack/lib/rejson.so"},
+		{Name: "search", LoadModule: "/modules/redisearch.so", Image: "redis/redis-stack-server:7.0.6-RC8"},
+		{Name: "bf", LoadModule: "/usr/lib/redis/modules/redisbloom.so"},
+		{Name: "bf", LoadModule: "/usr/lib/redis/modules/redisbloom.so"},
+	}
+	errs := validateRedisModules(modules)
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "search") || !strings.Contains(errs[1].Error(), "twice") {
+		t.Errorf("validateRedisModules() = %v, want the missing source of search and the duplicate bf", errs)
+	}
+	want := []string{"loadmodule /modules/rejson.so", "loadmodule /usr/lib/redis/modules/redisbloom.so"}
+	if got := generateRedisModuleConfig(modules); !reflect.DeepEqual(got, want) {
+		t.Errorf("generateRedisModuleConfig() = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateContainerDefModules(t *testing.T) {
+	probe := &redisv1beta1.Probe{}
+	params := containerParameters{
+		Image:          "quay.io/opstree/redis:v7.0.5",
+		Role:           "cluster",
+		ReadinessProbe: probe,
+		LivenessProbe:  probe,
+		Modules:        []redisv1beta1.RedisModule{{Name: "bf", LoadModule: "/usr/lib/redis/modules/redisbloom.so"}},
+	}
+	redis := generateContainerDef("redis-leader", params, false, nil, nil)[0]
+
+	if redis.Args != nil {
+		t.Errorf("got args %v, want the entrypoint of the opstree image", redis.Args)
+	}
+	externalConfig := ""
+	for _, env := range redis.Env {
+		if env.Name == "EXTERNAL_CONFIG_FILE" {
+			externalConfig = env.Value
+		}
+	}
+	if externalConfig != operatorConfigPath {
+		t.Errorf("got EXTERNAL_CONFIG_FILE %q, want %q", externalConfig, operatorConfigPath)
+	}
+	mounted := false
+	for _, mount := range redis.VolumeMounts {
+		mounted = mounted || mount.Name == redisConfigVolume && mount.MountPath == operatorConfigPath
+	}
+	if !mounted {
+		t.Errorf("the generated config is not mounted: %+v", redis.VolumeMounts)
+	}
+}
@@ -0,0 +1,328 @@
+package k8sutils
+
+import (
+	"context"
+	"fmt"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-logr/logr"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	serviceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+	prometheusRuleGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"}
+)
+
+// monitoringParameters will define the input params for ServiceMonitor and PrometheusRule objects
+type monitoringParameters struct {
+	Name      string
+	Namespace string
+	Exporter  *redisv1beta1.RedisExporter
+	Owner     metav1.OwnerReference
+	// Selector matches the services which expose the redis-exporter port
+	Selector map[string]interface{}
+	// ServiceRegex matches the "service" label prometheus puts on the scraped series
+	ServiceRegex string
+	Cluster      bool
+}
+
+// ReconcileRedisMonitoring will create or delete the monitoring objects of a standalone Redis
+func ReconcileRedisMonitoring(cr *redisv1beta1.Redis, cl client.Client) error {
+	return reconcileMonitoring(cl, monitoringParameters{
+		Name:      cr.ObjectMeta.Name,
+		Namespace: cr.Namespace,
+		Exporter:  cr.Spec.RedisExporter,
+		Owner:     redisAsOwner(cr),
+		Selector: map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				"app":              cr.ObjectMeta.Name,
+				"redis_setup_type": "standalone",
+			},
+		},
+		ServiceRegex: cr.ObjectMeta.Name,
+	})
+}
+
+// ReconcileRedisClusterMonitoring will create or delete the monitoring objects of a Redis cluster
+func ReconcileRedisClusterMonitoring(cr *redisv1beta1.RedisCluster, cl client.Client) error {
+	return reconcileMonitoring(cl, monitoringParameters{
+		Name:      cr.ObjectMeta.Name,
+		Namespace: cr.Namespace,
+		Exporter:  cr.Spec.RedisExporter,
+		Owner:     redisClusterAsOwner(cr),
+		Selector: map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				"redis_setup_type": "cluster",
+			},
+			"matchExpressions": []interface{}{
+				map[string]interface{}{
+					"key":      "app",
+					"operator": "In",
+					"values":   []interface{}{cr.ObjectMeta.Name + "-leader", cr.ObjectMeta.Name + "-follower"},
+				},
+			},
+		},
+		ServiceRegex: cr.ObjectMeta.Name + "-(leader|follower)",
+		Cluster:      true,
+	})
+}
+
+// reconcileMonitoring creates the ServiceMonitor and PrometheusRule if they are enabled, and removes them otherwise
+func reconcileMonitoring(cl client.Client, params monitoringParameters) error {
+	logger := monitoringLogger(params.Namespace, params.Name)
+	exporterEnabled := params.Exporter != nil && params.Exporter.Enabled
+
+	if monitoringResourceInstalled(cl, serviceMonitorGVK) {
+		if exporterEnabled && params.Exporter.ServiceMonitor != nil && params.Exporter.ServiceMonitor.Enabled {
+			if err := createOrUpdateMonitoringObject(cl, generateServiceMonitorDef(params)); err != nil {
+				return err
+			}
+		} else if err := deleteMonitoringObject(cl, serviceMonitorGVK, params.Namespace, params.Name); err != nil {
+			return err
+		}
+	} else if exporterEnabled && params.Exporter.ServiceMonitor != nil && params.Exporter.ServiceMonitor.Enabled {
+		logger.Info("ServiceMonitor CRD is not installed, skipping ServiceMonitor creation")
+	}
+
+	if monitoringResourceInstalled(cl, prometheusRuleGVK) {
+		if exporterEnabled && params.Exporter.PrometheusRule != nil && params.Exporter.PrometheusRule.Enabled {
+			if err := createOrUpdateMonitoringObject(cl, generatePrometheusRuleDef(params)); err != nil {
+				return err
+			}
+		} else if err := deleteMonitoringObject(cl, prometheusRuleGVK, params.Namespace, params.Name); err != nil {
+			return err
+		}
+	} else if exporterEnabled && params.Exporter.PrometheusRule != nil && params.Exporter.PrometheusRule.Enabled {
+		logger.Info("PrometheusRule CRD is not installed, skipping PrometheusRule creation")
+	}
+	return nil
+}
+
+// generateServiceMonitorDef generates the ServiceMonitor definition for the redis exporter
+func generateServiceMonitorDef(params monitoringParameters) *unstructured.Unstructured {
+	smParams := params.Exporter.ServiceMonitor
+	endpoint := map[string]interface{}{
+		"port": "redis-exporter",
+	}
+	if smParams.Interval != "" {
+		endpoint["interval"] = smParams.Interval
+	}
+	if smParams.ScrapeTimeout != "" {
+		endpoint["scrapeTimeout"] = smParams.ScrapeTimeout
+	}
+	serviceMonitor := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": params.Selector,
+				"namespaceSelector": map[string]interface{}{
+					"matchNames": []interface{}{params.Namespace},
+				},
+				"endpoints": []interface{}{endpoint},
+			},
+		},
+	}
+	serviceMonitor.SetGroupVersionKind(serviceMonitorGVK)
+	serviceMonitor.SetName(params.Name)
+	serviceMonitor.SetNamespace(params.Namespace)
+	serviceMonitor.SetLabels(smParams.Labels)
+	AddOwnerRefToObject(serviceMonitor, params.Owner)
+	return serviceMonitor
+}
+
+// generatePrometheusRuleDef generates the PrometheusRule definition with the redis alerts
+func generatePrometheusRuleDef(params monitoringParameters) *unstructured.Unstructured {
+	ruleParams := params.Exporter.PrometheusRule
+	var rules []interface{}
+	if !ruleParams.DisableDefaultRules {
+		for _, rule := range defaultPrometheusAlertRules(params) {
+			rules = append(rules, alertRuleToUnstructured(rule))
+		}
+	}
+	for _, rule := range ruleParams.AdditionalRules {
+		rules = append(rules, alertRuleToUnstructured(rule))
+	}
+	prometheusRule := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"groups": []interface{}{
+					map[string]interface{}{
+						"name":  params.Name + ".rules",
+						"rules": rules,
+					},
+				},
+			},
+		},
+	}
+	prometheusRule.SetGroupVersionKind(prometheusRuleGVK)
+	prometheusRule.SetName(params.Name)
+	prometheusRule.SetNamespace(params.Namespace)
+	prometheusRule.SetLabels(ruleParams.Labels)
+	AddOwnerRefToObject(prometheusRule, params.Owner)
+	return prometheusRule
+}
+
+// defaultPrometheusAlertRules returns the alerts shipped by the operator
+func defaultPrometheusAlertRules(params monitoringParameters) []redisv1beta1.PrometheusAlertRule {
+	selector := fmt.Sprintf(`namespace="%s",service=~"%s"`, params.Namespace, params.ServiceRegex)
+	memoryUsagePercent := int32(90)
+	if params.Exporter.PrometheusRule.MemoryUsagePercent != nil {
+		memoryUsagePercent = *params.Exporter.PrometheusRule.MemoryUsagePercent
+	}
+	rules := []redisv1beta1.PrometheusAlertRule{
+		{
+			Alert: "RedisDown",
+			Expr:  fmt.Sprintf("redis_up{%s} == 0", selector),
+			For:   "1m",
+			Labels: map[string]string{
+				"severity": "critical",
+			},
+			Annotations: map[string]string{
+				"summary":     "Redis instance is down",
+				"description": "Redis pod {{ $labels.pod }} of " + params.Name + " is not reachable by the exporter.",
+			},
+		},
+		{
+			Alert: "RedisMemoryNearMaxmemory",
+			Expr:  fmt.Sprintf("redis_memory_used_bytes{%s} / redis_memory_max_bytes{%s} * 100 > %d and redis_memory_max_bytes{%s} > 0", selector, selector, memoryUsagePercent, selector),
+			For:   "5m",
+			Labels: map[string]string{
+				"severity": "warning",
+			},
+			Annotations: map[string]string{
+				"summary":     "Redis memory usage is close to maxmemory",
+				"description": fmt.Sprintf("Redis pod {{ $labels.pod }} of %s uses more than %d%% of its maxmemory.", params.Name, memoryUsagePercent),
+			},
+		},
+	}
+	if params.Cluster {
+		rules = append(rules,
+			redisv1beta1.PrometheusAlertRule{
+				Alert: "RedisClusterStateNotOk",
+				Expr:  fmt.Sprintf("redis_cluster_state{%s} == 0", selector),
+				For:   "1m",
+				Labels: map[string]string{
+					"severity": "critical",
+				},
+				Annotations: map[string]string{
+					"summary":     "Redis cluster state is not ok",
+					"description": "Redis pod {{ $labels.pod }} of " + params.Name + " reports cluster_state:fail.",
+				},
+			},
+			redisv1beta1.PrometheusAlertRule{
+				Alert: "RedisReplicationLinkDown",
+				Expr:  fmt.Sprintf("redis_master_link_up{%s} == 0", selector),
+				For:   "1m",
+				Labels: map[string]string{
+					"severity": "critical",
+				},
+				Annotations: map[string]string{
+					"summary":     "Redis replication link is down",
+					"description": "Redis replica {{ $labels.pod }} of " + params.Name + " lost the link to its master.",
+				},
+			},
+		)
+	}
+	return rules
+}
+
+// alertRuleToUnstructured converts an alert rule into its unstructured representation
+func alertRuleToUnstructured(rule redisv1beta1.PrometheusAlertRule) map[string]interface{} {
+	res := map[string]interface{}{
+		"alert": rule.Alert,
+		"expr":  rule.Expr,
+	}
+	if rule.For != "" {
+		res["for"] = rule.For
+	}
+	if len(rule.Labels) != 0 {
+		res["labels"] = stringMapToUnstructured(rule.Labels)
+	}
+	if len(rule.Annotations) != 0 {
+		res["annotations"] = stringMapToUnstructured(rule.Annotations)
+	}
+	return res
+}
+
+func stringMapToUnstructured(in map[string]string) map[string]interface{} {
+	res := make(map[string]interface{}, len(in))
+	for k, v := range in {
+		res[k] = v
+	}
+	return res
+}
+
+// monitoringResourceInstalled checks whether the prometheus-operator CRD for the kind is served by the cluster
+func monitoringResourceInstalled(cl client.Client, gvk schema.GroupVersionKind) bool {
+	logger := monitoringLogger("", gvk.Kind)
+	_, err := cl.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		if !meta.IsNoMatchError(err) {
+			logger.Error(err, "Could not discover monitoring.coreos.com resources")
+		}
+		return false
+	}
+	return true
+}
+
+// createOrUpdateMonitoringObject will create or update a prometheus-operator object
+func createOrUpdateMonitoringObject(cl client.Client, obj *unstructured.Unstructured) error {
+	logger := monitoringLogger(obj.GetNamespace(), obj.GetName())
+	stored := &unstructured.Unstructured{}
+	stored.SetGroupVersionKind(obj.GroupVersionKind())
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, stored)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			err = cl.Create(context.TODO(), obj)
+			if err != nil {
+				logger.Error(err, "Redis monitoring object creation failed", "Kind", obj.GetKind())
+				return err
+			}
+			logger.Info("Redis monitoring object creation was successful", "Kind", obj.GetKind())
+			return nil
+		}
+		return err
+	}
+	if apiequality.Semantic.DeepEqual(stored.Object["spec"], obj.Object["spec"]) &&
+		apiequality.Semantic.DeepEqual(stored.GetLabels(), obj.GetLabels()) {
+		logger.Info("Redis monitoring object is already in-sync", "Kind", obj.GetKind())
+		return nil
+	}
+	obj.SetResourceVersion(stored.GetResourceVersion())
+	err = cl.Update(context.TODO(), obj)
+	if err != nil {
+		logger.Error(err, "Redis monitoring object update failed", "Kind", obj.GetKind())
+		return err
+	}
+	logger.Info("Redis monitoring object update was successful", "Kind", obj.GetKind())
+	return nil
+}
+
+// deleteMonitoringObject will delete a prometheus-operator object if it exists
+func deleteMonitoringObject(cl client.Client, gvk schema.GroupVersionKind, namespace, name string) error {
+	logger := monitoringLogger(namespace, name)
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	err := cl.Delete(context.TODO(), obj)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "Redis monitoring object deletion failed", "Kind", gvk.Kind)
+		return err
+	}
+	return nil
+}
+
+// monitoringLogger will generate logging interface for monitoring objects
+func monitoringLogger(namespace string, name string) logr.Logger {
+	reqLogger := log.WithValues("Request.Monitoring.Namespace", namespace, "Request.Monitoring.Name", name)
+	return reqLogger
+}
@@ -0,0 +1,54 @@
+package k8sutils
+
+import (
+	"fmt"
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestGeneratePrometheusRuleDef(t *testing.T) {
+	additional := []redisv1beta1.PrometheusAlertRule{{Alert: "Custom", Expr: "vector(1)"}}
+	var tests = []struct {
+		cluster        bool
+		disableDefault bool
+		additional     []redisv1beta1.PrometheusAlertRule
+		want           int
+	}{
+		{false, false, nil, 2},
+		{true, false, nil, 4},
+		{true, false, additional, 5},
+		{true, true, additional, 1},
+	}
+
+	for _, tt := range tests {
+		testname := fmt.Sprintf("cluster=%t,disableDefault=%t,additional=%d", tt.cluster, tt.disableDefault, len(tt.additional))
+		t.Run(testname, func(t *testing.T) {
+			params := monitoringParameters{
+				Name:      "redis",
+				Namespace: "default",
+				Exporter: &redisv1beta1.RedisExporter{
+					Enabled: true,
+					PrometheusRule: &redisv1beta1.PrometheusRule{
+						Enabled:             true,
+						DisableDefaultRules: tt.disableDefault,
+						AdditionalRules:     tt.additional,
+					},
+				},
+				ServiceRegex: "redis",
+				Cluster:      tt.cluster,
+			}
+			// DeepCopy panics on values which are not valid unstructured content
+			rule := generatePrometheusRuleDef(params).DeepCopy()
+			groups, _, _ := unstructured.NestedSlice(rule.Object, "spec", "groups")
+			if len(groups) != 1 {
+				t.Fatalf("got %d groups, want 1", len(groups))
+			}
+			rules, _, _ := unstructured.NestedSlice(groups[0].(map[string]interface{}), "rules")
+			if len(rules) != tt.want {
+				t.Errorf("got %d rules, want %d", len(rules), tt.want)
+			}
+		})
+	}
+}
@@ -2,88 +2,109 @@ package k8sutils
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/banzaicloud/k8s-objectmatcher/patch"
 	"github.com/go-logr/logr"
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	redisv1beta1 "redis-operator/api/v1beta1"
 )
 
-// CreateRedisLeaderPodDisruptionBudget check and create a PodDisruptionBudget for Leaders
-func ReconcileRedisPodDisruptionBudget(cr *redisv1beta1.RedisCluster, role string, pdbParams *redisv1beta1.RedisPodDisruptionBudget) error {
+// ReconcileRedisPodDisruptionBudget creates, updates or deletes the PodDisruptionBudget of the leaders or followers
+func ReconcileRedisPodDisruptionBudget(cr *redisv1beta1.RedisCluster, cl client.Client, role string, pdbParams *redisv1beta1.RedisPodDisruptionBudget) error {
 	pdbName := cr.ObjectMeta.Name + "-" + role
-	logger := pdbLogger(cr.Namespace, pdbName)
+	labels := getRedisLabels(cr.ObjectMeta.Name, "cluster", role, cr.ObjectMeta.GetLabels())
+	pdbMeta := generateObjectMetaInformation(pdbName, cr.Namespace, labels, generateStatefulSetsAnots(cr.ObjectMeta))
+	selector := map[string]string{
+		"app":  pdbName,
+		"role": role,
+	}
+	return reconcilePodDisruptionBudget(cl, pdbMeta, selector, cr.Spec.GetReplicaCounts(role), pdbParams, redisClusterAsOwner(cr))
+}
+
+// ReconcileStandalonePodDisruptionBudget creates, updates or deletes the PodDisruptionBudget of the standalone redis
+func ReconcileStandalonePodDisruptionBudget(cr *redisv1beta1.Redis, cl client.Client) error {
+	labels := getRedisLabels(cr.ObjectMeta.Name, "standalone", "standalone", cr.ObjectMeta.GetLabels())
+	pdbMeta := generateObjectMetaInformation(cr.ObjectMeta.Name, cr.Namespace, labels, generateStatefulSetsAnots(cr.ObjectMeta))
+	selector := map[string]string{
+		"app":  cr.ObjectMeta.Name,
+		"role": "standalone",
+	}
+	replicas := *generateRedisStandaloneParams(cr).Replicas
+	return reconcilePodDisruptionBudget(cl, pdbMeta, selector, replicas, cr.Spec.PodDisruptionBudget, redisAsOwner(cr))
+}
+
+// reconcilePodDisruptionBudget creates or updates the PodDisruptionBudget when it is enabled and deletes it otherwise
+func reconcilePodDisruptionBudget(cl client.Client, pdbMeta metav1.ObjectMeta, selector map[string]string, replicas int32, pdbParams *redisv1beta1.RedisPodDisruptionBudget, owner metav1.OwnerReference) error {
+	logger := pdbLogger(pdbMeta.Namespace, pdbMeta.Name)
 	if pdbParams != nil && pdbParams.Enabled {
-		labels := getRedisLabels(cr.ObjectMeta.Name, "cluster", role, cr.ObjectMeta.GetLabels())
-		annotations := generateStatefulSetsAnots(cr.ObjectMeta)
-		pdbMeta := generateObjectMetaInformation(pdbName, cr.Namespace, labels, annotations)
-		pdbDef := generatePodDisruptionBudgetDef(cr, role, pdbMeta, cr.Spec.RedisLeader.PodDisruptionBudget)
-		return CreateOrUpdatePodDisruptionBudget(pdbDef)
-	} else {
-		// Check if one exists, and delete it.
-		_, err := GetPodDisruptionBudget(cr.Namespace, pdbName)
-		if err == nil {
-			return deletePodDisruptionBudget(cr.Namespace, pdbName)
-		} else if err != nil && errors.IsNotFound(err) {
-			logger.Info("Reconciliation Successful, no PodDisruptionBudget Found.")
-			// Its ok if its not found, as we're deleting anyway
-			return nil
-		}
-		return err
+		pdbDef := generatePodDisruptionBudgetDef(pdbMeta, selector, replicas, pdbParams)
+		AddOwnerRefToObject(pdbDef, owner)
+		return CreateOrUpdatePodDisruptionBudget(cl, pdbDef)
+	}
+	// Check if one exists, and delete it.
+	_, err := GetPodDisruptionBudget(cl, pdbMeta.Namespace, pdbMeta.Name)
+	if err == nil {
+		return deletePodDisruptionBudget(cl, pdbMeta.Namespace, pdbMeta.Name)
+	} else if errors.IsNotFound(err) {
+		logger.Info("Reconciliation Successful, no PodDisruptionBudget Found.")
+		// Its ok if its not found, as we're deleting anyway
+		return nil
 	}
+	return err
 }
 
-// generatePodDisruptionBudgetDef will create a PodDisruptionBudget definition
-func generatePodDisruptionBudgetDef(cr *redisv1beta1.RedisCluster, role string, pdbMeta metav1.ObjectMeta, pdbParams *redisv1beta1.RedisPodDisruptionBudget) *policyv1.PodDisruptionBudget {
-	lblSelector := LabelSelectors(map[string]string{
-		"app":  fmt.Sprintf("%s-%s", cr.ObjectMeta.Name, role),
-		"role": role,
-	})
+// generatePodDisruptionBudgetDef will create a PodDisruptionBudget definition for the pods of the selector
+func generatePodDisruptionBudgetDef(pdbMeta metav1.ObjectMeta, selector map[string]string, replicas int32, pdbParams *redisv1beta1.RedisPodDisruptionBudget) *policyv1.PodDisruptionBudget {
 	pdbTemplate := &policyv1.PodDisruptionBudget{
 		TypeMeta:   generateMetaInformation("PodDisruptionBudget", "policy/v1"),
 		ObjectMeta: pdbMeta,
 		Spec: policyv1.PodDisruptionBudgetSpec{
-			Selector: lblSelector,
+			Selector: LabelSelectors(selector),
 		},
 	}
 	if pdbParams.MinAvailable != nil {
-		pdbTemplate.Spec.MinAvailable = &intstr.IntOrString{Type: intstr.Int, IntVal: int32(*pdbParams.MinAvailable)}
+		pdbTemplate.Spec.MinAvailable = &intstr.IntOrString{Type: intstr.Int, IntVal: *pdbParams.MinAvailable}
 	}
 	if pdbParams.MaxUnavailable != nil {
-		pdbTemplate.Spec.MaxUnavailable = &intstr.IntOrString{Type: intstr.Int, IntVal: int32(*pdbParams.MaxUnavailable)}
+		pdbTemplate.Spec.MaxUnavailable = &intstr.IntOrString{Type: intstr.Int, IntVal: *pdbParams.MaxUnavailable}
 	}
-	// If we don't have a value for either, assume quorum: (N/2)+1
+	// If we don't have a value for either, assume quorum of the pods of the role: (N/2)+1. A single pod
+	// would never be evictable with a quorum of 1 and block every node drain, so it may always go.
 	if pdbTemplate.Spec.MaxUnavailable == nil && pdbTemplate.Spec.MinAvailable == nil {
-		pdbTemplate.Spec.MinAvailable = &intstr.IntOrString{Type: intstr.Int, IntVal: int32((*cr.Spec.Size / 2) + 1)}
+		if replicas <= 1 {
+			pdbTemplate.Spec.MaxUnavailable = &intstr.IntOrString{Type: intstr.Int, IntVal: 1}
+		} else {
+			pdbTemplate.Spec.MinAvailable = &intstr.IntOrString{Type: intstr.Int, IntVal: (replicas / 2) + 1}
+		}
 	}
-	AddOwnerRefToObject(pdbTemplate, redisClusterAsOwner(cr))
 	return pdbTemplate
 }
 
 // CreateOrUpdateService method will create or update Redis service
-func CreateOrUpdatePodDisruptionBudget(pdbDef *policyv1.PodDisruptionBudget) error {
+func CreateOrUpdatePodDisruptionBudget(cl client.Client, pdbDef *policyv1.PodDisruptionBudget) error {
 	logger := pdbLogger(pdbDef.Namespace, pdbDef.Name)
-	storedPDB, err := GetPodDisruptionBudget(pdbDef.Namespace, pdbDef.Name)
+	storedPDB, err := GetPodDisruptionBudget(cl, pdbDef.Namespace, pdbDef.Name)
 	if err != nil {
 		if err := patch.DefaultAnnotator.SetLastAppliedAnnotation(pdbDef); err != nil {
 			logger.Error(err, "Unable to patch redis PodDisruptionBudget with comparison object")
 			return err
 		}
 		if errors.IsNotFound(err) {
-			return createPodDisruptionBudget(pdbDef.Namespace, pdbDef)
+			return createPodDisruptionBudget(cl, pdbDef.Namespace, pdbDef)
 		}
 		return err
 	}
-	return patchPodDisruptionBudget(storedPDB, pdbDef, pdbDef.Namespace)
+	return patchPodDisruptionBudget(cl, storedPDB, pdbDef, pdbDef.Namespace)
 }
 
 // patchPodDisruptionBudget will patch Redis Kubernetes PodDisruptionBudgets
-func patchPodDisruptionBudget(storedPdb *policyv1.PodDisruptionBudget, newPdb *policyv1.PodDisruptionBudget, namespace string) error {
+func patchPodDisruptionBudget(cl client.Client, storedPdb *policyv1.PodDisruptionBudget, newPdb *policyv1.PodDisruptionBudget, namespace string) error {
 	logger := pdbLogger(namespace, storedPdb.Name)
 	// We want to try and keep this atomic as possible.
 	newPdb.ResourceVersion = storedPdb.ResourceVersion
@@ -119,15 +140,15 @@ func patchPodDisruptionBudget(storedPdb *policyv1.PodDisruptionBudget, newPdb *p
 			logger.Error(err, "Unable to patch redis PodDisruptionBudget with comparison object")
 			return err
 		}
-		return updatePodDisruptionBudget(namespace, newPdb)
+		return updatePodDisruptionBudget(cl, namespace, newPdb)
 	}
 	return nil
 }
 
 // createPodDisruptionBudget is a method to create PodDisruptionBudgets in Kubernetes
-func createPodDisruptionBudget(namespace string, pdb *policyv1.PodDisruptionBudget) error {
+func createPodDisruptionBudget(cl client.Client, namespace string, pdb *policyv1.PodDisruptionBudget) error {
 	logger := pdbLogger(namespace, pdb.Name)
-	_, err := generateK8sClient().PolicyV1().PodDisruptionBudgets(namespace).Create(context.TODO(), pdb, metav1.CreateOptions{})
+	err := cl.Create(context.TODO(), pdb)
 	if err != nil {
 		logger.Error(err, "Redis PodDisruptionBudget creation failed")
 		return err
@@ -137,9 +158,9 @@ func createPodDisruptionBudget(namespace string, pdb *policyv1.PodDisruptionBudg
 }
 
 // updatePodDisruptionBudget is a method to update PodDisruptionBudgets in Kubernetes
-func updatePodDisruptionBudget(namespace string, pdb *policyv1.PodDisruptionBudget) error {
+func updatePodDisruptionBudget(cl client.Client, namespace string, pdb *policyv1.PodDisruptionBudget) error {
 	logger := pdbLogger(namespace, pdb.Name)
-	_, err := generateK8sClient().PolicyV1().PodDisruptionBudgets(namespace).Update(context.TODO(), pdb, metav1.UpdateOptions{})
+	err := cl.Update(context.TODO(), pdb)
 	if err != nil {
 		logger.Error(err, "Redis PodDisruptionBudget update failed")
 		return err
@@ -149,9 +170,9 @@ func updatePodDisruptionBudget(namespace string, pdb *policyv1.PodDisruptionBudg
 }
 
 // deletePodDisruptionBudget is a method to delete PodDisruptionBudgets in Kubernetes
-func deletePodDisruptionBudget(namespace string, pdbName string) error {
+func deletePodDisruptionBudget(cl client.Client, namespace string, pdbName string) error {
 	logger := pdbLogger(namespace, pdbName)
-	err := generateK8sClient().PolicyV1().PodDisruptionBudgets(namespace).Delete(context.TODO(), pdbName, metav1.DeleteOptions{})
+	err := cl.Delete(context.TODO(), &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: pdbName, Namespace: namespace}})
 	if err != nil {
 		logger.Error(err, "Redis PodDisruption deletion failed")
 		return err
@@ -161,12 +182,10 @@ func deletePodDisruptionBudget(namespace string, pdbName string) error {
 }
 
 // GetPodDisruptionBudget is a method to get PodDisruptionBudgets in Kubernetes
-func GetPodDisruptionBudget(namespace string, pdb string) (*policyv1.PodDisruptionBudget, error) {
+func GetPodDisruptionBudget(cl client.Client, namespace string, pdb string) (*policyv1.PodDisruptionBudget, error) {
 	logger := pdbLogger(namespace, pdb)
-	getOpts := metav1.GetOptions{
-		TypeMeta: generateMetaInformation("PodDisruptionBudget", "policy/v1"),
-	}
-	pdbInfo, err := generateK8sClient().PolicyV1().PodDisruptionBudgets(namespace).Get(context.TODO(), pdb, getOpts)
+	pdbInfo := &policyv1.PodDisruptionBudget{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: pdb}, pdbInfo)
 	if err != nil {
 		logger.Info("Redis PodDisruptionBudget get action failed")
 		return nil, err
@@ -0,0 +1,115 @@
+package k8sutils
+
+import (
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestGeneratePodDisruptionBudgetDef(t *testing.T) {
+	one, two := int32(1), int32(2)
+	var tests = []struct {
+		name           string
+		replicas       int32
+		params         redisv1beta1.RedisPodDisruptionBudget
+		minAvailable   *intstr.IntOrString
+		maxUnavailable *intstr.IntOrString
+	}{
+		{"quorum of three", 3, redisv1beta1.RedisPodDisruptionBudget{Enabled: true}, &intstr.IntOrString{IntVal: 2}, nil},
+		{"quorum of six", 6, redisv1beta1.RedisPodDisruptionBudget{Enabled: true}, &intstr.IntOrString{IntVal: 4}, nil},
+		{"min available", 6, redisv1beta1.RedisPodDisruptionBudget{Enabled: true, MinAvailable: &two}, &intstr.IntOrString{IntVal: 2}, nil},
+		{"max unavailable", 6, redisv1beta1.RedisPodDisruptionBudget{Enabled: true, MaxUnavailable: &one}, nil, &intstr.IntOrString{IntVal: 1}},
+	}
+
+	for _, tt := range tests {
+		pdb := generatePodDisruptionBudgetDef(metav1.ObjectMeta{Name: "redis"}, map[string]string{"app": "redis"}, tt.replicas, &tt.params)
+		if !sameIntOrString(pdb.Spec.MinAvailable, tt.minAvailable) || !sameIntOrString(pdb.Spec.MaxUnavailable, tt.maxUnavailable) {
+			t.Errorf("%s: got minAvailable %v and maxUnavailable %v, want %v and %v", tt.name, pdb.Spec.MinAvailable, pdb.Spec.MaxUnavailable, tt.minAvailable, tt.maxUnavailable)
+		}
+	}
+}
+
+// sameIntOrString compares the integer values of both, nil only equals nil
+func sameIntOrString(a, b *intstr.IntOrString) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.IntVal == b.IntVal
+}
+
+func TestReconcileRedisPodDisruptionBudgetPerRole(t *testing.T) {
+	leaders, followers, maxUnavailable := int32(3), int32(6), int32(2)
+	cr := &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default"},
+		Spec: redisv1beta1.RedisClusterSpec{
+			Size:          &leaders,
+			RedisLeader:   redisv1beta1.RedisLeader{PodDisruptionBudget: &redisv1beta1.RedisPodDisruptionBudget{Enabled: false}},
+			RedisFollower: redisv1beta1.RedisFollower{Replicas: &followers, PodDisruptionBudget: &redisv1beta1.RedisPodDisruptionBudget{Enabled: true, MaxUnavailable: &maxUnavailable}},
+		},
+	}
+	cl := newFakeClient()
+	for _, role := range []string{"leader", "follower"} {
+		var params *redisv1beta1.RedisPodDisruptionBudget
+		if role == "leader" {
+			params = cr.Spec.RedisLeader.PodDisruptionBudget
+		} else {
+			params = cr.Spec.RedisFollower.PodDisruptionBudget
+		}
+		if err := ReconcileRedisPodDisruptionBudget(cr, cl, role, params); err != nil {
+			t.Fatalf("ReconcileRedisPodDisruptionBudget(%s) returned %v", role, err)
+		}
+	}
+
+	if _, err := GetPodDisruptionBudget(cl, "default", "redis-cluster-leader"); !errors.IsNotFound(err) {
+		t.Errorf("got leader PodDisruptionBudget with %v, want none", err)
+	}
+	pdb, err := GetPodDisruptionBudget(cl, "default", "redis-cluster-follower")
+	if err != nil {
+		t.Fatalf("could not get follower PodDisruptionBudget: %v", err)
+	}
+	if pdb.Spec.MaxUnavailable == nil || pdb.Spec.MaxUnavailable.IntVal != 2 || pdb.Spec.MinAvailable != nil {
+		t.Errorf("got follower PodDisruptionBudget spec %+v, want maxUnavailable 2", pdb.Spec)
+	}
+	if got := pdb.Spec.Selector.MatchLabels["app"]; got != "redis-cluster-follower" {
+		t.Errorf("got selector app %q, want redis-cluster-follower", got)
+	}
+}
+
+func TestReconcileStandalonePodDisruptionBudget(t *testing.T) {
+	cr := &redisv1beta1.Redis{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default", UID: "uid"},
+		Spec:       redisv1beta1.RedisSpec{PodDisruptionBudget: &redisv1beta1.RedisPodDisruptionBudget{Enabled: true}},
+	}
+	cl := newFakeClient()
+	if err := ReconcileStandalonePodDisruptionBudget(cr, cl); err != nil {
+		t.Fatalf("ReconcileStandalonePodDisruptionBudget() returned %v", err)
+	}
+	pdb, err := GetPodDisruptionBudget(cl, "default", "redis")
+	if err != nil {
+		t.Fatalf("could not get PodDisruptionBudget: %v", err)
+	}
+	// a quorum of the single pod would block every drain
+	if pdb.Spec.MinAvailable != nil || pdb.Spec.MaxUnavailable == nil || pdb.Spec.MaxUnavailable.IntVal != 1 || pdb.Spec.Selector.MatchLabels["role"] != "standalone" {
+		t.Errorf("got PodDisruptionBudget spec %+v", pdb.Spec)
+	}
+
+	minAvailable := int32(1)
+	cr.Spec.PodDisruptionBudget.MinAvailable = &minAvailable
+	if err := ReconcileStandalonePodDisruptionBudget(cr, cl); err != nil {
+		t.Fatalf("ReconcileStandalonePodDisruptionBudget() returned %v", err)
+	}
+	if pdb, _ = GetPodDisruptionBudget(cl, "default", "redis"); pdb.Spec.MinAvailable == nil || pdb.Spec.MinAvailable.IntVal != 1 || pdb.Spec.MaxUnavailable != nil {
+		t.Errorf("got PodDisruptionBudget spec %+v, want the explicit minAvailable", pdb.Spec)
+	}
+
+	cr.Spec.PodDisruptionBudget.Enabled = false
+	if err := ReconcileStandalonePodDisruptionBudget(cr, cl); err != nil {
+		t.Fatalf("ReconcileStandalonePodDisruptionBudget() returned %v", err)
+	}
+	if _, err := GetPodDisruptionBudget(cl, "default", "redis"); !errors.IsNotFound(err) {
+		t.Errorf("got PodDisruptionBudget with %v after disabling it", err)
+	}
+}
@@ -0,0 +1,313 @@
+package k8sutils
+
+import (
+	"context"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/banzaicloud/k8s-objectmatcher/patch"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultProxyImage    = "envoyproxy/envoy:v1.24.1"
+	defaultProxyReplicas = int32(2)
+	proxyContainer       = "proxy"
+	proxyConfigKey       = "envoy.yaml"
+	proxyConfigDir       = "/etc/envoy"
+	// proxyAuthDir holds the password of the cluster under the key proxyAuthFile
+	proxyAuthDir     = "/etc/redis-proxy/auth"
+	proxyAuthFile    = "password"
+	proxyAdminPort   = 9901
+	proxyClusterName = "redis-cluster"
+)
+
+// proxyLogger will generate logging interface for the proxy of a cluster
+func proxyLogger(namespace string, name string) logr.Logger {
+	reqLogger := log.WithValues("Request.Proxy.Namespace", namespace, "Request.Proxy.Name", name)
+	return reqLogger
+}
+
+// isProxyEnabled returns true if the cluster wants the proxy tier
+func isProxyEnabled(cr *redisv1beta1.RedisCluster) bool {
+	return cr.Spec.Proxy != nil && cr.Spec.Proxy.Enabled
+}
+
+// getRedisProxySeed returns the name of the headless service of the leaders, envoy resolves it to the leader
+// pods and discovers the topology from them with CLUSTER SLOTS, so the config does not change on failovers
+func getRedisProxySeed(cr *redisv1beta1.RedisCluster) string {
+	return cr.ObjectMeta.Name + "-leader-headless." + cr.Namespace + ".svc"
+}
+
+// dataSource is an envoy DataSource reading the file
+func dataSource(filename string) map[string]interface{} {
+	return map[string]interface{}{"filename": filename}
+}
+
+// socketAddress is an envoy Address of the host and port
+func socketAddress(host string, port int) map[string]interface{} {
+	return map[string]interface{}{"socket_address": map[string]interface{}{"address": host, "port_value": port}}
+}
+
+// generateRedisProxyConfig renders the envoy bootstrap with a redis_proxy listener on the redis port and a
+// redis cluster seeded by the headless service of the leaders. The password and the certificates are read from
+// the mounted secrets.
+func generateRedisProxyConfig(cr *redisv1beta1.RedisCluster) (string, error) {
+	proxy := map[string]interface{}{
+		"@type":       "type.googleapis.com/envoy.extensions.filters.network.redis_proxy.v3.RedisProxy",
+		"stat_prefix": "redis",
+		"settings": map[string]interface{}{
+			"op_timeout":           "5s",
+			"enable_redirection":   true,
+			"enable_hashtagging":   true,
+			"enable_command_stats": false,
+		},
+		"prefix_routes": map[string]interface{}{
+			"catch_all_route": map[string]interface{}{"cluster": proxyClusterName},
+		},
+	}
+	endpoints := []interface{}{map[string]interface{}{
+		"endpoint": map[string]interface{}{"address": socketAddress(getRedisProxySeed(cr), redisPort)},
+	}}
+	cluster := map[string]interface{}{
+		"name":            proxyClusterName,
+		"connect_timeout": "1s",
+		"lb_policy":       "CLUSTER_PROVIDED",
+		"cluster_type": map[string]interface{}{
+			"name": "envoy.clusters.redis",
+			"typed_config": map[string]interface{}{
+				"@type":                     "type.googleapis.com/envoy.extensions.clusters.redis.v3.RedisClusterConfig",
+				"cluster_refresh_rate":      "5s",
+				"cluster_refresh_timeout":   "3s",
+				"redirect_refresh_interval": "1s",
+			},
+		},
+		"load_assignment": map[string]interface{}{
+			"cluster_name": proxyClusterName,
+			"endpoints":    []interface{}{map[string]interface{}{"lb_endpoints": endpoints}},
+		},
+	}
+
+	if cr.Spec.KubernetesConfig.ExistingPasswordSecret != nil {
+		password := dataSource(proxyAuthDir + "/" + proxyAuthFile)
+		if cr.Spec.Proxy.AuthPassthrough == nil || *cr.Spec.Proxy.AuthPassthrough {
+			proxy["downstream_auth_passwords"] = []interface{}{password}
+		}
+		cluster["typed_extension_protocol_options"] = map[string]interface{}{
+			"envoy.filters.network.redis_proxy": map[string]interface{}{
+				"@type":         "type.googleapis.com/envoy.extensions.filters.network.redis_proxy.v3.RedisProtocolOptions",
+				"auth_password": password,
+			},
+		}
+	}
+	filterChain := map[string]interface{}{
+		"filters": []interface{}{map[string]interface{}{
+			"name":         "envoy.filters.network.redis_proxy",
+			"typed_config": proxy,
+		}},
+	}
+	if cr.Spec.TLS != nil {
+		caCert, tlsCert, tlsCertKey := getTLSFilePaths(cr.Spec.TLS)
+		commonTLSContext := map[string]interface{}{
+			"tls_certificates": []interface{}{map[string]interface{}{
+				"certificate_chain": dataSource(tlsCert),
+				"private_key":       dataSource(tlsCertKey),
+			}},
+			"validation_context": map[string]interface{}{"trusted_ca": dataSource(caCert)},
+		}
+		cluster["transport_socket"] = map[string]interface{}{
+			"name": "envoy.transport_sockets.tls",
+			"typed_config": map[string]interface{}{
+				"@type":              "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.UpstreamTlsContext",
+				"common_tls_context": commonTLSContext,
+			},
+		}
+		// the clients connect with TLS like to the servers, client certificates are optional as with tls-auth-clients
+		filterChain["transport_socket"] = map[string]interface{}{
+			"name": "envoy.transport_sockets.tls",
+			"typed_config": map[string]interface{}{
+				"@type":                      "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext",
+				"common_tls_context":         commonTLSContext,
+				"require_client_certificate": false,
+			},
+		}
+	}
+
+	bootstrap := map[string]interface{}{
+		"admin": map[string]interface{}{"address": socketAddress("127.0.0.1", proxyAdminPort)},
+		"static_resources": map[string]interface{}{
+			"listeners": []interface{}{map[string]interface{}{
+				"name":          "redis",
+				"address":       socketAddress("0.0.0.0", redisPort),
+				"filter_chains": []interface{}{filterChain},
+			}},
+			"clusters": []interface{}{cluster},
+		},
+	}
+	config, err := yaml.Marshal(bootstrap)
+	if err != nil {
+		return "", err
+	}
+	return "# generated by the redis operator from the spec of the cluster, changes are overwritten\n" + string(config), nil
+}
+
+// generateRedisProxyDeployment returns the Deployment of the proxy, the config hash rolls it when the spec changes
+// the config, e.g. the password secret or TLS
+func generateRedisProxyDeployment(cr *redisv1beta1.RedisCluster, proxyMeta metav1.ObjectMeta, config string) *appsv1.Deployment {
+	proxy := cr.Spec.Proxy
+	replicas := defaultProxyReplicas
+	if proxy.Replicas != nil {
+		replicas = *proxy.Replicas
+	}
+	image := proxy.Image
+	if image == "" {
+		image = defaultProxyImage
+	}
+	container := corev1.Container{
+		Name:            proxyContainer,
+		Image:           image,
+		ImagePullPolicy: proxy.ImagePullPolicy,
+		Args:            []string{"-c", proxyConfigDir + "/" + proxyConfigKey},
+		Ports:           []corev1.ContainerPort{{Name: "redis-client", ContainerPort: redisPort, Protocol: corev1.ProtocolTCP}},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(redisPort)}},
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: "proxy-config", MountPath: proxyConfigDir, ReadOnly: true}},
+	}
+	if proxy.Resources != nil {
+		container.Resources = *proxy.Resources
+	}
+	volumes := []corev1.Volume{{
+		Name: "proxy-config",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: proxyMeta.Name}},
+		},
+	}}
+	if secret := cr.Spec.KubernetesConfig.ExistingPasswordSecret; secret != nil && secret.Name != nil && secret.Key != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "proxy-auth",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: *secret.Name,
+					Items:      []corev1.KeyToPath{{Key: *secret.Key, Path: proxyAuthFile}},
+				},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: "proxy-auth", MountPath: proxyAuthDir, ReadOnly: true})
+	}
+	if cr.Spec.TLS != nil {
+		volumes = append(volumes, corev1.Volume{Name: "tls-certs", VolumeSource: corev1.VolumeSource{Secret: &cr.Spec.TLS.Secret}})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: "tls-certs", MountPath: "/tls", ReadOnly: true})
+	}
+
+	deployment := &appsv1.Deployment{
+		TypeMeta:   generateMetaInformation("Deployment", "apps/v1"),
+		ObjectMeta: proxyMeta,
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: LabelSelectors(proxyMeta.GetLabels()),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      proxyMeta.GetLabels(),
+					Annotations: map[string]string{redisConfigHashAnnotation: configHash(config)},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{container},
+					Volumes:    volumes,
+				},
+			},
+		},
+	}
+	if cr.Spec.KubernetesConfig.ImagePullSecrets != nil {
+		deployment.Spec.Template.Spec.ImagePullSecrets = *cr.Spec.KubernetesConfig.ImagePullSecrets
+	}
+	AddOwnerRefToObject(deployment, redisClusterAsOwner(cr))
+	return deployment
+}
+
+// createOrUpdateDeployment creates the Deployment or updates it if it differs from the last applied one
+func createOrUpdateDeployment(cl client.Client, deployment *appsv1.Deployment) error {
+	logger := proxyLogger(deployment.Namespace, deployment.Name)
+	stored := &appsv1.Deployment{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: deployment.Namespace, Name: deployment.Name}, stored)
+	if errors.IsNotFound(err) {
+		if err := patch.DefaultAnnotator.SetLastAppliedAnnotation(deployment); err != nil {
+			logger.Error(err, "Unable to patch proxy deployment with comparison object")
+			return err
+		}
+		if err := cl.Create(context.TODO(), deployment); err != nil {
+			logger.Error(err, "Proxy deployment creation failed")
+			return err
+		}
+		logger.Info("Proxy deployment successfully created")
+		return nil
+	}
+	if err != nil {
+		logger.Error(err, "Proxy deployment get action failed")
+		return err
+	}
+	deployment.ResourceVersion = stored.ResourceVersion
+	deployment.CreationTimestamp = stored.CreationTimestamp
+	deployment.ManagedFields = stored.ManagedFields
+	patchResult, err := patch.DefaultPatchMaker.Calculate(stored, deployment,
+		patch.IgnoreStatusFields(),
+		patch.IgnoreField("kind"),
+		patch.IgnoreField("apiVersion"),
+	)
+	if err != nil {
+		logger.Error(err, "Unable to patch proxy deployment with comparison object")
+		return err
+	}
+	if patchResult.IsEmpty() {
+		return nil
+	}
+	logger.Info("Changes in proxy deployment Detected, Updating...", "patch", string(patchResult.Patch))
+	if err := patch.DefaultAnnotator.SetLastAppliedAnnotation(deployment); err != nil {
+		logger.Error(err, "Unable to patch proxy deployment with comparison object")
+		return err
+	}
+	if err := cl.Update(context.TODO(), deployment); err != nil {
+		logger.Error(err, "Proxy deployment update failed")
+		return err
+	}
+	logger.Info("Proxy deployment successfully updated")
+	return nil
+}
+
+// ReconcileRedisClusterProxy deploys the proxy of the cluster, the garbage collection removes it once it is
+// disabled. The config only depends on the spec, envoy follows failovers and resharding on its own.
+func ReconcileRedisClusterProxy(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
+	if !isProxyEnabled(cr) {
+		return nil
+	}
+	name := cr.ObjectMeta.Name + "-proxy"
+	logger := proxyLogger(cr.Namespace, name)
+	config, err := generateRedisProxyConfig(cr)
+	if err != nil {
+		logger.Error(err, "Could not generate the proxy config")
+		return err
+	}
+
+	labels := getRedisLabels(name, "cluster", "proxy", cr.ObjectMeta.Labels)
+	proxyMeta := generateObjectMetaInformation(name, cr.Namespace, labels, generateServiceAnots(cr.ObjectMeta))
+	changed, err := createOrUpdateConfigMap(cl, generateObjectMetaInformation(name, cr.Namespace, labels, nil), redisClusterAsOwner(cr), proxyConfigKey, config)
+	if err != nil {
+		return err
+	}
+	if changed {
+		recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonProxyConfigured, "Generated the proxy config seeded by %s", getRedisProxySeed(cr))
+	}
+	if err := createOrUpdateDeployment(cl, generateRedisProxyDeployment(cr, proxyMeta, config)); err != nil {
+		return err
+	}
+	return CreateOrUpdateService(cl, cr.Namespace, proxyMeta, redisClusterAsOwner(cr), false, false)
+}
@@ -0,0 +1,110 @@
+package k8sutils
+
+import (
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGenerateRedisProxyConfig(t *testing.T) {
+	secretName, secretKey := "redis-secret", "password"
+	passthrough := false
+	var tests = []struct {
+		name     string
+		secret   *redisv1beta1.ExistingPasswordSecret
+		proxy    redisv1beta1.RedisProxy
+		tls      *redisv1beta1.TLSConfig
+		contains []string
+		missing  []string
+	}{
+		{
+			name:     "without password",
+			proxy:    redisv1beta1.RedisProxy{Enabled: true},
+			contains: []string{"address: redis-cluster-leader-headless.default.svc", "port_value: 6379", "lb_policy: CLUSTER_PROVIDED", "enable_redirection: true", "cluster: redis-cluster"},
+			missing:  []string{"auth_password", "downstream_auth_passwords", "transport_socket"},
+		},
+		{
+			name:     "with auth passthrough",
+			secret:   &redisv1beta1.ExistingPasswordSecret{Name: &secretName, Key: &secretKey},
+			proxy:    redisv1beta1.RedisProxy{Enabled: true},
+			contains: []string{"auth_password:", "downstream_auth_passwords:", "filename: /etc/redis-proxy/auth/password"},
+		},
+		{
+			name:     "without auth passthrough",
+			secret:   &redisv1beta1.ExistingPasswordSecret{Name: &secretName, Key: &secretKey},
+			proxy:    redisv1beta1.RedisProxy{Enabled: true, AuthPassthrough: &passthrough},
+			contains: []string{"auth_password:"},
+			missing:  []string{"downstream_auth_passwords"},
+		},
+		{
+			name:     "with tls",
+			proxy:    redisv1beta1.RedisProxy{Enabled: true},
+			tls:      &redisv1beta1.TLSConfig{CaKeyFile: "root.crt"},
+			contains: []string{"UpstreamTlsContext", "DownstreamTlsContext", "require_client_certificate: false", "filename: /tls/root.crt", "filename: /tls/tls.crt", "filename: /tls/tls.key"},
+		},
+	}
+
+	for _, tt := range tests {
+		proxy := tt.proxy
+		cr := &redisv1beta1.RedisCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default"},
+			Spec: redisv1beta1.RedisClusterSpec{
+				KubernetesConfig: redisv1beta1.KubernetesConfig{ExistingPasswordSecret: tt.secret},
+				TLS:              tt.tls,
+				Proxy:            &proxy,
+			},
+		}
+		config, err := generateRedisProxyConfig(cr)
+		if err != nil {
+			t.Fatalf("%s: generateRedisProxyConfig() returned %v", tt.name, err)
+		}
+		for _, line := range tt.contains {
+			if !strings.Contains(config, line) {
+				t.Errorf("%s: config misses %q:\n%s", tt.name, line, config)
+			}
+		}
+		for _, line := range tt.missing {
+			if strings.Contains(config, line) {
+				t.Errorf("%s: config has %q:\n%s", tt.name, line, config)
+			}
+		}
+	}
+}
+
+func TestGenerateRedisProxyDeployment(t *testing.T) {
+	secretName, secretKey := "redis-secret", "password"
+	replicas := int32(3)
+	cr := &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default", UID: "uid"},
+		Spec: redisv1beta1.RedisClusterSpec{
+			KubernetesConfig: redisv1beta1.KubernetesConfig{ExistingPasswordSecret: &redisv1beta1.ExistingPasswordSecret{Name: &secretName, Key: &secretKey}},
+			Proxy: &redisv1beta1.RedisProxy{
+				Enabled:   true,
+				Replicas:  &replicas,
+				Resources: &corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+			},
+		},
+	}
+	labels := getRedisLabels("redis-cluster-proxy", "cluster", "proxy", nil)
+	proxyMeta := generateObjectMetaInformation("redis-cluster-proxy", "default", labels, nil)
+	deployment := generateRedisProxyDeployment(cr, proxyMeta, "config")
+
+	if *deployment.Spec.Replicas != 3 || deployment.Spec.Template.Annotations[redisConfigHashAnnotation] != configHash("config") {
+		t.Errorf("got deployment spec %+v", deployment.Spec)
+	}
+	container := deployment.Spec.Template.Spec.Containers[0]
+	if container.Image != defaultProxyImage || container.Resources.Limits.Cpu().String() != "500m" {
+		t.Errorf("got container %+v", container)
+	}
+	volumes := deployment.Spec.Template.Spec.Volumes
+	if len(volumes) != 2 || volumes[0].ConfigMap.Name != "redis-cluster-proxy" || volumes[1].Secret.SecretName != secretName || volumes[1].Secret.Items[0].Path != proxyAuthFile {
+		t.Errorf("got volumes %+v, want the config and the password", volumes)
+	}
+	if len(deployment.OwnerReferences) != 1 || deployment.OwnerReferences[0].UID != "uid" {
+		t.Errorf("got owner references %+v", deployment.OwnerReferences)
+	}
+}
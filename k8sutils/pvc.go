@@ -0,0 +1,120 @@
+package k8sutils
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// pvcLogger will generate logging interface for PVCs
+func pvcLogger(namespace string, name string) logr.Logger {
+	reqLogger := log.WithValues("Request.PVC.Namespace", namespace, "Request.PVC.Name", name)
+	return reqLogger
+}
+
+// listRedisPVCs returns the PVCs of the statefulset, they are selected by the labels which the volume claim
+// template copies from the statefulset, so that the PVCs of pods beyond the current replicas are found as well.
+// The labels of the custom resource override app and role, so the selector is taken from the volume claim
+// template of the statefulset, or built like the labels of the statefulset while it does not exist.
+func listRedisPVCs(cl client.Client, namespace, stsName, setupType, role string, crLabels map[string]string) ([]corev1.PersistentVolumeClaim, error) {
+	selector := getRedisLabels(stsName, setupType, role, crLabels)
+	if sts, err := GetStatefulSet(cl, namespace, stsName); err == nil {
+		for _, template := range sts.Spec.VolumeClaimTemplates {
+			if template.Name == stsName && len(template.Labels) > 0 {
+				selector = template.Labels
+			}
+		}
+	}
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	err := cl.List(context.TODO(), pvcs, client.InNamespace(namespace), client.MatchingLabels(selector))
+	if err != nil {
+		pvcLogger(namespace, stsName).Error(err, "Could not list the Persistent Volume Claims of the statefulset")
+		return nil, err
+	}
+	// custom resources sharing their labels would select each other's PVCs, the name tells them apart
+	var owned []corev1.PersistentVolumeClaim
+	for _, pvc := range pvcs.Items {
+		if _, ok := pvcOrdinal(pvc, stsName); ok {
+			owned = append(owned, pvc)
+		}
+	}
+	return owned, nil
+}
+
+// pvcOrdinal returns the ordinal of the pod the PVC belongs to, the PVCs of a statefulset are named
+// <template>-<statefulset>-<ordinal> and the template is named like the statefulset
+func pvcOrdinal(pvc corev1.PersistentVolumeClaim, stsName string) (int, bool) {
+	prefix := stsName + "-" + stsName + "-"
+	if !strings.HasPrefix(pvc.Name, prefix) {
+		return 0, false
+	}
+	ordinal, err := strconv.Atoi(strings.TrimPrefix(pvc.Name, prefix))
+	if err != nil {
+		return 0, false
+	}
+	return ordinal, true
+}
+
+// deleteRedisPVC deletes the PVC, a PVC which is gone already is no error
+func deleteRedisPVC(cl client.Client, pvc *corev1.PersistentVolumeClaim) error {
+	err := cl.Delete(context.TODO(), pvc)
+	if err != nil && !errors.IsNotFound(err) {
+		pvcLogger(pvc.Namespace, pvc.Name).Error(err, "Could not delete Persistent Volume Claim "+pvc.Name)
+		return err
+	}
+	return nil
+}
+
+// DeleteScaledDownRedisClusterPVCs deletes the PVCs of the pods removed by a scale-down of the leader and follower
+// statefulsets if the retention policy asks for it. A PVC is only deleted once its pod is gone.
+func DeleteScaledDownRedisClusterPVCs(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
+	if !cr.Spec.Storage.DeletePVCsWhenScaled() {
+		return nil
+	}
+	for _, role := range []string{"leader", "follower"} {
+		stsName := cr.ObjectMeta.Name + "-" + role
+		sts, err := GetStatefulSet(cl, cr.Namespace, stsName)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		replicas := 1
+		if sts.Spec.Replicas != nil {
+			replicas = int(*sts.Spec.Replicas)
+		}
+		pvcs, err := listRedisPVCs(cl, cr.Namespace, stsName, "cluster", role, cr.ObjectMeta.Labels)
+		if err != nil {
+			return err
+		}
+		for i := range pvcs {
+			ordinal, ok := pvcOrdinal(pvcs[i], stsName)
+			if !ok || ordinal < replicas {
+				continue
+			}
+			podName := stsName + "-" + strconv.Itoa(ordinal)
+			err := cl.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: podName}, &corev1.Pod{})
+			if err == nil {
+				continue
+			}
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			if err := deleteRedisPVC(cl, &pvcs[i]); err != nil {
+				return err
+			}
+			recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonPVCDeleted, "Deleted pvc %s of the scaled down pod %s", pvcs[i].Name, podName)
+		}
+	}
+	return nil
+}
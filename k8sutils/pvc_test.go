@@ -0,0 +1,173 @@
+package k8sutils
+
+import (
+	"context"
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newRedisClusterPVC returns a PVC of the leader or follower pod with the labels of the volume claim template
+func newRedisClusterPVC(cluster, role string, ordinal int) *corev1.PersistentVolumeClaim {
+	stsName := cluster + "-" + role
+	return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      stsName + "-" + stsName + "-" + strconv.Itoa(ordinal),
+		Namespace: "default",
+		Labels:    getRedisLabels(stsName, "cluster", role, map[string]string{"team": "cache"}),
+	}}
+}
+
+// pvcNames returns the sorted names of the PVCs of the namespace
+func pvcNames(t *testing.T, cl client.Client) []string {
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := cl.List(context.TODO(), pvcs, client.InNamespace("default")); err != nil {
+		t.Fatalf("could not list pvcs: %v", err)
+	}
+	var names []string
+	for _, pvc := range pvcs.Items {
+		names = append(names, pvc.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPVCOrdinal(t *testing.T) {
+	var tests = []struct {
+		name    string
+		ordinal int
+		ok      bool
+	}{
+		{"redis-cluster-leader-redis-cluster-leader-4", 4, true},
+		{"redis-cluster-leader-redis-cluster-leader-x", 0, false},
+		{"data-redis-cluster-leader-0", 0, false},
+	}
+
+	for _, tt := range tests {
+		pvc := corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: tt.name}}
+		if ordinal, ok := pvcOrdinal(pvc, "redis-cluster-leader"); ordinal != tt.ordinal || ok != tt.ok {
+			t.Errorf("pvcOrdinal(%s) = %d, %t, want %d, %t", tt.name, ordinal, ok, tt.ordinal, tt.ok)
+		}
+	}
+}
+
+func TestFinalizeRedisClusterPVC(t *testing.T) {
+	size := int32(3)
+	var tests = []struct {
+		name   string
+		policy *redisv1beta1.PVCRetentionPolicy
+		want   []string
+	}{
+		// the PVCs of the pods of a previously larger cluster are deleted as well
+		{"default", nil, []string{"other"}},
+		{"retain", &redisv1beta1.PVCRetentionPolicy{WhenDeleted: redisv1beta1.PVCRetentionRetain}, []string{
+			"other",
+			"redis-cluster-follower-redis-cluster-follower-0",
+			"redis-cluster-leader-redis-cluster-leader-0",
+			"redis-cluster-leader-redis-cluster-leader-4",
+		}},
+	}
+
+	for _, tt := range tests {
+		cr := &redisv1beta1.RedisCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default"},
+			Spec:       redisv1beta1.RedisClusterSpec{Size: &size, Storage: &redisv1beta1.Storage{RetentionPolicy: tt.policy}},
+		}
+		other := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default", Labels: map[string]string{"app": "other"}}}
+		cl := newFakeClient(other, newRedisClusterPVC("redis-cluster", "leader", 0), newRedisClusterPVC("redis-cluster", "leader", 4), newRedisClusterPVC("redis-cluster", "follower", 0))
+
+		if err := finalizeRedisClusterPVC(cr, cl); err != nil {
+			t.Fatalf("%s: finalizeRedisClusterPVC() returned %v", tt.name, err)
+		}
+		if got := pvcNames(t, cl); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got pvcs %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteScaledDownRedisClusterPVCs(t *testing.T) {
+	replicas := int32(3)
+	var tests = []struct {
+		name   string
+		policy *redisv1beta1.PVCRetentionPolicy
+		want   []string
+	}{
+		{"default", nil, []string{
+			"redis-cluster-leader-redis-cluster-leader-0",
+			"redis-cluster-leader-redis-cluster-leader-3",
+			"redis-cluster-leader-redis-cluster-leader-4",
+		}},
+		// the pvc of the pod which is still terminating is kept until the pod is gone
+		{"delete", &redisv1beta1.PVCRetentionPolicy{WhenScaled: redisv1beta1.PVCRetentionDelete}, []string{
+			"redis-cluster-leader-redis-cluster-leader-0",
+			"redis-cluster-leader-redis-cluster-leader-3",
+		}},
+	}
+
+	for _, tt := range tests {
+		cr := &redisv1beta1.RedisCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default"},
+			Spec:       redisv1beta1.RedisClusterSpec{Size: &replicas, Storage: &redisv1beta1.Storage{RetentionPolicy: tt.policy}},
+		}
+		sts := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster-leader", Namespace: "default"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		}
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster-leader-3", Namespace: "default"}}
+		cl := newFakeClient(sts, pod, newRedisClusterPVC("redis-cluster", "leader", 0), newRedisClusterPVC("redis-cluster", "leader", 3), newRedisClusterPVC("redis-cluster", "leader", 4))
+
+		if err := DeleteScaledDownRedisClusterPVCs(cr, cl, nil); err != nil {
+			t.Fatalf("%s: DeleteScaledDownRedisClusterPVCs() returned %v", tt.name, err)
+		}
+		if got := pvcNames(t, cl); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got pvcs %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListRedisPVCsWithCustomResourceLabels(t *testing.T) {
+	labels := map[string]string{"app": "myapp"}
+	stsName := "redis-cluster-leader"
+	pvc := func(name string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    getRedisLabels(stsName, "cluster", "leader", labels),
+		}}
+	}
+	// another cluster labelled app: myapp, the selector matches its PVCs as well
+	other := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+		Name:      "cache-leader-cache-leader-0",
+		Namespace: "default",
+		Labels:    getRedisLabels("cache-leader", "cluster", "leader", labels),
+	}}
+	cl := newFakeClient(pvc(stsName+"-"+stsName+"-0"), pvc(stsName+"-"+stsName+"-1"), other)
+
+	pvcs, err := listRedisPVCs(cl, "default", stsName, "cluster", "leader", labels)
+	if err != nil {
+		t.Fatalf("listRedisPVCs() returned %v", err)
+	}
+	if len(pvcs) != 2 {
+		t.Errorf("got pvcs %v, want the two of %s", pvcs, stsName)
+	}
+
+	// the labels of the volume claim template win over the current labels of the custom resource
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: stsName, Namespace: "default"},
+		Spec: appsv1.StatefulSetSpec{VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+			{ObjectMeta: metav1.ObjectMeta{Name: stsName, Labels: getRedisLabels(stsName, "cluster", "leader", labels)}},
+		}},
+	}
+	if err := cl.Create(context.TODO(), sts); err != nil {
+		t.Fatalf("could not create the statefulset: %v", err)
+	}
+	if pvcs, _ = listRedisPVCs(cl, "default", stsName, "cluster", "leader", map[string]string{"app": "renamed"}); len(pvcs) != 2 {
+		t.Errorf("got pvcs %v after the custom resource was relabelled, want the two of %s", pvcs, stsName)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	redisv1beta1 "redis-operator/api/v1beta1"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // RedisClusterSTS is a interface to call Redis Statefulset function
@@ -32,6 +34,7 @@ func generateRedisClusterParams(cr *redisv1beta1.RedisCluster, replicas int32, e
 		Tolerations:        cr.Spec.Tolerations,
 		ServiceAccountName: cr.Spec.ServiceAccountName,
 		UpdateStrategy:     cr.Spec.KubernetesConfig.UpdateStrategy,
+		PodTemplate:        cr.Spec.PodTemplate,
 	}
 	if cr.Spec.RedisExporter != nil {
 		res.EnableMetrics = cr.Spec.RedisExporter.Enabled
@@ -57,6 +60,9 @@ func generateRedisClusterContainerParams(cr *redisv1beta1.RedisCluster, readines
 		Image:           cr.Spec.KubernetesConfig.Image,
 		ImagePullPolicy: cr.Spec.KubernetesConfig.ImagePullPolicy,
 		Resources:       cr.Spec.KubernetesConfig.Resources,
+		ImageMode:       cr.Spec.KubernetesConfig.ImageMode,
+		Modules:         cr.Spec.Modules,
+		Memory:          cr.Spec.Memory,
 	}
 	if cr.Spec.KubernetesConfig.ExistingPasswordSecret != nil {
 		containerProp.EnabledPassword = &trueProperty
@@ -97,7 +103,7 @@ func generateRedisClusterContainerParams(cr *redisv1beta1.RedisCluster, readines
 }
 
 // CreateRedisLeader will create a leader redis setup
-func CreateRedisLeader(cr *redisv1beta1.RedisCluster) error {
+func CreateRedisLeader(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
 	prop := RedisClusterSTS{
 		RedisStateFulType: "leader",
 		Affinity:          cr.Spec.RedisLeader.Affinity,
@@ -107,11 +113,11 @@ func CreateRedisLeader(cr *redisv1beta1.RedisCluster) error {
 	if cr.Spec.RedisLeader.RedisConfig != nil {
 		prop.ExternalConfig = cr.Spec.RedisLeader.RedisConfig.AdditionalRedisConfig
 	}
-	return prop.CreateRedisClusterSetup(cr)
+	return prop.CreateRedisClusterSetup(cr, cl, recorder)
 }
 
 // CreateRedisFollower will create a follower redis setup
-func CreateRedisFollower(cr *redisv1beta1.RedisCluster) error {
+func CreateRedisFollower(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
 	prop := RedisClusterSTS{
 		RedisStateFulType: "follower",
 		Affinity:          cr.Spec.RedisFollower.Affinity,
@@ -121,23 +127,23 @@ func CreateRedisFollower(cr *redisv1beta1.RedisCluster) error {
 	if cr.Spec.RedisFollower.RedisConfig != nil {
 		prop.ExternalConfig = cr.Spec.RedisFollower.RedisConfig.AdditionalRedisConfig
 	}
-	return prop.CreateRedisClusterSetup(cr)
+	return prop.CreateRedisClusterSetup(cr, cl, recorder)
 }
 
 // CreateRedisLeaderService method will create service for Redis Leader
-func CreateRedisLeaderService(cr *redisv1beta1.RedisCluster) error {
+func CreateRedisLeaderService(cr *redisv1beta1.RedisCluster, cl client.Client) error {
 	prop := RedisClusterService{
 		RedisServiceRole: "leader",
 	}
-	return prop.CreateRedisClusterService(cr)
+	return prop.CreateRedisClusterService(cr, cl)
 }
 
 // CreateRedisFollowerService method will create service for Redis Follower
-func CreateRedisFollowerService(cr *redisv1beta1.RedisCluster) error {
+func CreateRedisFollowerService(cr *redisv1beta1.RedisCluster, cl client.Client) error {
 	prop := RedisClusterService{
 		RedisServiceRole: "follower",
 	}
-	return prop.CreateRedisClusterService(cr)
+	return prop.CreateRedisClusterService(cr, cl)
 }
 
 func (service RedisClusterSTS) getReplicaCount(cr *redisv1beta1.RedisCluster) int32 {
@@ -145,7 +151,7 @@ func (service RedisClusterSTS) getReplicaCount(cr *redisv1beta1.RedisCluster) in
 }
 
 // CreateRedisClusterSetup will create Redis Setup for leader and follower
-func (service RedisClusterSTS) CreateRedisClusterSetup(cr *redisv1beta1.RedisCluster) error {
+func (service RedisClusterSTS) CreateRedisClusterSetup(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
 	stateFulName := cr.ObjectMeta.Name + "-" + service.RedisStateFulType
 	logger := statefulSetLogger(cr.Namespace, stateFulName)
 	// 把默认的和用户添加的所有label都整合起来
@@ -154,13 +160,19 @@ func (service RedisClusterSTS) CreateRedisClusterSetup(cr *redisv1beta1.RedisClu
 	annotations := generateStatefulSetsAnots(cr.ObjectMeta)
 	// 使用上面的信息构造对象Meta数据
 	objectMetaInfo := generateObjectMetaInformation(stateFulName, cr.Namespace, labels, annotations)
+	params := generateRedisClusterParams(cr, service.getReplicaCount(cr), service.ExternalConfig, service.Affinity)
+	if cr.Spec.IsTopologyAware() {
+		params.TopologySpread = generateTopologySpreadConstraints(cr, labels)
+	}
 	err := CreateOrUpdateStateFul(
+		cl,
 		cr.Namespace,
 		objectMetaInfo,
-		generateRedisClusterParams(cr, service.getReplicaCount(cr), service.ExternalConfig, service.Affinity),
+		params,
 		redisClusterAsOwner(cr),
 		generateRedisClusterContainerParams(cr, service.ReadinessProbe, service.LivenessProbe),
 		cr.Spec.Sidecars,
+		recorder,
 	)
 	if err != nil {
 		logger.Error(err, "Cannot create statefulset for Redis", "Setup.Type", service.RedisStateFulType)
@@ -170,7 +182,7 @@ func (service RedisClusterSTS) CreateRedisClusterSetup(cr *redisv1beta1.RedisClu
 }
 
 // CreateRedisClusterService method will create service for Redis
-func (service RedisClusterService) CreateRedisClusterService(cr *redisv1beta1.RedisCluster) error {
+func (service RedisClusterService) CreateRedisClusterService(cr *redisv1beta1.RedisCluster, cl client.Client) error {
 	serviceName := cr.ObjectMeta.Name + "-" + service.RedisServiceRole
 	logger := serviceLogger(cr.Namespace, serviceName)
 	labels := getRedisLabels(serviceName, "cluster", service.RedisServiceRole, cr.ObjectMeta.Labels)
@@ -180,12 +192,12 @@ func (service RedisClusterService) CreateRedisClusterService(cr *redisv1beta1.Re
 	}
 	objectMetaInfo := generateObjectMetaInformation(serviceName, cr.Namespace, labels, annotations)
 	headlessObjectMetaInfo := generateObjectMetaInformation(serviceName+"-headless", cr.Namespace, labels, annotations)
-	err := CreateOrUpdateService(cr.Namespace, headlessObjectMetaInfo, redisClusterAsOwner(cr), false, true)
+	err := CreateOrUpdateService(cl, cr.Namespace, headlessObjectMetaInfo, redisClusterAsOwner(cr), false, true)
 	if err != nil {
 		logger.Error(err, "Cannot create headless service for Redis", "Setup.Type", service.RedisServiceRole)
 		return err
 	}
-	err = CreateOrUpdateService(cr.Namespace, objectMetaInfo, redisClusterAsOwner(cr), enableMetrics, false)
+	err = CreateOrUpdateService(cl, cr.Namespace, objectMetaInfo, redisClusterAsOwner(cr), enableMetrics, false)
 	if err != nil {
 		logger.Error(err, "Cannot create service for Redis", "Setup.Type", service.RedisServiceRole)
 		return err
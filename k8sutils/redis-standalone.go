@@ -2,6 +2,9 @@ package k8sutils
 
 import (
 	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var (
@@ -9,7 +12,7 @@ var (
 )
 
 // CreateStandaloneService method will create standalone service for Redis
-func CreateStandaloneService(cr *redisv1beta1.Redis) error {
+func CreateStandaloneService(cr *redisv1beta1.Redis, cl client.Client) error {
 	logger := serviceLogger(cr.Namespace, cr.ObjectMeta.Name)
 	labels := getRedisLabels(cr.ObjectMeta.Name, "standalone", "standalone", cr.ObjectMeta.Labels)
 	annotations := generateServiceAnots(cr.ObjectMeta)
@@ -18,12 +21,12 @@ func CreateStandaloneService(cr *redisv1beta1.Redis) error {
 	}
 	objectMetaInfo := generateObjectMetaInformation(cr.ObjectMeta.Name, cr.Namespace, labels, annotations)
 	headlessObjectMetaInfo := generateObjectMetaInformation(cr.ObjectMeta.Name+"-headless", cr.Namespace, labels, annotations)
-	err := CreateOrUpdateService(cr.Namespace, headlessObjectMetaInfo, redisAsOwner(cr), false, true)
+	err := CreateOrUpdateService(cl, cr.Namespace, headlessObjectMetaInfo, redisAsOwner(cr), false, true)
 	if err != nil {
 		logger.Error(err, "Cannot create standalone headless service for Redis")
 		return err
 	}
-	err = CreateOrUpdateService(cr.Namespace, objectMetaInfo, redisAsOwner(cr), enableMetrics, false)
+	err = CreateOrUpdateService(cl, cr.Namespace, objectMetaInfo, redisAsOwner(cr), enableMetrics, false)
 	if err != nil {
 		logger.Error(err, "Cannot create standalone service for Redis")
 		return err
@@ -32,17 +35,19 @@ func CreateStandaloneService(cr *redisv1beta1.Redis) error {
 }
 
 // CreateStandaloneRedis will create a standalone redis setup
-func CreateStandaloneRedis(cr *redisv1beta1.Redis) error {
+func CreateStandaloneRedis(cr *redisv1beta1.Redis, cl client.Client, recorder record.EventRecorder) error {
 	logger := statefulSetLogger(cr.Namespace, cr.ObjectMeta.Name)
 	labels := getRedisLabels(cr.ObjectMeta.Name, "standalone", "standalone", cr.ObjectMeta.Labels)
 	annotations := generateStatefulSetsAnots(cr.ObjectMeta)
 	objectMetaInfo := generateObjectMetaInformation(cr.ObjectMeta.Name, cr.Namespace, labels, annotations)
-	err := CreateOrUpdateStateFul(cr.Namespace,
+	err := CreateOrUpdateStateFul(cl,
+		cr.Namespace,
 		objectMetaInfo,
 		generateRedisStandaloneParams(cr),
 		redisAsOwner(cr),
 		generateRedisStandaloneContainerParams(cr),
 		cr.Spec.Sidecars,
+		recorder,
 	)
 	if err != nil {
 		logger.Error(err, "Cannot create standalone statefulset for Redis")
@@ -62,6 +67,7 @@ func generateRedisStandaloneParams(cr *redisv1beta1.Redis) statefulSetParameters
 		Affinity:          cr.Spec.Affinity,
 		Tolerations:       cr.Spec.Tolerations,
 		UpdateStrategy:    cr.Spec.KubernetesConfig.UpdateStrategy,
+		PodTemplate:       cr.Spec.PodTemplate,
 	}
 	if cr.Spec.KubernetesConfig.ImagePullSecrets != nil {
 		res.ImagePullSecrets = cr.Spec.KubernetesConfig.ImagePullSecrets
@@ -91,6 +97,9 @@ func generateRedisStandaloneContainerParams(cr *redisv1beta1.Redis) containerPar
 		Image:           cr.Spec.KubernetesConfig.Image,
 		ImagePullPolicy: cr.Spec.KubernetesConfig.ImagePullPolicy,
 		Resources:       cr.Spec.KubernetesConfig.Resources,
+		ImageMode:       cr.Spec.KubernetesConfig.ImageMode,
+		Modules:         cr.Spec.Modules,
+		Memory:          cr.Spec.Memory,
 	}
 	if cr.Spec.KubernetesConfig.ExistingPasswordSecret != nil {
 		containerProp.EnabledPassword = &trueProperty
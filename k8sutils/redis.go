@@ -1,7 +1,6 @@
 package k8sutils
 
 import (
-	"bytes"
 	"context"
 	"encoding/csv"
 	"fmt"
@@ -14,9 +13,9 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/go-redis/redis/v8"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // RedisDetails will hold the information for Redis Pod
@@ -26,9 +25,10 @@ type RedisDetails struct {
 }
 
 // getRedisServerIP will return the IP of redis service
-func getRedisServerIP(redisInfo RedisDetails) string {
+func getRedisServerIP(cl client.Client, redisInfo RedisDetails) string {
 	logger := generateRedisManagerLogger(redisInfo.Namespace, redisInfo.PodName)
-	redisPod, err := generateK8sClient().CoreV1().Pods(redisInfo.Namespace).Get(context.TODO(), redisInfo.PodName, metav1.GetOptions{})
+	redisPod := &corev1.Pod{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: redisInfo.Namespace, Name: redisInfo.PodName}, redisPod)
 	if err != nil {
 		logger.Error(err, "Error in getting redis pod IP")
 	}
@@ -65,7 +65,7 @@ func CreateSingleLeaderRedisCommand(cr *redisv1beta1.RedisCluster) []string {
 }
 
 // CreateMultipleLeaderRedisCommand will create command for single leader cluster creation
-func CreateMultipleLeaderRedisCommand(cr *redisv1beta1.RedisCluster) []string {
+func CreateMultipleLeaderRedisCommand(cr *redisv1beta1.RedisCluster, cl client.Client) []string {
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
 	cmd := []string{"redis-cli", "--cluster", "create"}
 	replicas := cr.Spec.GetReplicaCounts("leader")
@@ -78,7 +78,7 @@ func CreateMultipleLeaderRedisCommand(cr *redisv1beta1.RedisCluster) []string {
 		if *cr.Spec.ClusterVersion == "v7" {
 			cmd = append(cmd, getRedisHostname(pod, cr, "leader")+":6379")
 		} else {
-			cmd = append(cmd, getRedisServerIP(pod)+":6379")
+			cmd = append(cmd, getRedisServerIP(cl, pod)+":6379")
 		}
 	}
 	cmd = append(cmd, "--cluster-yes")
@@ -88,25 +88,26 @@ func CreateMultipleLeaderRedisCommand(cr *redisv1beta1.RedisCluster) []string {
 }
 
 // ExecuteRedisClusterCommand will execute redis cluster creation command
-func ExecuteRedisClusterCommand(cr *redisv1beta1.RedisCluster) {
+func ExecuteRedisClusterCommand(cr *redisv1beta1.RedisCluster, cl client.Client, executor PodExecutor, recorder record.EventRecorder) {
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
 	var cmd []string
 	replicas := cr.Spec.GetReplicaCounts("leader")
 	switch int(replicas) {
 	case 1:
-		err := executeFailoverCommand(cr, "leader")
+		err := executeFailoverCommand(cr, cl, "leader", recorder)
 		if err != nil {
 			logger.Error(err, "error executing failover command")
 		}
 		cmd = CreateSingleLeaderRedisCommand(cr)
 	default:
-		cmd = CreateMultipleLeaderRedisCommand(cr)
+		cmd = CreateMultipleLeaderRedisCommand(cr, cl)
 	}
 
 	if cr.Spec.KubernetesConfig.ExistingPasswordSecret != nil {
-		pass, err := getRedisPassword(cr.Namespace, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Name, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Key)
+		pass, err := getRedisPassword(cl, cr.Namespace, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Name, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Key)
 		if err != nil {
 			logger.Error(err, "Error in getting redis password")
+			recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonPasswordLookupFailed, "Could not read password from secret %s: %v", *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Name, err)
 		}
 		cmd = append(cmd, "--user")
 		cmd = append(cmd, "default")
@@ -115,7 +116,11 @@ func ExecuteRedisClusterCommand(cr *redisv1beta1.RedisCluster) {
 	}
 	cmd = append(cmd, getRedisTLSArgs(cr.Spec.TLS, cr.ObjectMeta.Name+"-leader-0")...)
 	logger.Info("Redis cluster creation command is", "Command", cmd)
-	executeCommand(cr, cmd, cr.ObjectMeta.Name+"-leader-0")
+	if err := executeCommand(cr, cl, executor, cmd, cr.ObjectMeta.Name+"-leader-0"); err != nil {
+		recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonClusterCreateFailed, "Redis cluster creation with %d leaders failed: %v", replicas, err)
+		return
+	}
+	recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonClusterCreated, "Redis cluster created with %d leaders", replicas)
 }
 
 func getRedisTLSArgs(tlsConfig *redisv1beta1.TLSConfig, clientHost string) []string {
@@ -131,22 +136,23 @@ func getRedisTLSArgs(tlsConfig *redisv1beta1.TLSConfig, clientHost string) []str
 }
 
 // createRedisReplicationCommand will create redis replication creation command
-func createRedisReplicationCommand(cr *redisv1beta1.RedisCluster, leaderPod RedisDetails, followerPod RedisDetails) []string {
+func createRedisReplicationCommand(cr *redisv1beta1.RedisCluster, cl client.Client, leaderPod RedisDetails, followerPod RedisDetails, masterID string, recorder record.EventRecorder) []string {
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
 	cmd := []string{"redis-cli", "--cluster", "add-node"}
 	if *cr.Spec.ClusterVersion == "v7" {
 		cmd = append(cmd, getRedisHostname(followerPod, cr, "follower")+":6379")
 		cmd = append(cmd, getRedisHostname(leaderPod, cr, "leader")+":6379")
 	} else {
-		cmd = append(cmd, getRedisServerIP(followerPod)+":6379")
-		cmd = append(cmd, getRedisServerIP(leaderPod)+":6379")
+		cmd = append(cmd, getRedisServerIP(cl, followerPod)+":6379")
+		cmd = append(cmd, getRedisServerIP(cl, leaderPod)+":6379")
 	}
-	cmd = append(cmd, "--cluster-slave")
+	cmd = append(cmd, "--cluster-slave", "--cluster-master-id", masterID)
 
 	if cr.Spec.KubernetesConfig.ExistingPasswordSecret != nil {
-		pass, err := getRedisPassword(cr.Namespace, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Name, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Key)
+		pass, err := getRedisPassword(cl, cr.Namespace, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Name, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Key)
 		if err != nil {
 			logger.Error(err, "Error in getting redis password")
+			recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonPasswordLookupFailed, "Could not read password from secret %s: %v", *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Name, err)
 		}
 		cmd = append(cmd, "--user")
 		cmd = append(cmd, "default")
@@ -158,40 +164,139 @@ func createRedisReplicationCommand(cr *redisv1beta1.RedisCluster, leaderPod Redi
 	return cmd
 }
 
-// ExecuteRedisReplicationCommand will execute the replication command
-func ExecuteRedisReplicationCommand(cr *redisv1beta1.RedisCluster) {
-	var podIP string
-	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
-	followerCounts := cr.Spec.GetReplicaCounts("follower")
-	leaderCounts := cr.Spec.GetReplicaCounts("leader")
-	nodes := checkRedisCluster(cr)
-	for followerIdx := 0; followerIdx <= int(followerCounts)-1; followerIdx++ {
-		followerPod := RedisDetails{
-			PodName:   cr.ObjectMeta.Name + "-follower-" + strconv.Itoa(followerIdx),
-			Namespace: cr.Namespace,
+// redisClusterTopology collects the cluster nodes of the leader and follower pods
+type redisClusterTopology struct {
+	nodes []clusterNode
+	// masters are the pods whose nodes are masters serving slots, no matter which statefulset they belong
+	// to, a follower promoted by a failover heads the shard of its former leader
+	masters []redisClusterMaster
+	// zones holds the zone of every pod, it is only filled in topology aware mode
+	zones map[string]string
+	// replicas are the follower pods and the leader pods which were demoted to replicas by a failover
+	replicas []replicaCandidate
+}
+
+// redisClusterMaster is a pod whose node is a master serving slots
+type redisClusterMaster struct {
+	PodName string
+	Node    *clusterNode
+}
+
+// getRedisClusterTopology matches the CLUSTER NODES output against the pod IPs
+func getRedisClusterTopology(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) redisClusterTopology {
+	topology := redisClusterTopology{
+		nodes: parseClusterNodes(checkRedisCluster(cr, cl, recorder)),
+		zones: map[string]string{},
+	}
+	podZone := func(podName string) string {
+		if !cr.Spec.IsTopologyAware() {
+			return ""
 		}
-		leaderPod := RedisDetails{
-			PodName:   cr.ObjectMeta.Name + "-leader-" + strconv.Itoa(int(followerIdx)%int(leaderCounts)),
-			Namespace: cr.Namespace,
+		zone := getRedisPodZone(cl, cr.Namespace, podName, cr.Spec.GetZoneKey())
+		topology.zones[podName] = zone
+		return zone
+	}
+	for _, role := range []string{"leader", "follower"} {
+		for idx := 0; idx < int(cr.Spec.GetReplicaCounts(role)); idx++ {
+			podName := cr.ObjectMeta.Name + "-" + role + "-" + strconv.Itoa(idx)
+			ip := getRedisServerIP(cl, RedisDetails{PodName: podName, Namespace: cr.Namespace})
+			if ip == "" {
+				// the pod is not scheduled yet, it will be handled on a later reconcile
+				continue
+			}
+			node := findClusterNode(topology.nodes, ip)
+			switch {
+			case node != nil && node.Master && node.Slots != "":
+				topology.masters = append(topology.masters, redisClusterMaster{PodName: podName, Node: node})
+				podZone(podName)
+			case role == "follower":
+				topology.replicas = append(topology.replicas, replicaCandidate{PodName: podName, Node: node, Zone: podZone(podName)})
+			case node != nil && !node.Master:
+				// a leader which lost its slots to a failover serves as replica of the promoted follower
+				topology.replicas = append(topology.replicas, replicaCandidate{PodName: podName, Node: node, Zone: podZone(podName)})
+			}
 		}
-		podIP = getRedisServerIP(followerPod)
-		if !checkRedisNodePresence(cr, nodes, podIP) {
-			logger.Info("Adding node to cluster.", "Node.IP", podIP, "Follower.Pod", followerPod)
-			cmd := createRedisReplicationCommand(cr, leaderPod, followerPod)
-			executeCommand(cr, cmd, cr.ObjectMeta.Name+"-leader-0")
+	}
+	return topology
+}
+
+// getRedisClusterMasters returns the leaders and the promoted followers which serve slots
+func getRedisClusterMasters(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) []redisClusterMaster {
+	return getRedisClusterTopology(cr, cl, recorder).masters
+}
+
+// ExecuteRedisReplicationCommand will attach the followers to the leaders, so that every leader has the
+// same number of healthy replicas. Orphaned followers are moved to a new leader with CLUSTER REPLICATE.
+func ExecuteRedisReplicationCommand(cr *redisv1beta1.RedisCluster, cl client.Client, executor PodExecutor, recorder record.EventRecorder) {
+	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
+	topology := getRedisClusterTopology(cr, cl, recorder)
+	var leaders []replicaLeader
+	leaderPodOf := map[string]string{}
+	for _, master := range topology.masters {
+		leaders = append(leaders, replicaLeader{ID: master.Node.ID, Zone: topology.zones[master.PodName]})
+		leaderPodOf[master.Node.ID] = master.PodName
+	}
+
+	for _, assignment := range planReplicaAssignment(leaders, topology.replicas) {
+		followerPod := RedisDetails{PodName: assignment.PodName, Namespace: cr.Namespace}
+		leaderPod := RedisDetails{PodName: leaderPodOf[assignment.MasterID], Namespace: cr.Namespace}
+		if assignment.Attach {
+			logger.Info("Adding node to cluster.", "Follower.Pod", followerPod, "Leader.Pod", leaderPod)
+			cmd := createRedisReplicationCommand(cr, cl, leaderPod, followerPod, assignment.MasterID, recorder)
+			if err := executeCommand(cr, cl, executor, cmd, cr.ObjectMeta.Name+"-leader-0"); err != nil {
+				recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonReplicaAttachFailed, "Could not attach %s as replica of %s: %v", followerPod.PodName, leaderPod.PodName, err)
+				continue
+			}
 		} else {
-			logger.Info("Skipping Adding node to cluster, already present.", "Follower.Pod", followerPod)
+			logger.Info("Moving follower to another leader.", "Follower.Pod", followerPod, "Leader.Pod", leaderPod)
+			if err := replicateRedisNode(cr, cl, followerPod.PodName, assignment.MasterID, recorder); err != nil {
+				recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonReplicaAttachFailed, "Could not move %s to leader %s: %v", followerPod.PodName, leaderPod.PodName, err)
+				continue
+			}
+		}
+		recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonReplicaAttached, "Attached %s as replica of %s", followerPod.PodName, leaderPod.PodName)
+	}
+}
+
+// replicateRedisNode makes the node of the pod a replica of the master
+func replicateRedisNode(cr *redisv1beta1.RedisCluster, cl client.Client, podName, masterID string, recorder record.EventRecorder) error {
+	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
+	client := configureRedisClient(cr, cl, podName, recorder)
+	defer client.Close()
+	if err := client.Do(ctx, "cluster", "replicate", masterID).Err(); err != nil {
+		logger.Error(err, "Redis cluster replicate command failed", "Pod", podName, "Master", masterID)
+		return err
+	}
+	return nil
+}
+
+// GetRedisClusterShards reports the healthy replicas of every master serving slots
+func GetRedisClusterShards(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) []redisv1beta1.RedisClusterShardStatus {
+	topology := getRedisClusterTopology(cr, cl, recorder)
+	desired := cr.Spec.GetReplicasPerLeader()
+	var shards []redisv1beta1.RedisClusterShardStatus
+	for _, master := range topology.masters {
+		shard := redisv1beta1.RedisClusterShardStatus{Leader: master.PodName, NodeID: master.Node.ID, Slots: master.Node.Slots, DesiredReplicas: desired}
+		for _, replica := range topology.replicas {
+			if replica.Node != nil && replica.Node.MasterID == master.Node.ID {
+				shard.Replicas = append(shard.Replicas, replica.PodName)
+				if replica.Node.Healthy {
+					shard.HealthyReplicas++
+				}
+			}
 		}
+		shards = append(shards, shard)
 	}
+	return shards
 }
 
 var ctx = context.Background()
 
 // checkRedisCluster will check the redis cluster have sufficient nodes or not
-func checkRedisCluster(cr *redisv1beta1.RedisCluster) [][]string {
+func checkRedisCluster(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) [][]string {
 	var client *redis.Client
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
-	client = configureRedisClient(cr, cr.ObjectMeta.Name+"-leader-0")
+	client = configureRedisClient(cr, cl, cr.ObjectMeta.Name+"-leader-0", recorder)
 	defer client.Close()
 	output, err := client.Do(ctx, "cluster", "nodes").Result()
 	if err != nil {
@@ -210,29 +315,32 @@ func checkRedisCluster(cr *redisv1beta1.RedisCluster) [][]string {
 }
 
 // ExecuteFailoverOperation will execute redis failover operations
-func ExecuteFailoverOperation(cr *redisv1beta1.RedisCluster) error {
+func ExecuteFailoverOperation(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) error {
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
-	err := executeFailoverCommand(cr, "leader")
+	err := executeFailoverCommand(cr, cl, "leader", recorder)
 	if err != nil {
 		logger.Error(err, "Redis command failed for leader nodes")
+		recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonFailoverFailed, "Failover of leader nodes failed: %v", err)
 		return err
 	}
-	err = executeFailoverCommand(cr, "follower")
+	err = executeFailoverCommand(cr, cl, "follower", recorder)
 	if err != nil {
 		logger.Error(err, "Redis command failed for follower nodes")
+		recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonFailoverFailed, "Failover of follower nodes failed: %v", err)
 		return err
 	}
+	recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonFailoverExecuted, "Cluster nodes were reset after too many failed nodes")
 	return nil
 }
 
 // executeFailoverCommand will execute failover command
-func executeFailoverCommand(cr *redisv1beta1.RedisCluster, role string) error {
+func executeFailoverCommand(cr *redisv1beta1.RedisCluster, cl client.Client, role string, recorder record.EventRecorder) error {
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
 	replicas := cr.Spec.GetReplicaCounts(role)
 	podName := fmt.Sprintf("%s-%s-", cr.ObjectMeta.Name, role)
 	for podCount := 0; podCount <= int(replicas)-1; podCount++ {
 		logger.Info("Executing redis failover operations", "Redis Node", podName+strconv.Itoa(podCount))
-		client := configureRedisClient(cr, podName+strconv.Itoa(podCount))
+		client := configureRedisClient(cr, cl, podName+strconv.Itoa(podCount), recorder)
 		defer client.Close()
 
 		_, err := client.Do(ctx, "cluster", "reset").Result()
@@ -256,10 +364,10 @@ func executeFailoverCommand(cr *redisv1beta1.RedisCluster, role string) error {
 }
 
 // CheckRedisNodeCount will check the count of redis nodes
-func CheckRedisNodeCount(cr *redisv1beta1.RedisCluster, nodeType string) int32 {
+func CheckRedisNodeCount(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder, nodeType string) int32 {
 	var redisNodeType string
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
-	clusterNodes := checkRedisCluster(cr)
+	clusterNodes := checkRedisCluster(cr, cl, recorder)
 	count := len(clusterNodes)
 
 	switch nodeType {
@@ -285,9 +393,9 @@ func CheckRedisNodeCount(cr *redisv1beta1.RedisCluster, nodeType string) int32 {
 }
 
 // CheckRedisClusterState will check the redis cluster state
-func CheckRedisClusterState(cr *redisv1beta1.RedisCluster) int {
+func CheckRedisClusterState(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) int {
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
-	clusterNodes := checkRedisCluster(cr)
+	clusterNodes := checkRedisCluster(cr, cl, recorder)
 	count := 0
 
 	for _, node := range clusterNodes {
@@ -300,84 +408,67 @@ func CheckRedisClusterState(cr *redisv1beta1.RedisCluster) int {
 }
 
 // configureRedisClient will configure the Redis Client
-func configureRedisClient(cr *redisv1beta1.RedisCluster, podName string) *redis.Client {
-	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
+func configureRedisClient(cr *redisv1beta1.RedisCluster, cl client.Client, podName string, recorder record.EventRecorder) *redis.Client {
+	return newRedisClient(cr, cr.Spec.KubernetesConfig, cr.Spec.TLS, cl, podName, recorder)
+}
+
+// newRedisClient will configure a Redis Client for a pod of the Redis or RedisCluster object
+func newRedisClient(cr client.Object, kubernetesConfig redisv1beta1.KubernetesConfig, tlsConfig *redisv1beta1.TLSConfig, cl client.Client, podName string, recorder record.EventRecorder) *redis.Client {
+	logger := generateRedisManagerLogger(cr.GetNamespace(), cr.GetName())
 	redisInfo := RedisDetails{
 		PodName:   podName,
-		Namespace: cr.Namespace,
+		Namespace: cr.GetNamespace(),
 	}
 	var client *redis.Client
 
-	if cr.Spec.KubernetesConfig.ExistingPasswordSecret != nil {
-		pass, err := getRedisPassword(cr.Namespace, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Name, *cr.Spec.KubernetesConfig.ExistingPasswordSecret.Key)
+	if kubernetesConfig.ExistingPasswordSecret != nil {
+		pass, err := getRedisPassword(cl, cr.GetNamespace(), *kubernetesConfig.ExistingPasswordSecret.Name, *kubernetesConfig.ExistingPasswordSecret.Key)
 		if err != nil {
 			logger.Error(err, "Error in getting redis password")
+			recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonPasswordLookupFailed, "Could not read password from secret %s: %v", *kubernetesConfig.ExistingPasswordSecret.Name, err)
 		}
 		client = redis.NewClient(&redis.Options{
-			Addr:      getRedisServerIP(redisInfo) + ":6379",
+			Addr:      getRedisServerIP(cl, redisInfo) + ":6379",
 			Username:  "default",
 			Password:  pass,
 			DB:        0,
-			TLSConfig: getRedisTLSConfig(cr, redisInfo),
+			TLSConfig: getRedisTLSConfig(cr, tlsConfig, cl, redisInfo, recorder),
 		})
 	} else {
 		client = redis.NewClient(&redis.Options{
-			Addr:      getRedisServerIP(redisInfo) + ":6379",
+			Addr:      getRedisServerIP(cl, redisInfo) + ":6379",
 			Password:  "",
 			DB:        0,
-			TLSConfig: getRedisTLSConfig(cr, redisInfo),
+			TLSConfig: getRedisTLSConfig(cr, tlsConfig, cl, redisInfo, recorder),
 		})
 	}
 	return client
 }
 
 // executeCommand will execute the commands in pod
-func executeCommand(cr *redisv1beta1.RedisCluster, cmd []string, podName string) {
-	var (
-		execOut bytes.Buffer
-		execErr bytes.Buffer
-	)
+func executeCommand(cr *redisv1beta1.RedisCluster, cl client.Client, executor PodExecutor, cmd []string, podName string) error {
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
-	config, err := generateK8sConfig()
-	if err != nil {
-		logger.Error(err, "Could not find pod to execute")
-		return
-	}
-	targetContainer, pod := getContainerID(cr, podName)
+	targetContainer, pod := getContainerID(cr, cl, podName)
 	if targetContainer < 0 {
+		err := fmt.Errorf("could not find container to execute in pod %s", podName)
 		logger.Error(err, "Could not find pod to execute")
-		return
-	}
-
-	req := generateK8sClient().CoreV1().RESTClient().Post().Resource("pods").Name(podName).Namespace(cr.Namespace).SubResource("exec")
-	req.VersionedParams(&corev1.PodExecOptions{
-		Container: pod.Spec.Containers[targetContainer].Name,
-		Command:   cmd,
-		Stdout:    true,
-		Stderr:    true,
-	}, scheme.ParameterCodec)
-	exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
-	if err != nil {
-		logger.Error(err, "Failed to init executor")
-		return
+		return err
 	}
 
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdout: &execOut,
-		Stderr: &execErr,
-		Tty:    false,
-	})
+	execOut, execErr, err := executor.Exec(cr.Namespace, podName, pod.Spec.Containers[targetContainer].Name, cmd)
 	if err != nil {
-		logger.Error(err, "Could not execute command", "Command", cmd, "Output", execOut.String(), "Error", execErr.String())
-		return
+		logger.Error(err, "Could not execute command", "Command", cmd, "Output", execOut, "Error", execErr)
+		return err
 	}
-	logger.Info("Successfully executed the command", "Command", cmd, "Output", execOut.String())
+	logger.Info("Successfully executed the command", "Command", cmd, "Output", execOut)
+	return nil
 }
 
 // getContainerID will return the id of container from pod
-func getContainerID(cr *redisv1beta1.RedisCluster, podName string) (int, *corev1.Pod) {
+func getContainerID(cr *redisv1beta1.RedisCluster, cl client.Client, podName string) (int, *corev1.Pod) {
 	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
-	pod, err := generateK8sClient().CoreV1().Pods(cr.Namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	pod := &corev1.Pod{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: podName}, pod)
 	if err != nil {
 		logger.Error(err, "Could not get pod info")
 	}
@@ -1,3 +1,8 @@
+//go:build livenet
+// +build livenet
+
+// These tests talk to the redis servers of a developer network and only run with `go test -tags livenet`.
+
 package k8sutils
 
 import (
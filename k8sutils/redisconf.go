@@ -0,0 +1,229 @@
+package k8sutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// redisConfigVolume is the volume of the ConfigMap with the redis.conf generated by the operator
+	redisConfigVolume = "redis-config"
+	redisConfigKey    = "redis.conf"
+	redisConfigPath   = "/etc/redis/redis.conf"
+	// operatorConfigPath is where the opstree images include the generated config instead of the external one
+	operatorConfigPath = "/etc/redis/operator.conf.d/redis-operator.conf"
+	// externalConfigPath is the file of the additional redis config, the same the opstree images include
+	externalConfigPath = "/etc/redis/external.conf.d/redis-external.conf"
+	// redisConfigHashAnnotation restarts the pods when the generated redis.conf changed
+	redisConfigHashAnnotation = "redis.opstreelabs.in/config-hash"
+)
+
+// redisConfigName returns the name of the ConfigMap with the redis.conf of the statefulset
+func redisConfigName(stsName string) string {
+	return stsName + "-config"
+}
+
+// hasRedisConfig returns true if the operator generates a config for the pods, the upstream images get the
+// full redis.conf and the opstree images only the modules
+func hasRedisConfig(params containerParameters) bool {
+	return params.ImageMode == redisv1beta1.ImageModeUpstream || len(params.Modules) > 0
+}
+
+// generateRedisConfig renders the config of the redis servers. For the upstream images it has the settings
+// which the entrypoint of the opstree images writes from the environment, the password is not part of it,
+// it is passed as argument from the secret.
+func generateRedisConfig(params containerParameters, externalConfig *string) string {
+	lines := []string{"# generated by the redis operator, changes are overwritten"}
+	if params.ImageMode == redisv1beta1.ImageModeUpstream {
+		lines = append(lines, generateUpstreamConfig(params)...)
+	}
+	lines = append(lines, generateRedisModuleConfig(params.Modules)...)
+	// included last, so that the additional config overrides the generated one
+	if externalConfig != nil {
+		lines = append(lines, "include "+externalConfigPath)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// generateUpstreamConfig returns the server settings of the redis.conf of the upstream images
+func generateUpstreamConfig(params containerParameters) []string {
+	lines := []string{
+		"protected-mode no",
+		"dir /data",
+	}
+	if params.TLSConfig != nil {
+		caCert, tlsCert, tlsCertKey := getTLSFilePaths(params.TLSConfig)
+		lines = append(lines,
+			"port 0",
+			"tls-port 6379",
+			"tls-cert-file "+tlsCert,
+			"tls-key-file "+tlsCertKey,
+			"tls-ca-cert-file "+caCert,
+			// redis-cli of the operator only presents the CA certificate
+			"tls-auth-clients optional",
+			"tls-replication yes",
+		)
+		if params.Role == "cluster" {
+			lines = append(lines, "tls-cluster yes")
+		}
+	} else {
+		lines = append(lines, "port 6379")
+	}
+	if params.Role == "cluster" {
+		lines = append(lines,
+			"cluster-enabled yes",
+			"cluster-node-timeout 5000",
+			"cluster-require-full-coverage no",
+			"cluster-migration-barrier 1",
+			"cluster-config-file /data/nodes.conf",
+		)
+	}
+	if params.PersistenceEnabled != nil && *params.PersistenceEnabled {
+		lines = append(lines,
+			"save 900 1",
+			"save 300 10",
+			"save 60 10000",
+			"appendonly yes",
+			`appendfilename "appendonly.aof"`,
+		)
+	}
+	return lines
+}
+
+// configureRedisConfigContainer mounts the generated config into the redis container. The entrypoint of the
+// opstree images includes it instead of the external config, which it includes in turn.
+func configureRedisConfigContainer(container *corev1.Container, params containerParameters) {
+	if params.ImageMode == redisv1beta1.ImageModeUpstream {
+		configureUpstreamContainer(container, params)
+		return
+	}
+	container.Env = append(container.Env, corev1.EnvVar{Name: "EXTERNAL_CONFIG_FILE", Value: operatorConfigPath})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      redisConfigVolume,
+		MountPath: operatorConfigPath,
+		SubPath:   redisConfigKey,
+		ReadOnly:  true,
+	})
+}
+
+// configureUpstreamContainer starts redis-server with the generated redis.conf. The arguments keep the
+// entrypoint of the image, which drops the root privileges in the official images.
+func configureUpstreamContainer(container *corev1.Container, params containerParameters) {
+	container.Args = []string{"redis-server", redisConfigPath}
+	if params.EnabledPassword != nil && *params.EnabledPassword {
+		container.Args = append(container.Args, "--requirepass", "$(REDIS_PASSWORD)", "--masterauth", "$(REDIS_PASSWORD)")
+	}
+	if params.Role == "cluster" {
+		container.Args = append(container.Args, "--cluster-announce-ip", "$(POD_IP)")
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name: "POD_IP",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
+			},
+		})
+	}
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      redisConfigVolume,
+		MountPath: redisConfigPath,
+		SubPath:   redisConfigKey,
+		ReadOnly:  true,
+	})
+	// the upstream images have no /usr/bin/healthcheck.sh, the script probes PING instead
+	if usesHealthcheckScript(params.ReadinessProbe) {
+		container.ReadinessProbe.ProbeHandler = getProbeHandler(redisv1beta1.ProbeModePing)
+	}
+	if usesHealthcheckScript(params.LivenessProbe) {
+		container.LivenessProbe.ProbeHandler = getProbeHandler(redisv1beta1.ProbeModePing)
+	}
+}
+
+// usesHealthcheckScript returns true if the probe runs the healthcheck script of the opstree images
+func usesHealthcheckScript(probe *redisv1beta1.Probe) bool {
+	return probe != nil && (probe.Mode == "" || probe.Mode == redisv1beta1.ProbeModeScript)
+}
+
+// addRedisConfigVolume mounts the ConfigMap of the redis.conf into the pods, its hash rolls them when it changed
+func addRedisConfigVolume(statefulset *appsv1.StatefulSet, redisConfig string) {
+	template := &statefulset.Spec.Template
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[redisConfigHashAnnotation] = configHash(redisConfig)
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name: redisConfigVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: redisConfigName(statefulset.Name)},
+			},
+		},
+	})
+}
+
+// configHash returns a short hash of the config for the pod template annotation
+func configHash(config string) string {
+	hash := sha256.Sum256([]byte(config))
+	return hex.EncodeToString(hash[:8])
+}
+
+// CreateOrUpdateRedisConfig writes the redis.conf into the ConfigMap of the statefulset, it is owned by the CR
+func CreateOrUpdateRedisConfig(cl client.Client, namespace string, stsMeta metav1.ObjectMeta, ownerDef metav1.OwnerReference, redisConfig string) error {
+	configMeta := generateObjectMetaInformation(redisConfigName(stsMeta.Name), namespace, stsMeta.GetLabels(), nil)
+	_, err := createOrUpdateConfigMap(cl, configMeta, ownerDef, redisConfigKey, redisConfig)
+	return err
+}
+
+// createOrUpdateConfigMap writes the value of the key into the ConfigMap and returns true if it changed
+func createOrUpdateConfigMap(cl client.Client, configMeta metav1.ObjectMeta, ownerDef metav1.OwnerReference, key, value string) (bool, error) {
+	logger := redisConfigLogger(configMeta.Namespace, configMeta.Name)
+	configMap := &corev1.ConfigMap{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: configMeta.Namespace, Name: configMeta.Name}, configMap)
+	if errors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			TypeMeta:   generateMetaInformation("ConfigMap", "v1"),
+			ObjectMeta: configMeta,
+			Data:       map[string]string{key: value},
+		}
+		AddOwnerRefToObject(configMap, ownerDef)
+		if err := cl.Create(context.TODO(), configMap); err != nil {
+			logger.Error(err, "Redis config creation failed")
+			return false, err
+		}
+		logger.Info("Redis config successfully created")
+		return true, nil
+	}
+	if err != nil {
+		logger.Error(err, "Redis config get action failed")
+		return false, err
+	}
+	if current, ok := configMap.Data[key]; ok && current == value {
+		return false, nil
+	}
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[key] = value
+	if err := cl.Update(context.TODO(), configMap); err != nil {
+		logger.Error(err, "Redis config update failed")
+		return false, err
+	}
+	logger.Info("Redis config successfully updated")
+	return true, nil
+}
+
+// redisConfigLogger will generate logging interface for the redis.conf ConfigMaps
+func redisConfigLogger(namespace string, name string) logr.Logger {
+	reqLogger := log.WithValues("Request.ConfigMap.Namespace", namespace, "Request.ConfigMap.Name", name)
+	return reqLogger
+}
@@ -0,0 +1,157 @@
+package k8sutils
+
+import (
+	"context"
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestGenerateRedisConfig(t *testing.T) {
+	persistence := true
+	external := "redis-external-config"
+	upstream := redisv1beta1.ImageModeUpstream
+	modules := []redisv1beta1.RedisModule{{Name: "ReJSON", LoadModule: "/modules/rejson.so"}, {Name: "search", LoadModule: "/modules/redisearch.so", Args: []string{"MAXSEARCHRESULTS", "1000"}}}
+	var tests = []struct {
+		name       string
+		params     containerParameters
+		external   *string
+		contains   []string
+		missing    []string
+		lastDirect string
+	}{
+		{
+			name:     "standalone",
+			params:   containerParameters{Role: "standalone", ImageMode: upstream},
+			contains: []string{"port 6379", "dir /data"},
+			missing:  []string{"cluster-enabled", "appendonly", "tls-port", "include"},
+		},
+		{
+			name:       "cluster with persistence and external config",
+			params:     containerParameters{Role: "cluster", PersistenceEnabled: &persistence, ImageMode: upstream, Modules: modules},
+			external:   &external,
+			contains:   []string{"cluster-enabled yes", "cluster-config-file /data/nodes.conf", "appendonly yes", "loadmodule /modules/rejson.so"},
+			missing:    []string{"tls-port"},
+			lastDirect: "include /etc/redis/external.conf.d/redis-external.conf",
+		},
+		{
+			name:     "cluster with tls",
+			params:   containerParameters{Role: "cluster", TLSConfig: &redisv1beta1.TLSConfig{CaKeyFile: "root.crt"}, ImageMode: upstream},
+			contains: []string{"port 0", "tls-port 6379", "tls-ca-cert-file /tls/root.crt", "tls-cert-file /tls/tls.crt", "tls-cluster yes"},
+			missing:  []string{"port 6379\n"},
+		},
+		{
+			name:       "modules of the opstree images",
+			params:     containerParameters{Role: "cluster", PersistenceEnabled: &persistence, Modules: modules},
+			external:   &external,
+			contains:   []string{"loadmodule /modules/rejson.so", "loadmodule /modules/redisearch.so MAXSEARCHRESULTS 1000"},
+			missing:    []string{"dir", "cluster-enabled", "appendonly", "port"},
+			lastDirect: "include /etc/redis/external.conf.d/redis-external.conf",
+		},
+	}
+
+	for _, tt := range tests {
+		config := generateRedisConfig(tt.params, tt.external)
+		for _, line := range tt.contains {
+			if !strings.Contains(config, line+"\n") {
+				t.Errorf("%s: config misses %q:\n%s", tt.name, line, config)
+			}
+		}
+		for _, line := range tt.missing {
+			if strings.Contains(config, "\n"+line) {
+				t.Errorf("%s: config has %q:\n%s", tt.name, line, config)
+			}
+		}
+		if tt.lastDirect != "" && !strings.HasSuffix(config, tt.lastDirect+"\n") {
+			t.Errorf("%s: config does not end with %q:\n%s", tt.name, tt.lastDirect, config)
+		}
+	}
+}
+
+func TestGenerateContainerDefUpstream(t *testing.T) {
+	enabled, secretName, secretKey := true, "redis-secret", "password"
+	params := containerParameters{
+		Image:           "redis:7",
+		Role:            "cluster",
+		EnabledPassword: &enabled,
+		SecretName:      &secretName,
+		SecretKey:       &secretKey,
+		ReadinessProbe:  &redisv1beta1.Probe{},
+		LivenessProbe:   &redisv1beta1.Probe{Mode: redisv1beta1.ProbeModeTCP},
+		ImageMode:       redisv1beta1.ImageModeUpstream,
+	}
+	redis := generateContainerDef("redis-leader", params, false, nil, nil)[0]
+
+	if got := strings.Join(redis.Args, " "); got != "redis-server /etc/redis/redis.conf --requirepass $(REDIS_PASSWORD) --masterauth $(REDIS_PASSWORD) --cluster-announce-ip $(POD_IP)" {
+		t.Errorf("got args %q", got)
+	}
+	if redis.Command != nil {
+		t.Errorf("got command %v, want the entrypoint of the image", redis.Command)
+	}
+	podIP := false
+	for _, env := range redis.Env {
+		podIP = podIP || env.Name == "POD_IP" && env.ValueFrom != nil && env.ValueFrom.FieldRef.FieldPath == "status.podIP"
+	}
+	if !podIP {
+		t.Errorf("POD_IP is not set from the pod status: %+v", redis.Env)
+	}
+	mounted := false
+	for _, mount := range redis.VolumeMounts {
+		mounted = mounted || mount.Name == redisConfigVolume && mount.MountPath == redisConfigPath && mount.SubPath == redisConfigKey
+	}
+	if !mounted {
+		t.Errorf("redis.conf is not mounted: %+v", redis.VolumeMounts)
+	}
+	if redis.ReadinessProbe.Exec == nil || !strings.Contains(strings.Join(redis.ReadinessProbe.Exec.Command, " "), "PING") {
+		t.Errorf("got readiness handler %+v, want PING instead of the healthcheck script", redis.ReadinessProbe.ProbeHandler)
+	}
+	if redis.LivenessProbe.TCPSocket == nil {
+		t.Errorf("got liveness handler %+v, want the tcp mode to be kept", redis.LivenessProbe.ProbeHandler)
+	}
+}
+
+func TestCreateOrUpdateStatefulSetUpstream(t *testing.T) {
+	cl := newFakeClient()
+	stsMeta := metav1.ObjectMeta{Name: "redis", Namespace: "default", Labels: map[string]string{"app": "redis"}, Annotations: map[string]string{}}
+	replicas := int32(1)
+	probe := &redisv1beta1.Probe{}
+	params := containerParameters{Image: "redis:7", Role: "standalone", ReadinessProbe: probe, LivenessProbe: probe, ImageMode: redisv1beta1.ImageModeUpstream}
+	if err := CreateOrUpdateStateFul(cl, "default", stsMeta, statefulSetParameters{Replicas: &replicas}, metav1.OwnerReference{Name: "redis", UID: "uid"}, params, nil, nil); err != nil {
+		t.Fatalf("CreateOrUpdateStateFul() returned %v", err)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "redis-config"}, configMap); err != nil {
+		t.Fatalf("could not get the redis.conf ConfigMap: %v", err)
+	}
+	if configMap.Data[redisConfigKey] != generateRedisConfig(params, nil) || len(configMap.OwnerReferences) != 1 {
+		t.Errorf("got ConfigMap %+v", configMap)
+	}
+	sts, err := GetStatefulSet(cl, "default", "redis")
+	if err != nil {
+		t.Fatalf("could not get the statefulset: %v", err)
+	}
+	hash := sts.Spec.Template.Annotations[redisConfigHashAnnotation]
+	if hash == "" || len(sts.Spec.Template.Spec.Volumes) != 1 || sts.Spec.Template.Spec.Volumes[0].ConfigMap.Name != "redis-config" {
+		t.Errorf("got pod template %+v, want the redis.conf volume and its hash", sts.Spec.Template)
+	}
+
+	persistence := true
+	params.PersistenceEnabled = &persistence
+	if err := CreateOrUpdateStateFul(cl, "default", stsMeta, statefulSetParameters{Replicas: &replicas}, metav1.OwnerReference{Name: "redis", UID: "uid"}, params, nil, nil); err != nil {
+		t.Fatalf("CreateOrUpdateStateFul() returned %v", err)
+	}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "redis-config"}, configMap); err != nil {
+		t.Fatalf("could not get the redis.conf ConfigMap: %v", err)
+	}
+	if !strings.Contains(configMap.Data[redisConfigKey], "appendonly yes") {
+		t.Errorf("redis.conf was not updated:\n%s", configMap.Data[redisConfigKey])
+	}
+	if sts, _ = GetStatefulSet(cl, "default", "redis"); sts.Spec.Template.Annotations[redisConfigHashAnnotation] == hash {
+		t.Errorf("the config hash of the pods did not change")
+	}
+}
@@ -0,0 +1,177 @@
+package k8sutils
+
+import (
+	"sort"
+	"strings"
+)
+
+// clusterNode is a single line of the CLUSTER NODES output
+type clusterNode struct {
+	ID       string
+	IP       string
+	MasterID string
+	Master   bool
+	Healthy  bool
+	Slots    string
+}
+
+// parseClusterNodes converts the CLUSTER NODES records into nodes, malformed lines are skipped
+func parseClusterNodes(records [][]string) []clusterNode {
+	var nodes []clusterNode
+	for _, record := range records {
+		if len(record) < 8 {
+			continue
+		}
+		node := clusterNode{
+			ID:      record[0],
+			IP:      strings.Split(record[1], ":")[0],
+			Master:  strings.Contains(record[2], "master"),
+			Healthy: !strings.Contains(record[2], "fail") && record[7] == "connected",
+			Slots:   strings.Join(record[8:], " "),
+		}
+		if record[3] != "-" {
+			node.MasterID = record[3]
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// findClusterNode returns the node with the ip
+func findClusterNode(nodes []clusterNode, ip string) *clusterNode {
+	for i := range nodes {
+		if nodes[i].IP == ip {
+			return &nodes[i]
+		}
+	}
+	return nil
+}
+
+// replicaLeader is a leader as seen by the replica planner
+type replicaLeader struct {
+	ID string
+	// Zone is only set in topology aware mode
+	Zone string
+}
+
+// replicaCandidate is a follower pod as seen by the replica planner
+type replicaCandidate struct {
+	PodName string
+	// Node is nil when the follower has not joined the cluster yet
+	Node *clusterNode
+	// Zone is only set in topology aware mode
+	Zone string
+}
+
+// replicaAssignment attaches a follower to a leader, followers which already joined the
+// cluster are moved with CLUSTER REPLICATE, the others are added with redis-cli
+type replicaAssignment struct {
+	PodName  string
+	MasterID string
+	Attach   bool
+}
+
+// sameZone returns true if both zones are known and equal
+func sameZone(a, b string) bool {
+	return a != "" && a == b
+}
+
+// planReplicaAssignment decides where the followers have to go so that every leader ends up
+// with the same number of healthy replicas. The leaders are the masters serving slots, whichever
+// statefulset their pods belong to. Followers which are not in the cluster and orphaned followers,
+// whose master serves no slots, go to the leader with the fewest replicas.
+// Afterwards healthy followers are moved from the most to the least replicated leader until
+// the replica counts differ by one at most. When zones are known a follower is never placed in
+// the zone of its leader if a leader in another zone is available.
+func planReplicaAssignment(leaders []replicaLeader, followers []replicaCandidate) []replicaAssignment {
+	if len(leaders) == 0 {
+		return nil
+	}
+	zoneOf := map[string]string{}
+	counts := map[string]int{}
+	for _, leader := range leaders {
+		zoneOf[leader.ID] = leader.Zone
+		counts[leader.ID] = 0
+	}
+	// pick returns the least replicated leader outside of the zone, or "" if there is none
+	pick := func(zone string) string {
+		target := ""
+		for _, leader := range leaders {
+			if sameZone(leader.Zone, zone) {
+				continue
+			}
+			if target == "" || counts[leader.ID] < counts[target] {
+				target = leader.ID
+			}
+		}
+		return target
+	}
+
+	// keep the followers which are healthy replicas of a leader in another zone
+	masterOf := map[string]string{}
+	var unplaced []replicaCandidate
+	for _, follower := range followers {
+		switch {
+		case follower.Node == nil:
+			unplaced = append(unplaced, follower)
+		case !follower.Node.Healthy:
+			continue
+		case follower.Node.Master && follower.Node.Slots != "":
+			// a follower which was promoted by a failover serves slots, it is left alone
+			continue
+		default:
+			master := follower.Node.MasterID
+			if _, ok := counts[master]; !ok || (sameZone(zoneOf[master], follower.Zone) && pick(follower.Zone) != "") {
+				unplaced = append(unplaced, follower)
+				continue
+			}
+			masterOf[follower.PodName] = master
+			counts[master]++
+		}
+	}
+	for _, follower := range unplaced {
+		target := pick(follower.Zone)
+		if target == "" {
+			// every leader shares the zone of the follower, a replica there is better than none
+			target = pick("")
+		}
+		masterOf[follower.PodName] = target
+		counts[target]++
+	}
+
+	// move followers from the most to the least replicated leaders
+	for moved := true; moved; {
+		moved = false
+		sorted := append([]replicaLeader(nil), leaders...)
+		sort.SliceStable(sorted, func(i, j int) bool { return counts[sorted[i].ID] < counts[sorted[j].ID] })
+		least, most := sorted[0], sorted[len(sorted)-1]
+		if counts[most.ID]-counts[least.ID] <= 1 {
+			break
+		}
+		for i := len(followers) - 1; i >= 0; i-- {
+			follower := followers[i]
+			if masterOf[follower.PodName] != most.ID || sameZone(follower.Zone, least.Zone) {
+				continue
+			}
+			masterOf[follower.PodName] = least.ID
+			counts[most.ID]--
+			counts[least.ID]++
+			moved = true
+			break
+		}
+	}
+
+	var assignments []replicaAssignment
+	for _, follower := range followers {
+		target, ok := masterOf[follower.PodName]
+		if !ok {
+			continue
+		}
+		if follower.Node == nil {
+			assignments = append(assignments, replicaAssignment{PodName: follower.PodName, MasterID: target, Attach: true})
+		} else if follower.Node.MasterID != target {
+			assignments = append(assignments, replicaAssignment{PodName: follower.PodName, MasterID: target})
+		}
+	}
+	return assignments
+}
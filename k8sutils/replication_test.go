@@ -0,0 +1,128 @@
+package k8sutils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPlanReplicaAssignment(t *testing.T) {
+	leaders := []replicaLeader{{ID: "l0"}, {ID: "l1"}, {ID: "l2"}}
+	replica := func(master string) *clusterNode {
+		return &clusterNode{MasterID: master, Healthy: true}
+	}
+	var tests = []struct {
+		name      string
+		followers []replicaCandidate
+		want      []replicaAssignment
+	}{
+		{
+			name: "new followers go to distinct leaders",
+			followers: []replicaCandidate{
+				{PodName: "f0"}, {PodName: "f1"}, {PodName: "f2"},
+			},
+			want: []replicaAssignment{
+				{PodName: "f0", MasterID: "l0", Attach: true},
+				{PodName: "f1", MasterID: "l1", Attach: true},
+				{PodName: "f2", MasterID: "l2", Attach: true},
+			},
+		},
+		{
+			name: "new follower goes to the leader without replicas",
+			followers: []replicaCandidate{
+				{PodName: "f0", Node: replica("l0")}, {PodName: "f1", Node: replica("l2")}, {PodName: "f2"},
+			},
+			want: []replicaAssignment{{PodName: "f2", MasterID: "l1", Attach: true}},
+		},
+		{
+			name: "orphaned follower is reassigned",
+			followers: []replicaCandidate{
+				{PodName: "f0", Node: replica("l0")}, {PodName: "f1", Node: replica("gone")}, {PodName: "f2", Node: replica("l2")},
+			},
+			want: []replicaAssignment{{PodName: "f1", MasterID: "l1"}},
+		},
+		{
+			name: "follower of an overloaded leader is moved",
+			followers: []replicaCandidate{
+				{PodName: "f0", Node: replica("l0")}, {PodName: "f1", Node: replica("l0")}, {PodName: "f2", Node: replica("l2")},
+			},
+			want: []replicaAssignment{{PodName: "f1", MasterID: "l1"}},
+		},
+		{
+			name: "failed and promoted followers are left alone",
+			followers: []replicaCandidate{
+				{PodName: "f0", Node: &clusterNode{MasterID: "gone"}},
+				{PodName: "f1", Node: &clusterNode{Master: true, Healthy: true, Slots: "0-5460"}},
+				{PodName: "f2", Node: replica("l2")},
+			},
+			want: nil,
+		},
+		{
+			name: "balanced replicas need no changes",
+			followers: []replicaCandidate{
+				{PodName: "f0", Node: replica("l0")}, {PodName: "f1", Node: replica("l1")}, {PodName: "f2", Node: replica("l2")},
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planReplicaAssignment(leaders, tt.followers)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanReplicaAssignmentZones(t *testing.T) {
+	leaders := []replicaLeader{{ID: "l0", Zone: "a"}, {ID: "l1", Zone: "b"}, {ID: "l2", Zone: "c"}}
+	var tests = []struct {
+		name      string
+		followers []replicaCandidate
+		want      []replicaAssignment
+	}{
+		{
+			name: "new followers avoid the zone of their leader",
+			followers: []replicaCandidate{
+				{PodName: "f0", Zone: "a"}, {PodName: "f1", Zone: "b"}, {PodName: "f2", Zone: "c"},
+			},
+			want: []replicaAssignment{
+				{PodName: "f0", MasterID: "l1", Attach: true},
+				{PodName: "f1", MasterID: "l2", Attach: true},
+				{PodName: "f2", MasterID: "l0", Attach: true},
+			},
+		},
+		{
+			name: "follower in the zone of its leader is moved",
+			followers: []replicaCandidate{
+				{PodName: "f0", Zone: "a", Node: &clusterNode{MasterID: "l0", Healthy: true}},
+				{PodName: "f1", Zone: "c", Node: &clusterNode{MasterID: "l0", Healthy: true}},
+				{PodName: "f2", Zone: "a", Node: &clusterNode{MasterID: "l2", Healthy: true}},
+			},
+			want: []replicaAssignment{{PodName: "f0", MasterID: "l1"}},
+		},
+		{
+			name: "single zone still gets replicas",
+			followers: []replicaCandidate{
+				{PodName: "f0", Zone: "a"},
+			},
+			want: []replicaAssignment{{PodName: "f0", MasterID: "l1", Attach: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planReplicaAssignment(leaders, tt.followers)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	sameZoneLeaders := []replicaLeader{{ID: "l0", Zone: "a"}, {ID: "l1", Zone: "a"}}
+	got := planReplicaAssignment(sameZoneLeaders, []replicaCandidate{{PodName: "f0", Zone: "a"}})
+	if len(got) != 1 || got[0].MasterID != "l0" {
+		t.Errorf("got %v, want f0 attached to l0 when every leader shares its zone", got)
+	}
+}
@@ -8,16 +8,20 @@ import (
 	"strings"
 
 	"github.com/go-logr/logr"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var log = logf.Log.WithName("controller_redis")
 
 // getRedisPassword method will return the redis password
-func getRedisPassword(namespace, name, secretKey string) (string, error) {
+func getRedisPassword(cl client.Client, namespace, name, secretKey string) (string, error) {
 	logger := secretLogger(namespace, name)
-	secretName, err := generateK8sClient().CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	secretName := &corev1.Secret{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, secretName)
 	if err != nil {
 		logger.Error(err, "Failed in getting existing secret for redis")
 		return "", err
@@ -35,12 +39,14 @@ func secretLogger(namespace string, name string) logr.Logger {
 	return reqLogger
 }
 
-func getRedisTLSConfig(cr *redisv1beta1.RedisCluster, redisInfo RedisDetails) *tls.Config {
-	if cr.Spec.TLS != nil {
-		reqLogger := log.WithValues("Request.Namespace", cr.Namespace, "Request.Name", cr.ObjectMeta.Name)
-		secretName, err := generateK8sClient().CoreV1().Secrets(cr.Namespace).Get(context.TODO(), cr.Spec.TLS.Secret.SecretName, metav1.GetOptions{})
+func getRedisTLSConfig(cr client.Object, tlsConfig *redisv1beta1.TLSConfig, cl client.Client, redisInfo RedisDetails, recorder record.EventRecorder) *tls.Config {
+	if tlsConfig != nil {
+		reqLogger := log.WithValues("Request.Namespace", cr.GetNamespace(), "Request.Name", cr.GetName())
+		secretName := &corev1.Secret{}
+		err := cl.Get(context.TODO(), types.NamespacedName{Namespace: cr.GetNamespace(), Name: tlsConfig.Secret.SecretName}, secretName)
 		if err != nil {
 			reqLogger.Error(err, "Failed in getting TLS secret for redis")
+			recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonTLSLookupFailed, "Could not read TLS secret %s: %v", tlsConfig.Secret.SecretName, err)
 		}
 
 		var (
@@ -51,11 +57,11 @@ func getRedisTLSConfig(cr *redisv1beta1.RedisCluster, redisInfo RedisDetails) *t
 			tlsClientCertificates []tls.Certificate
 		)
 		for key, value := range secretName.Data {
-			if key == cr.Spec.TLS.CaKeyFile || key == "ca.crt" {
+			if key == tlsConfig.CaKeyFile || key == "ca.crt" {
 				tlsCaCertificate = value
-			} else if key == cr.Spec.TLS.CertKeyFile || key == "tls.crt" {
+			} else if key == tlsConfig.CertKeyFile || key == "tls.crt" {
 				tlsClientCert = value
-			} else if key == cr.Spec.TLS.KeyFile || key == "tls.key" {
+			} else if key == tlsConfig.KeyFile || key == "tls.key" {
 				tlsClientKey = value
 			}
 		}
@@ -63,6 +69,7 @@ func getRedisTLSConfig(cr *redisv1beta1.RedisCluster, redisInfo RedisDetails) *t
 		cert, err := tls.X509KeyPair(tlsClientCert, tlsClientKey)
 		if err != nil {
 			reqLogger.Error(err, "Couldn't load TLS client key pair")
+			recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonTLSLookupFailed, "Could not load TLS client key pair from secret %s: %v", tlsConfig.Secret.SecretName, err)
 		}
 		tlsClientCertificates = append(tlsClientCertificates, cert)
 
@@ -8,7 +8,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
@@ -76,9 +78,9 @@ func generateServiceType(k8sServiceType string) corev1.ServiceType {
 }
 
 // createService is a method to create service is Kubernetes
-func createService(namespace string, service *corev1.Service) error {
+func createService(cl client.Client, namespace string, service *corev1.Service) error {
 	logger := serviceLogger(namespace, service.Name)
-	_, err := generateK8sClient().CoreV1().Services(namespace).Create(context.TODO(), service, metav1.CreateOptions{})
+	err := cl.Create(context.TODO(), service)
 	if err != nil {
 		logger.Error(err, "Redis service creation is failed")
 		return err
@@ -88,9 +90,9 @@ func createService(namespace string, service *corev1.Service) error {
 }
 
 // updateService is a method to update service is Kubernetes
-func updateService(namespace string, service *corev1.Service) error {
+func updateService(cl client.Client, namespace string, service *corev1.Service) error {
 	logger := serviceLogger(namespace, service.Name)
-	_, err := generateK8sClient().CoreV1().Services(namespace).Update(context.TODO(), service, metav1.UpdateOptions{})
+	err := cl.Update(context.TODO(), service)
 	if err != nil {
 		logger.Error(err, "Redis service update failed")
 		return err
@@ -100,12 +102,10 @@ func updateService(namespace string, service *corev1.Service) error {
 }
 
 // getService is a method to get service is Kubernetes
-func getService(namespace string, service string) (*corev1.Service, error) {
+func getService(cl client.Client, namespace string, service string) (*corev1.Service, error) {
 	logger := serviceLogger(namespace, service)
-	getOpts := metav1.GetOptions{
-		TypeMeta: generateMetaInformation("Service", "v1"),
-	}
-	serviceInfo, err := generateK8sClient().CoreV1().Services(namespace).Get(context.TODO(), service, getOpts)
+	serviceInfo := &corev1.Service{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: service}, serviceInfo)
 	if err != nil {
 		logger.Info("Redis service get action is failed")
 		return nil, err
@@ -120,24 +120,24 @@ func serviceLogger(namespace string, name string) logr.Logger {
 }
 
 // CreateOrUpdateService method will create or update Redis service
-func CreateOrUpdateService(namespace string, serviceMeta metav1.ObjectMeta, ownerDef metav1.OwnerReference, enableMetrics, headless bool) error {
+func CreateOrUpdateService(cl client.Client, namespace string, serviceMeta metav1.ObjectMeta, ownerDef metav1.OwnerReference, enableMetrics, headless bool) error {
 	logger := serviceLogger(namespace, serviceMeta.Name)
 	serviceDef := generateServiceDef(serviceMeta, enableMetrics, ownerDef, headless)
-	storedService, err := getService(namespace, serviceMeta.Name)
+	storedService, err := getService(cl, namespace, serviceMeta.Name)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			if err := patch.DefaultAnnotator.SetLastAppliedAnnotation(serviceDef); err != nil {
 				logger.Error(err, "Unable to patch redis service with compare annotations")
 			}
-			return createService(namespace, serviceDef)
+			return createService(cl, namespace, serviceDef)
 		}
 		return err
 	}
-	return patchService(storedService, serviceDef, namespace)
+	return patchService(cl, storedService, serviceDef, namespace)
 }
 
 // patchService will patch Redis Kubernetes service
-func patchService(storedService *corev1.Service, newService *corev1.Service, namespace string) error {
+func patchService(cl client.Client, storedService *corev1.Service, newService *corev1.Service, namespace string) error {
 	logger := serviceLogger(namespace, storedService.Name)
 	// We want to try and keep this atomic as possible.
 	newService.ResourceVersion = storedService.ResourceVersion
@@ -170,7 +170,7 @@ func patchService(storedService *corev1.Service, newService *corev1.Service, nam
 			return err
 		}
 		logger.Info("Syncing Redis service with defined properties")
-		return updateService(namespace, newService)
+		return updateService(cl, namespace, newService)
 	}
 	logger.Info("Redis service is already in-sync")
 	return nil
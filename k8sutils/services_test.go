@@ -0,0 +1,48 @@
+package k8sutils
+
+import (
+	"context"
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCreateOrUpdateService(t *testing.T) {
+	var tests = []struct {
+		name          string
+		enableMetrics bool
+		headless      bool
+		wantPorts     int
+	}{
+		{"redis", false, false, 1},
+		{"redis-headless", false, true, 1},
+		{"redis-metrics", true, false, 2},
+	}
+
+	cl := newFakeClient()
+	owner := redisAsOwner(&redisv1beta1.Redis{ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default", UID: "redis-uid"}})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceMeta := metav1.ObjectMeta{Name: tt.name, Namespace: "default", Labels: map[string]string{"app": "redis"}}
+			// the second call must go through the patch path without failing
+			for i := 0; i < 2; i++ {
+				if err := CreateOrUpdateService(cl, "default", serviceMeta, owner, tt.enableMetrics, tt.headless); err != nil {
+					t.Fatalf("CreateOrUpdateService() returned %v", err)
+				}
+			}
+			service := &corev1.Service{}
+			if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: tt.name}, service); err != nil {
+				t.Fatalf("could not get service: %v", err)
+			}
+			if len(service.Spec.Ports) != tt.wantPorts {
+				t.Errorf("got %d ports, want %d", len(service.Spec.Ports), tt.wantPorts)
+			}
+			if tt.headless && service.Spec.ClusterIP != "None" {
+				t.Errorf("got clusterIP %q, want None", service.Spec.ClusterIP)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package k8sutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	"github.com/go-redis/redis/v8"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SlotsPath is the path of the slot inspection endpoint on the metrics server
+const SlotsPath = "/slots"
+
+// SlotNode is a redis node of the slot layout and the pod running it
+type SlotNode struct {
+	PodName string `json:"podName,omitempty"`
+	NodeID  string `json:"nodeID"`
+	Addr    string `json:"addr"`
+}
+
+// SlotRange is a range of slots as reported by CLUSTER SLOTS
+type SlotRange struct {
+	Start    int        `json:"start"`
+	End      int        `json:"end"`
+	Master   SlotNode   `json:"master"`
+	Replicas []SlotNode `json:"replicas,omitempty"`
+}
+
+// KeySlot is the slot of a key and the master owning it
+type KeySlot struct {
+	Key string `json:"key"`
+	// HashTag is the part of the key between the first braces which is hashed instead of the whole key
+	HashTag string    `json:"hashTag,omitempty"`
+	Slot    int       `json:"slot"`
+	Master  *SlotNode `json:"master,omitempty"`
+}
+
+// SlotsReport is the response of the slot inspection endpoint
+type SlotsReport struct {
+	Namespace string      `json:"namespace,omitempty"`
+	Cluster   string      `json:"cluster,omitempty"`
+	Slots     []SlotRange `json:"slots,omitempty"`
+	Keys      []KeySlot   `json:"keys,omitempty"`
+	// SameSlot is set if all keys hash to one slot, multi key commands fail with CROSSSLOT otherwise
+	SameSlot *bool `json:"sameSlot,omitempty"`
+}
+
+// generateSlotLayout converts the CLUSTER SLOTS reply into slot ranges ordered by slot, the
+// node addresses are matched against the pod IPs
+func generateSlotLayout(clusterSlots []redis.ClusterSlot, podsByIP map[string]string) []SlotRange {
+	node := func(n redis.ClusterNode) SlotNode {
+		host, _, err := net.SplitHostPort(n.Addr)
+		if err != nil {
+			host = n.Addr
+		}
+		return SlotNode{PodName: podsByIP[host], NodeID: n.ID, Addr: n.Addr}
+	}
+	var layout []SlotRange
+	for _, slots := range clusterSlots {
+		if len(slots.Nodes) == 0 {
+			continue
+		}
+		slotRange := SlotRange{Start: slots.Start, End: slots.End, Master: node(slots.Nodes[0])}
+		for _, replica := range slots.Nodes[1:] {
+			slotRange.Replicas = append(slotRange.Replicas, node(replica))
+		}
+		layout = append(layout, slotRange)
+	}
+	sort.Slice(layout, func(i, j int) bool { return layout[i].Start < layout[j].Start })
+	return layout
+}
+
+// lookupKeySlots returns the slot of every key and its master in the layout
+func lookupKeySlots(keys []string, layout []SlotRange) ([]KeySlot, bool) {
+	var result []KeySlot
+	sameSlot := true
+	for _, key := range keys {
+		keySlot := KeySlot{Key: key, Slot: Slot(key)}
+		if tag := Key(key); tag != key {
+			keySlot.HashTag = tag
+		}
+		for i := range layout {
+			if keySlot.Slot >= layout[i].Start && keySlot.Slot <= layout[i].End {
+				keySlot.Master = &layout[i].Master
+				break
+			}
+		}
+		if len(result) > 0 && result[0].Slot != keySlot.Slot {
+			sameSlot = false
+		}
+		result = append(result, keySlot)
+	}
+	return result, sameSlot
+}
+
+// GetRedisClusterSlots reads the slot layout with CLUSTER SLOTS from the first leader which answers
+func GetRedisClusterSlots(cr *redisv1beta1.RedisCluster, cl client.Client) ([]SlotRange, error) {
+	logger := generateRedisManagerLogger(cr.Namespace, cr.ObjectMeta.Name)
+	podsByIP := map[string]string{}
+	var leaders []string
+	for _, role := range []string{"leader", "follower"} {
+		for i := 0; i < int(cr.Spec.GetReplicaCounts(role)); i++ {
+			podName := cr.ObjectMeta.Name + "-" + role + "-" + strconv.Itoa(i)
+			ip := getRedisServerIP(cl, RedisDetails{PodName: podName, Namespace: cr.Namespace})
+			if ip == "" {
+				continue
+			}
+			podsByIP[strings.Trim(ip, "[]")] = podName
+			if role == "leader" {
+				leaders = append(leaders, podName)
+			}
+		}
+	}
+
+	err := fmt.Errorf("redis cluster %s has no running leader", cr.ObjectMeta.Name)
+	for _, podName := range leaders {
+		rc := configureRedisClient(cr, cl, podName, nil)
+		var clusterSlots []redis.ClusterSlot
+		clusterSlots, err = rc.ClusterSlots(ctx).Result()
+		rc.Close()
+		if err != nil {
+			logger.Error(err, "Could not read the cluster slots", "Pod", podName)
+			continue
+		}
+		return generateSlotLayout(clusterSlots, podsByIP), nil
+	}
+	return nil, err
+}
+
+// NewSlotsHandler returns the handler of the slot inspection endpoint. It maps the key parameters to
+// their slots, and with the namespace and cluster parameters to the pods owning the slots:
+//
+//	GET /slots?namespace=default&cluster=redis-cluster&key=user:{42}:cart&key=user:{42}:orders
+//
+// The handler reads the clusters of all namespaces, the auth proxy in front of the metrics server only lets
+// the subjects of the slots-reader-redis-operator ClusterRole through.
+func NewSlotsHandler(cl client.Client) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		report := SlotsReport{Namespace: query.Get("namespace"), Cluster: query.Get("cluster")}
+		keys := query["key"]
+		if report.Cluster == "" && len(keys) == 0 {
+			http.Error(w, "either the cluster or the key parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		if report.Cluster != "" {
+			if report.Namespace == "" {
+				http.Error(w, "the namespace parameter is required with the cluster parameter", http.StatusBadRequest)
+				return
+			}
+			cr := &redisv1beta1.RedisCluster{}
+			err := cl.Get(context.TODO(), types.NamespacedName{Namespace: report.Namespace, Name: report.Cluster}, cr)
+			if apierrors.IsNotFound(err) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if err == nil {
+				report.Slots, err = GetRedisClusterSlots(cr, cl)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		if len(keys) > 0 {
+			var sameSlot bool
+			report.Keys, sameSlot = lookupKeySlots(keys, report.Slots)
+			report.SameSlot = &sameSlot
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			generateRedisManagerLogger(report.Namespace, report.Cluster).Error(err, "Could not write the slots report")
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package k8sutils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestGenerateSlotLayout(t *testing.T) {
+	clusterSlots := []redis.ClusterSlot{
+		{Start: 5461, End: 16383, Nodes: []redis.ClusterNode{{ID: "b", Addr: "10.0.0.2:6379"}}},
+		{Start: 0, End: 5460, Nodes: []redis.ClusterNode{{ID: "a", Addr: "10.0.0.1:6379"}, {ID: "c", Addr: "10.0.0.3:6379"}}},
+		{Start: 1, End: 1},
+	}
+	podsByIP := map[string]string{"10.0.0.1": "redis-cluster-leader-0", "10.0.0.3": "redis-cluster-follower-0"}
+
+	layout := generateSlotLayout(clusterSlots, podsByIP)
+	if len(layout) != 2 {
+		t.Fatalf("got %d slot ranges, want 2", len(layout))
+	}
+	if layout[0].Start != 0 || layout[0].Master.PodName != "redis-cluster-leader-0" || layout[0].Replicas[0].PodName != "redis-cluster-follower-0" {
+		t.Errorf("got first range %+v", layout[0])
+	}
+	if layout[1].Master.NodeID != "b" || layout[1].Master.PodName != "" {
+		t.Errorf("got second range %+v", layout[1])
+	}
+}
+
+func TestLookupKeySlots(t *testing.T) {
+	layout := []SlotRange{{Start: 0, End: 8191, Master: SlotNode{PodName: "leader-0"}}, {Start: 8192, End: 16383, Master: SlotNode{PodName: "leader-1"}}}
+
+	keys, sameSlot := lookupKeySlots([]string{"user:{42}:cart", "user:{42}:orders"}, layout)
+	if !sameSlot || keys[0].HashTag != "42" || keys[0].Slot != Slot("42") || keys[1].Slot != keys[0].Slot {
+		t.Errorf("got %+v, same slot %t", keys, sameSlot)
+	}
+	if keys[0].Master == nil || keys[0].Master.PodName != "leader-0" {
+		t.Errorf("got master %+v for slot %d", keys[0].Master, keys[0].Slot)
+	}
+
+	keys, sameSlot = lookupKeySlots([]string{"user:1", "user:2"}, nil)
+	if sameSlot || keys[0].HashTag != "" || keys[0].Master != nil {
+		t.Errorf("got %+v, same slot %t", keys, sameSlot)
+	}
+}
+
+func TestSlotsHandler(t *testing.T) {
+	handler := NewSlotsHandler(newFakeClient())
+	var tests = []struct {
+		name string
+		url  string
+		want int
+	}{
+		{"keys only", "/slots?key=a&key=b", http.StatusOK},
+		{"no parameters", "/slots", http.StatusBadRequest},
+		{"cluster without namespace", "/slots?cluster=redis-cluster", http.StatusBadRequest},
+		{"unknown cluster", "/slots?namespace=default&cluster=redis-cluster", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if recorder.Code != tt.want {
+				t.Errorf("got status %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slots?key={a}1&key={a}2", nil))
+	report := SlotsReport{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Keys) != 2 || report.SameSlot == nil || !*report.SameSlot {
+		t.Errorf("got report %+v", report)
+	}
+}
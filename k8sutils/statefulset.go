@@ -2,6 +2,7 @@ package k8sutils
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/banzaicloud/k8s-objectmatcher/patch"
 	"github.com/go-logr/logr"
@@ -10,15 +11,20 @@ import (
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/tools/record"
 	"path"
 	redisv1beta1 "redis-operator/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sort"
-	"strconv"
 )
 
 const (
 	redisExporterContainer = "redis-exporter"
+	// sidecarDataVolume is the name under which sidecars mount the persistent volume of redis
+	sidecarDataVolume = "data"
 )
 
 // statefulSetParameters will define statefulsets input params
@@ -30,12 +36,14 @@ type statefulSetParameters struct {
 	PriorityClassName     string
 	Affinity              *corev1.Affinity
 	Tolerations           *[]corev1.Toleration
+	TopologySpread        []corev1.TopologySpreadConstraint
 	EnableMetrics         bool
 	PersistentVolumeClaim corev1.PersistentVolumeClaim
 	ImagePullSecrets      *[]corev1.LocalObjectReference
 	ExternalConfig        *string
 	ServiceAccountName    *string
 	UpdateStrategy        appsv1.StatefulSetUpdateStrategy
+	PodTemplate           *redisv1beta1.PodTemplate
 }
 
 // containerParameters will define container input params
@@ -55,30 +63,45 @@ type containerParameters struct {
 	TLSConfig                    *redisv1beta1.TLSConfig
 	ReadinessProbe               *redisv1beta1.Probe
 	LivenessProbe                *redisv1beta1.Probe
+	ImageMode                    redisv1beta1.ImageMode
+	Modules                      []redisv1beta1.RedisModule
+	Memory                       *redisv1beta1.RedisMemory
 }
 
 // CreateOrUpdateStateFul method will create or update Redis service
-func CreateOrUpdateStateFul(namespace string, stsMeta metav1.ObjectMeta, params statefulSetParameters, ownerDef metav1.OwnerReference, containerParams containerParameters, sidecars *[]redisv1beta1.Sidecar) error {
+func CreateOrUpdateStateFul(cl client.Client, namespace string, stsMeta metav1.ObjectMeta, params statefulSetParameters, ownerDef metav1.OwnerReference, containerParams containerParameters, sidecars *[]redisv1beta1.Sidecar, recorder record.EventRecorder) error {
 	logger := statefulSetLogger(namespace, stsMeta.Name)
 	// 从k8s集群中获取已经存在的同样命名空间，同样名称的sts, 如果未获取到，则为空
-	storedStateful, err := GetStatefulSet(namespace, stsMeta.Name)
+	storedStateful, err := GetStatefulSet(cl, namespace, stsMeta.Name)
 	// 使用用户提供的cr文件内容生成一个sts对象，这个对象将和上面的已存在对象进行比较，如果不一样，则更新
 	statefulSetDef := generateStatefulSetsDef(stsMeta, params, ownerDef, containerParams, getSidecars(sidecars))
+	if hasRedisConfig(containerParams) {
+		redisConfig := generateRedisConfig(containerParams, params.ExternalConfig)
+		if err := CreateOrUpdateRedisConfig(cl, namespace, stsMeta, ownerDef, redisConfig); err != nil {
+			logger.Error(err, "Unable to write the redis.conf generated by the operator")
+			return err
+		}
+		addRedisConfigVolume(statefulSetDef, redisConfig)
+	}
+	if mergeErr := mergePodTemplate(&statefulSetDef.Spec.Template, params.PodTemplate); mergeErr != nil {
+		logger.Error(mergeErr, "Unable to merge the pod template override into the redis statefulset")
+		return mergeErr
+	}
 	if err != nil {
 		if err := patch.DefaultAnnotator.SetLastAppliedAnnotation(statefulSetDef); err != nil {
 			logger.Error(err, "Unable to patch redis statefulset with comparison object")
 			return err
 		}
 		if errors.IsNotFound(err) {
-			return createStatefulSet(namespace, statefulSetDef)
+			return createStatefulSet(cl, namespace, statefulSetDef)
 		}
 		return err
 	}
-	return patchStatefulSet(storedStateful, statefulSetDef, namespace)
+	return patchStatefulSet(cl, storedStateful, statefulSetDef, namespace, recorder)
 }
 
 // patchStateFulSet will patch Redis Kubernetes StateFulSet
-func patchStatefulSet(storedStateful *appsv1.StatefulSet, newStateful *appsv1.StatefulSet, namespace string) error {
+func patchStatefulSet(cl client.Client, storedStateful *appsv1.StatefulSet, newStateful *appsv1.StatefulSet, namespace string, recorder record.EventRecorder) error {
 	logger := statefulSetLogger(namespace, storedStateful.Name)
 
 	// We want to try and keep this atomic as possible.
@@ -98,69 +121,9 @@ func patchStatefulSet(storedStateful *appsv1.StatefulSet, newStateful *appsv1.St
 	}
 	if !patchResult.IsEmpty() {
 		logger.Info("Changes in statefulset Detected, Updating...", "patch", string(patchResult.Patch))
-		// Field is immutable therefore we MUST keep it as is.
+		// Field is immutable therefore we MUST keep it as is, the storage reconcile expands the PVCs
+		// and recreates the statefulset once they have the new size.
 		if !apiequality.Semantic.DeepEqual(newStateful.Spec.VolumeClaimTemplates, storedStateful.Spec.VolumeClaimTemplates) {
-			// resize pvc
-			// 1.Get the data already stored internally
-			// 2.Get the desired data
-			// 3.Start querying the pvc list when you find data inconsistencies
-			// 3.1 Comparison using real pvc capacity and desired data
-			// 3.1.1 Update if you find inconsistencies
-			// 3.2 Writing successful updates to internal
-			// 4. Set to old VolumeClaimTemplates to update.Prevent update error reporting
-			// 5. Set to old annotations to update
-			annotations := storedStateful.Annotations
-			if annotations == nil {
-				annotations = map[string]string{
-					"storageCapacity": "0",
-				}
-			}
-			storedCapacity, _ := strconv.ParseInt(annotations["storageCapacity"], 0, 64)
-			if len(newStateful.Spec.VolumeClaimTemplates) != 0 {
-				stateCapacity := newStateful.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests.Storage().Value()
-				if storedCapacity != stateCapacity {
-					listOpt := metav1.ListOptions{
-						LabelSelector: labels.FormatLabels(
-							map[string]string{
-								"app":                         storedStateful.Name,
-								"app.kubernetes.io/component": "redis",
-								"app.kubernetes.io/name":      storedStateful.Name,
-							},
-						),
-					}
-					pvcs, err := generateK8sClient().CoreV1().PersistentVolumeClaims(storedStateful.Namespace).List(context.Background(), listOpt)
-					if err != nil {
-						return err
-					}
-					updateFailed := false
-					realUpdate := false
-					for _, pvc := range pvcs.Items {
-						realCapacity := pvc.Spec.Resources.Requests.Storage().Value()
-						if realCapacity != stateCapacity {
-							realUpdate = true
-							pvc.Spec.Resources.Requests = newStateful.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests
-							_, err = generateK8sClient().CoreV1().PersistentVolumeClaims(storedStateful.Namespace).Update(context.Background(), &pvc, metav1.UpdateOptions{})
-							if err != nil {
-								if !updateFailed {
-									updateFailed = true
-								}
-								logger.Error(fmt.Errorf("redis:%s resize pvc failed:%s", storedStateful.Name, err.Error()), "")
-							}
-						}
-					}
-					if !updateFailed && len(pvcs.Items) != 0 {
-						annotations["storageCapacity"] = fmt.Sprintf("%d", stateCapacity)
-						storedStateful.Annotations = annotations
-						if realUpdate {
-							logger.Info(fmt.Sprintf("redis:%s resize pvc from  %d to %d", storedStateful.Name, storedCapacity, stateCapacity))
-						} else {
-							logger.Info(fmt.Sprintf("redis:%s resize noting,just set annotations", storedStateful.Name))
-						}
-					}
-				}
-			}
-			// set stored.volumeClaimTemplates
-			newStateful.Annotations = storedStateful.Annotations
 			newStateful.Spec.VolumeClaimTemplates = storedStateful.Spec.VolumeClaimTemplates
 		}
 
@@ -173,7 +136,7 @@ func patchStatefulSet(storedStateful *appsv1.StatefulSet, newStateful *appsv1.St
 			logger.Error(err, "Unable to patch redis statefulset with comparison object")
 			return err
 		}
-		return updateStatefulSet(namespace, newStateful)
+		return updateStatefulSet(cl, namespace, newStateful)
 	}
 	logger.Info("Reconciliation Complete, no Changes required.")
 	return nil
@@ -195,11 +158,12 @@ func generateStatefulSetsDef(stsMeta metav1.ObjectMeta, params statefulSetParame
 					Annotations: generateStatefulSetsAnots(stsMeta),
 				},
 				Spec: corev1.PodSpec{
-					Containers:        generateContainerDef(stsMeta.GetName(), containerParams, params.EnableMetrics, params.ExternalConfig, sidecars),
-					NodeSelector:      params.NodeSelector,
-					SecurityContext:   params.SecurityContext,
-					PriorityClassName: params.PriorityClassName,
-					Affinity:          params.Affinity,
+					Containers:                generateContainerDef(stsMeta.GetName(), containerParams, params.EnableMetrics, params.ExternalConfig, sidecars),
+					NodeSelector:              params.NodeSelector,
+					SecurityContext:           params.SecurityContext,
+					PriorityClassName:         params.PriorityClassName,
+					Affinity:                  params.Affinity,
+					TopologySpreadConstraints: params.TopologySpread,
 				},
 			},
 		},
@@ -230,11 +194,57 @@ func generateStatefulSetsDef(stsMeta metav1.ObjectMeta, params statefulSetParame
 	if params.ServiceAccountName != nil {
 		statefulset.Spec.Template.Spec.ServiceAccountName = *params.ServiceAccountName
 	}
+	addRedisModuleInitContainers(&statefulset.Spec.Template.Spec, containerParams.Modules)
 
 	AddOwnerRefToObject(statefulset, ownerDef)
 	return statefulset
 }
 
+// mergePodTemplate strategically merges the user provided pod template into the generated one,
+// the pod labels used by the statefulset selector always keep their generated values
+func mergePodTemplate(template *corev1.PodTemplateSpec, override *redisv1beta1.PodTemplate) error {
+	if override == nil {
+		return nil
+	}
+	selectorLabels := template.Labels
+	// nil maps are left out, a null in a strategic merge patch deletes the field
+	metadata := map[string]interface{}{}
+	if override.Labels != nil {
+		metadata["labels"] = override.Labels
+	}
+	if override.Annotations != nil {
+		metadata["annotations"] = override.Annotations
+	}
+	overridePatch := map[string]interface{}{"metadata": metadata}
+	if override.Spec != nil && len(override.Spec.Raw) > 0 {
+		overridePatch["spec"] = json.RawMessage(override.Spec.Raw)
+	}
+	patchJSON, err := json.Marshal(overridePatch)
+	if err != nil {
+		return err
+	}
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	mergedJSON, err := strategicpatch.StrategicMergePatch(templateJSON, patchJSON, corev1.PodTemplateSpec{})
+	if err != nil {
+		return err
+	}
+	merged := corev1.PodTemplateSpec{}
+	if err := json.Unmarshal(mergedJSON, &merged); err != nil {
+		return err
+	}
+	if merged.Labels == nil {
+		merged.Labels = map[string]string{}
+	}
+	for key, value := range selectorLabels {
+		merged.Labels[key] = value
+	}
+	*template = merged
+	return nil
+}
+
 // getExternalConfig will return the redis external configuration
 func getExternalConfig(configMapName string) []corev1.Volume {
 	return []corev1.Volume{
@@ -290,16 +300,21 @@ func generateContainerDef(name string, containerParams containerParameters, enab
 				containerParams.PersistenceEnabled,
 				containerParams.RedisExporterEnv,
 				containerParams.TLSConfig,
+				containerParams.Memory,
 			),
 			ReadinessProbe: getProbeInfo(containerParams.ReadinessProbe),
 			LivenessProbe:  getProbeInfo(containerParams.LivenessProbe),
 			VolumeMounts:   getVolumeMount(name, containerParams.PersistenceEnabled, externalConfig, containerParams.TLSConfig),
+			Lifecycle:      getRedisLifecycle(containerParams.Role),
 		},
 	}
 
 	if containerParams.Resources != nil {
 		containerDefinition[0].Resources = *containerParams.Resources
 	}
+	if hasRedisConfig(containerParams) {
+		configureRedisConfigContainer(&containerDefinition[0], containerParams)
+	}
 	if enableMetrics {
 		containerDefinition = append(containerDefinition, enableRedisMonitoring(containerParams))
 	}
@@ -308,9 +323,19 @@ func generateContainerDef(name string, containerParams containerParameters, enab
 			Name:            sidecar.Name,
 			Image:           sidecar.Image,
 			ImagePullPolicy: sidecar.ImagePullPolicy,
+			Command:         sidecar.Command,
+			Args:            sidecar.Args,
+			WorkingDir:      sidecar.WorkingDir,
+			Ports:           sidecar.Ports,
+			EnvFrom:         sidecar.EnvFrom,
+			LivenessProbe:   sidecar.LivenessProbe,
+			ReadinessProbe:  sidecar.ReadinessProbe,
+			StartupProbe:    sidecar.StartupProbe,
+			Lifecycle:       sidecar.Lifecycle,
+			SecurityContext: sidecar.SecurityContext,
 			// 对于sidecar容器，如果也开启持久化的话，默认也挂在/data目录，否额redis-agent无法访问到/data/dump.rdb
 			// 另外这个挂载盘的名称必须是name，因为这里构建的sts的 volumeClaimTemplates 中的挂载模板的名称为 name
-			VolumeMounts: getVolumeMountForSidecar(name, containerParams.PersistenceEnabled),
+			VolumeMounts: getVolumeMountForSidecar(name, containerParams.PersistenceEnabled, sidecar.VolumeMounts),
 		}
 		if sidecar.Resources != nil {
 			container.Resources = *sidecar.Resources
@@ -323,8 +348,8 @@ func generateContainerDef(name string, containerParams containerParameters, enab
 	return containerDefinition
 }
 
-func GenerateTLSEnvironmentVariables(tlsconfig *redisv1beta1.TLSConfig) []corev1.EnvVar {
-	var envVars []corev1.EnvVar
+// getTLSFilePaths returns the paths of the CA certificate, the certificate and the key in the mounted TLS secret
+func getTLSFilePaths(tlsconfig *redisv1beta1.TLSConfig) (string, string, string) {
 	root := "/tls/"
 
 	// get and set Defaults
@@ -341,6 +366,12 @@ func GenerateTLSEnvironmentVariables(tlsconfig *redisv1beta1.TLSConfig) []corev1
 	if tlsconfig.KeyFile != "" {
 		tlsCertKey = tlsconfig.KeyFile
 	}
+	return path.Join(root, caCert), path.Join(root, tlsCert), path.Join(root, tlsCertKey)
+}
+
+func GenerateTLSEnvironmentVariables(tlsconfig *redisv1beta1.TLSConfig) []corev1.EnvVar {
+	var envVars []corev1.EnvVar
+	caCert, tlsCert, tlsCertKey := getTLSFilePaths(tlsconfig)
 
 	envVars = append(envVars, corev1.EnvVar{
 		Name:  "TLS_MODE",
@@ -348,15 +379,15 @@ func GenerateTLSEnvironmentVariables(tlsconfig *redisv1beta1.TLSConfig) []corev1
 	})
 	envVars = append(envVars, corev1.EnvVar{
 		Name:  "REDIS_TLS_CA_KEY",
-		Value: path.Join(root, caCert),
+		Value: caCert,
 	})
 	envVars = append(envVars, corev1.EnvVar{
 		Name:  "REDIS_TLS_CERT",
-		Value: path.Join(root, tlsCert),
+		Value: tlsCert,
 	})
 	envVars = append(envVars, corev1.EnvVar{
 		Name:  "REDIS_TLS_CERT_KEY",
-		Value: path.Join(root, tlsCertKey),
+		Value: tlsCertKey,
 	})
 	return envVars
 }
@@ -376,6 +407,7 @@ func enableRedisMonitoring(params containerParameters) corev1.Container {
 			params.PersistenceEnabled,
 			params.RedisExporterEnv,
 			params.TLSConfig,
+			params.Memory,
 		),
 		VolumeMounts: getVolumeMount("", nil, nil, params.TLSConfig), // We need/want the tls-certs but we DON'T need the PVC (if one is available)
 	}
@@ -414,19 +446,37 @@ func getVolumeMount(name string, persistenceEnabled *bool, externalConfig *strin
 	return VolumeMounts
 }
 
-// getVolumeMount gives information about persistence mount
-func getVolumeMountForSidecar(name string, persistenceEnabled *bool) []corev1.VolumeMount {
+// getVolumeMountForSidecar gives the mounts of a sidecar, the "data" volume is resolved to the
+// persistent volume of the statefulset which is mounted on /data if the sidecar does not mount it
+func getVolumeMountForSidecar(name string, persistenceEnabled *bool, mounts []corev1.VolumeMount) []corev1.VolumeMount {
 	var VolumeMounts []corev1.VolumeMount
+	dataMounted := false
+	for _, mount := range mounts {
+		if mount.Name == sidecarDataVolume || mount.Name == name {
+			mount.Name = name
+			dataMounted = true
+		}
+		VolumeMounts = append(VolumeMounts, mount)
+	}
 
-	if persistenceEnabled != nil && *persistenceEnabled {
-		VolumeMounts = append(VolumeMounts, corev1.VolumeMount{
+	if persistenceEnabled != nil && *persistenceEnabled && !dataMounted {
+		VolumeMounts = append([]corev1.VolumeMount{{
 			Name:      name,
 			MountPath: "/data",
-		})
+		}}, VolumeMounts...)
 	}
 	return VolumeMounts
 }
 
+// probeChecks are the shell checks of the probe modes which run redis-cli inside the redis container. The
+// cluster state check passes before the slots are assigned, as the operator creates the cluster from ready pods.
+var probeChecks = map[redisv1beta1.ProbeMode]string{
+	redisv1beta1.ProbeModePing:         `[ "$(cli 127.0.0.1 PING)" = "PONG" ]`,
+	redisv1beta1.ProbeModeClusterState: `info=$(cli 127.0.0.1 CLUSTER INFO) && echo "$info" | grep -q -e '^cluster_state:ok' -e '^cluster_slots_assigned:0'`,
+	redisv1beta1.ProbeModeReplication:  `info=$(cli 127.0.0.1 INFO replication) && echo "$info" | grep -q -e '^role:master' -e '^master_link_status:up'`,
+	redisv1beta1.ProbeModeLoading:      `cli 127.0.0.1 INFO persistence | grep -q '^loading:0'`,
+}
+
 // getProbeInfo generate probe for Redis StatefulSet
 func getProbeInfo(probe *redisv1beta1.Probe) *corev1.Probe {
 	return &corev1.Probe{
@@ -435,19 +485,36 @@ func getProbeInfo(probe *redisv1beta1.Probe) *corev1.Probe {
 		FailureThreshold:    probe.FailureThreshold,
 		TimeoutSeconds:      probe.TimeoutSeconds,
 		SuccessThreshold:    probe.SuccessThreshold,
-		ProbeHandler: corev1.ProbeHandler{
+		ProbeHandler:        getProbeHandler(probe.Mode),
+	}
+}
+
+// getProbeHandler returns the check of the probe mode
+func getProbeHandler(mode redisv1beta1.ProbeMode) corev1.ProbeHandler {
+	if mode == redisv1beta1.ProbeModeTCP {
+		return corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(6379)},
+		}
+	}
+	if check, ok := probeChecks[mode]; ok {
+		return corev1.ProbeHandler{
 			Exec: &corev1.ExecAction{
-				Command: []string{
-					"bash",
-					"/usr/bin/healthcheck.sh",
-				},
+				Command: []string{"/bin/sh", "-c", redisCLIShellFunction + check},
+			},
+		}
+	}
+	return corev1.ProbeHandler{
+		Exec: &corev1.ExecAction{
+			Command: []string{
+				"bash",
+				"/usr/bin/healthcheck.sh",
 			},
 		},
 	}
 }
 
 // getEnvironmentVariables returns all the required Environment Variables
-func getEnvironmentVariables(role string, enabledMetric bool, enabledPassword *bool, secretName *string, secretKey *string, persistenceEnabled *bool, extraEnv *[]corev1.EnvVar, tlsConfig *redisv1beta1.TLSConfig) []corev1.EnvVar {
+func getEnvironmentVariables(role string, enabledMetric bool, enabledPassword *bool, secretName *string, secretKey *string, persistenceEnabled *bool, extraEnv *[]corev1.EnvVar, tlsConfig *redisv1beta1.TLSConfig, memory *redisv1beta1.RedisMemory) []corev1.EnvVar {
 	envVars := []corev1.EnvVar{
 		{Name: "SERVER_MODE", Value: role},
 		{Name: "SETUP_MODE", Value: role},
@@ -522,7 +589,9 @@ func getEnvironmentVariables(role string, enabledMetric bool, enabledPassword *b
 		},
 	})
 
-	envVars = append(envVars, corev1.EnvVar{Name: "MEMORY_RATIO", Value: "0.6"})
+	if ratio, ok := getMemoryRatio(memory); ok {
+		envVars = append(envVars, corev1.EnvVar{Name: "MEMORY_RATIO", Value: ratio})
+	}
 
 	sort.SliceStable(envVars, func(i, j int) bool {
 		return envVars[i].Name < envVars[j].Name
@@ -531,9 +600,9 @@ func getEnvironmentVariables(role string, enabledMetric bool, enabledPassword *b
 }
 
 // createStatefulSet is a method to create statefulset in Kubernetes
-func createStatefulSet(namespace string, stateful *appsv1.StatefulSet) error {
+func createStatefulSet(cl client.Client, namespace string, stateful *appsv1.StatefulSet) error {
 	logger := statefulSetLogger(namespace, stateful.Name)
-	_, err := generateK8sClient().AppsV1().StatefulSets(namespace).Create(context.TODO(), stateful, metav1.CreateOptions{})
+	err := cl.Create(context.TODO(), stateful)
 	if err != nil {
 		logger.Error(err, "Redis stateful creation failed")
 		return err
@@ -543,10 +612,10 @@ func createStatefulSet(namespace string, stateful *appsv1.StatefulSet) error {
 }
 
 // updateStatefulSet is a method to update statefulset in Kubernetes
-func updateStatefulSet(namespace string, stateful *appsv1.StatefulSet) error {
+func updateStatefulSet(cl client.Client, namespace string, stateful *appsv1.StatefulSet) error {
 	logger := statefulSetLogger(namespace, stateful.Name)
 	// logger.Info(fmt.Sprintf("Setting Statefulset to the following: %s", stateful))
-	_, err := generateK8sClient().AppsV1().StatefulSets(namespace).Update(context.TODO(), stateful, metav1.UpdateOptions{})
+	err := cl.Update(context.TODO(), stateful)
 	if err != nil {
 		logger.Error(err, "Redis stateful update failed")
 		return err
@@ -556,12 +625,10 @@ func updateStatefulSet(namespace string, stateful *appsv1.StatefulSet) error {
 }
 
 // GetStateFulSet is a method to get statefulset in Kubernetes
-func GetStatefulSet(namespace string, stateful string) (*appsv1.StatefulSet, error) {
+func GetStatefulSet(cl client.Client, namespace string, stateful string) (*appsv1.StatefulSet, error) {
 	logger := statefulSetLogger(namespace, stateful)
-	getOpts := metav1.GetOptions{
-		TypeMeta: generateMetaInformation("StatefulSet", "apps/v1"),
-	}
-	statefulInfo, err := generateK8sClient().AppsV1().StatefulSets(namespace).Get(context.TODO(), stateful, getOpts)
+	statefulInfo := &appsv1.StatefulSet{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: stateful}, statefulInfo)
 	if err != nil {
 		logger.Info("Redis statefulset get action failed")
 		return nil, err
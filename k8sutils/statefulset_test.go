@@ -0,0 +1,225 @@
+package k8sutils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	redisv1beta1 "redis-operator/api/v1beta1"
+)
+
+func generatedPodTemplate() corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      map[string]string{"app": "redis", "role": "standalone"},
+			Annotations: map[string]string{"redis.opstreelabs.in": "true"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:         "redis",
+				Image:        "redis:7",
+				VolumeMounts: []corev1.VolumeMount{{Name: "external-config", MountPath: "/etc/redis/external.conf.d"}},
+			}},
+			Volumes: []corev1.Volume{{Name: "external-config"}},
+		},
+	}
+}
+
+func TestMergePodTemplate(t *testing.T) {
+	grace := int64(60)
+	var tests = []struct {
+		name     string
+		override *redisv1beta1.PodTemplate
+		check    func(t *testing.T, template corev1.PodTemplateSpec)
+	}{
+		{
+			name: "no override",
+			check: func(t *testing.T, template corev1.PodTemplateSpec) {
+				want := generatedPodTemplate()
+				if len(template.Spec.Containers) != 1 || template.Labels["app"] != "redis" || template.Annotations["redis.opstreelabs.in"] != want.Annotations["redis.opstreelabs.in"] {
+					t.Errorf("template changed without override: %+v", template)
+				}
+			},
+		},
+		{
+			name: "labels and annotations are added",
+			override: &redisv1beta1.PodTemplate{
+				Labels:      map[string]string{"team": "cache", "app": "other"},
+				Annotations: map[string]string{"sidecar.istio.io/inject": "false"},
+			},
+			check: func(t *testing.T, template corev1.PodTemplateSpec) {
+				if template.Labels["team"] != "cache" || template.Labels["app"] != "redis" {
+					t.Errorf("unexpected labels %v", template.Labels)
+				}
+				if template.Annotations["sidecar.istio.io/inject"] != "false" || template.Annotations["redis.opstreelabs.in"] != "true" {
+					t.Errorf("unexpected annotations %v", template.Annotations)
+				}
+			},
+		},
+		{
+			name: "spec is merged by keys",
+			override: &redisv1beta1.PodTemplate{
+				Spec: &runtime.RawExtension{Raw: []byte(`{
+					"terminationGracePeriodSeconds": 60,
+					"hostAliases": [{"ip": "10.0.0.1", "hostnames": ["backup"]}],
+					"initContainers": [{"name": "sysctl", "image": "busybox"}],
+					"containers": [{"name": "redis", "volumeMounts": [{"name": "data", "mountPath": "/backup"}]}],
+					"volumes": [{"name": "data", "emptyDir": {}}],
+					"topologySpreadConstraints": [{"maxSkew": 1, "topologyKey": "kubernetes.io/hostname", "whenUnsatisfiable": "ScheduleAnyway"}]
+				}`)},
+			},
+			check: func(t *testing.T, template corev1.PodTemplateSpec) {
+				spec := template.Spec
+				if spec.TerminationGracePeriodSeconds == nil || *spec.TerminationGracePeriodSeconds != grace {
+					t.Errorf("terminationGracePeriodSeconds not set")
+				}
+				if len(spec.HostAliases) != 1 || len(spec.InitContainers) != 1 || len(spec.TopologySpreadConstraints) != 1 {
+					t.Errorf("lists not added: %+v", spec)
+				}
+				if len(spec.Containers) != 1 || spec.Containers[0].Image != "redis:7" || len(spec.Containers[0].VolumeMounts) != 2 {
+					t.Errorf("redis container not patched: %+v", spec.Containers)
+				}
+				if len(spec.Volumes) != 2 {
+					t.Errorf("got %d volumes, want 2", len(spec.Volumes))
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := generatedPodTemplate()
+			if err := mergePodTemplate(&template, tt.override); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			tt.check(t, template)
+		})
+	}
+}
+
+func TestMergePodTemplateInvalid(t *testing.T) {
+	template := generatedPodTemplate()
+	override := &redisv1beta1.PodTemplate{Spec: &runtime.RawExtension{Raw: []byte(`{"containers": "redis"}`)}}
+	if err := mergePodTemplate(&template, override); err == nil {
+		t.Errorf("expected an error for a malformed pod spec")
+	}
+}
+
+func TestGetVolumeMountForSidecar(t *testing.T) {
+	enabled, disabled := true, false
+	var tests = []struct {
+		name        string
+		persistence *bool
+		mounts      []corev1.VolumeMount
+		want        []corev1.VolumeMount
+	}{
+		{"persistence mounts data", &enabled, nil, []corev1.VolumeMount{{Name: "redis-leader", MountPath: "/data"}}},
+		{"no persistence", &disabled, nil, nil},
+		{
+			"data alias is resolved",
+			&enabled,
+			[]corev1.VolumeMount{{Name: "data", MountPath: "/backup", ReadOnly: true}},
+			[]corev1.VolumeMount{{Name: "redis-leader", MountPath: "/backup", ReadOnly: true}},
+		},
+		{
+			"operator volumes are kept",
+			&enabled,
+			[]corev1.VolumeMount{{Name: "tls-certs", MountPath: "/tls"}, {Name: "external-config", MountPath: "/conf"}},
+			[]corev1.VolumeMount{{Name: "redis-leader", MountPath: "/data"}, {Name: "tls-certs", MountPath: "/tls"}, {Name: "external-config", MountPath: "/conf"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getVolumeMountForSidecar("redis-leader", tt.persistence, tt.mounts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateContainerDefSidecar(t *testing.T) {
+	probe := &redisv1beta1.Probe{InitialDelaySeconds: 1, TimeoutSeconds: 1, PeriodSeconds: 10, SuccessThreshold: 1, FailureThreshold: 3}
+	params := containerParameters{Image: "redis:7", Role: "cluster", ReadinessProbe: probe, LivenessProbe: probe}
+	agentProbe := &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz"}}}
+	sidecar := redisv1beta1.Sidecar{
+		Name:           "redis-agent",
+		Image:          "redis-agent:v1",
+		Command:        []string{"/agent"},
+		Args:           []string{"--port=8080"},
+		Ports:          []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+		ReadinessProbe: agentProbe,
+		VolumeMounts:   []corev1.VolumeMount{{Name: "external-config", MountPath: "/etc/redis/external.conf.d"}},
+	}
+
+	containers := generateContainerDef("redis-leader", params, false, nil, []redisv1beta1.Sidecar{sidecar})
+	if len(containers) != 2 {
+		t.Fatalf("got %d containers, want 2", len(containers))
+	}
+	agent := containers[1]
+	if agent.Name != "redis-agent" || !reflect.DeepEqual(agent.Command, sidecar.Command) || !reflect.DeepEqual(agent.Args, sidecar.Args) {
+		t.Errorf("unexpected sidecar %+v", agent)
+	}
+	if !reflect.DeepEqual(agent.Ports, sidecar.Ports) || agent.ReadinessProbe != agentProbe {
+		t.Errorf("ports or probe not copied: %+v", agent)
+	}
+	if !reflect.DeepEqual(agent.VolumeMounts, sidecar.VolumeMounts) {
+		t.Errorf("got mounts %v, want %v", agent.VolumeMounts, sidecar.VolumeMounts)
+	}
+}
+
+func TestGenerateContainerDefPreStop(t *testing.T) {
+	probe := &redisv1beta1.Probe{InitialDelaySeconds: 1, TimeoutSeconds: 1, PeriodSeconds: 10, SuccessThreshold: 1, FailureThreshold: 3}
+	var tests = []struct {
+		role    string
+		preStop bool
+	}{
+		{"cluster", true},
+		{"standalone", false},
+	}
+
+	for _, tt := range tests {
+		containers := generateContainerDef("redis", containerParameters{Image: "redis:7", Role: tt.role, ReadinessProbe: probe, LivenessProbe: probe}, false, nil, nil)
+		lifecycle := containers[0].Lifecycle
+		if got := lifecycle != nil && lifecycle.PreStop != nil && lifecycle.PreStop.Exec != nil; got != tt.preStop {
+			t.Errorf("%s: got preStop hook %t, want %t", tt.role, got, tt.preStop)
+			continue
+		}
+		if tt.preStop && !strings.Contains(lifecycle.PreStop.Exec.Command[2], "CLUSTER FAILOVER") {
+			t.Errorf("%s: unexpected preStop hook %v", tt.role, lifecycle.PreStop.Exec.Command)
+		}
+	}
+}
+
+func TestGetProbeInfo(t *testing.T) {
+	var tests = []struct {
+		mode  redisv1beta1.ProbeMode
+		check string
+	}{
+		{"", "/usr/bin/healthcheck.sh"},
+		{redisv1beta1.ProbeModeScript, "/usr/bin/healthcheck.sh"},
+		{redisv1beta1.ProbeModePing, "PING"},
+		{redisv1beta1.ProbeModeClusterState, "cluster_state:ok"},
+		{redisv1beta1.ProbeModeReplication, "master_link_status:up"},
+		{redisv1beta1.ProbeModeLoading, "loading:0"},
+	}
+
+	for _, tt := range tests {
+		probe := getProbeInfo(&redisv1beta1.Probe{Mode: tt.mode, PeriodSeconds: 10, FailureThreshold: 3})
+		if probe.PeriodSeconds != 10 || probe.FailureThreshold != 3 {
+			t.Errorf("%q: timings not copied: %+v", tt.mode, probe)
+		}
+		if probe.Exec == nil || !strings.Contains(strings.Join(probe.Exec.Command, " "), tt.check) {
+			t.Errorf("%q: got handler %+v, want a command with %q", tt.mode, probe.ProbeHandler, tt.check)
+		}
+	}
+
+	probe := getProbeInfo(&redisv1beta1.Probe{Mode: redisv1beta1.ProbeModeTCP})
+	if probe.TCPSocket == nil || probe.TCPSocket.Port.IntValue() != 6379 || probe.Exec != nil {
+		t.Errorf("got tcp handler %+v", probe.ProbeHandler)
+	}
+}
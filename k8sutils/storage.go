@@ -0,0 +1,197 @@
+package k8sutils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// storageState is the reason and message of the StorageResized condition for a statefulset
+type storageState struct {
+	reason  string
+	message string
+}
+
+// desiredStorageSize returns the size requested by the volume claim template
+func desiredStorageSize(storage *redisv1beta1.Storage) (resource.Quantity, bool) {
+	if storage == nil {
+		return resource.Quantity{}, false
+	}
+	size, ok := storage.VolumeClaimTemplate.Spec.Resources.Requests[corev1.ResourceStorage]
+	return size, ok && !size.IsZero()
+}
+
+// hasPVCCondition returns true if the condition of the PVC is true
+func hasPVCCondition(pvc *corev1.PersistentVolumeClaim, conditionType corev1.PersistentVolumeClaimConditionType) bool {
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// storageClassAllowsExpansion returns true if the storage class of the PVC has allowVolumeExpansion set
+func storageClassAllowsExpansion(cl client.Client, pvc *corev1.PersistentVolumeClaim) (bool, string, error) {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return false, "", nil
+	}
+	storageClass := &storagev1.StorageClass{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Name: *pvc.Spec.StorageClassName}, storageClass); err != nil {
+		return false, *pvc.Spec.StorageClassName, err
+	}
+	return storageClass.AllowVolumeExpansion != nil && *storageClass.AllowVolumeExpansion, storageClass.Name, nil
+}
+
+// expandStatefulSetStorage moves the PVCs of the statefulset one at a time to the desired size. A PVC is only
+// patched once the previous one reports the new capacity, and the statefulset is recreated with orphaned pods
+// when all PVCs are done, as its volumeClaimTemplates are immutable.
+func expandStatefulSetStorage(cr client.Object, cl client.Client, recorder record.EventRecorder, stsName, setupType, role string, desired resource.Quantity) storageState {
+	logger := pvcLogger(cr.GetNamespace(), stsName)
+	pvcs, err := listRedisPVCs(cl, cr.GetNamespace(), stsName, setupType, role, cr.GetLabels())
+	if err != nil {
+		return storageState{redisv1beta1.StorageReasonExpansionFailed, fmt.Sprintf("could not list the pvcs of %s: %v", stsName, err)}
+	}
+	sort.Slice(pvcs, func(i, j int) bool {
+		a, _ := pvcOrdinal(pvcs[i], stsName)
+		b, _ := pvcOrdinal(pvcs[j], stsName)
+		return a < b
+	})
+	for i := range pvcs {
+		request := pvcs[i].Spec.Resources.Requests[corev1.ResourceStorage]
+		if request.Cmp(desired) > 0 {
+			return storageState{redisv1beta1.StorageReasonShrinkNotSupported, fmt.Sprintf("pvc %s requests %s, volumes cannot shrink to %s", pvcs[i].Name, request.String(), desired.String())}
+		}
+	}
+
+	for i := range pvcs {
+		pvc := &pvcs[i]
+		if pvc.Status.Phase != corev1.ClaimBound {
+			// unbound claims are provisioned with their request, which the statefulset sets once it is recreated
+			continue
+		}
+		request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+		if request.Cmp(desired) >= 0 {
+			if capacity.Cmp(desired) >= 0 {
+				continue
+			}
+			if hasPVCCondition(pvc, corev1.PersistentVolumeClaimFileSystemResizePending) {
+				return storageState{redisv1beta1.StorageReasonFileSystemResizePending, fmt.Sprintf("pvc %s waits for the kubelet to resize the file system to %s", pvc.Name, desired.String())}
+			}
+			return storageState{redisv1beta1.StorageReasonExpanding, fmt.Sprintf("pvc %s is expanded from %s to %s", pvc.Name, capacity.String(), desired.String())}
+		}
+
+		allowed, storageClass, err := storageClassAllowsExpansion(cl, pvc)
+		if err != nil {
+			logger.Error(err, "Could not get the storage class of the pvc", "PVC", pvc.Name)
+			return storageState{redisv1beta1.StorageReasonExpansionFailed, fmt.Sprintf("could not get storage class %s of pvc %s: %v", storageClass, pvc.Name, err)}
+		}
+		if !allowed {
+			return storageState{redisv1beta1.StorageReasonExpansionNotAllowed, fmt.Sprintf("storage class %q of pvc %s does not allow volume expansion", storageClass, pvc.Name)}
+		}
+		patch := client.MergeFrom(pvc.DeepCopy())
+		if pvc.Spec.Resources.Requests == nil {
+			pvc.Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = desired
+		if err := cl.Patch(context.TODO(), pvc, patch); err != nil {
+			logger.Error(err, "Could not expand the pvc", "PVC", pvc.Name)
+			recordEvent(recorder, cr, corev1.EventTypeWarning, EventReasonPVCResizeFailed, "Could not expand pvc %s to %s: %v", pvc.Name, desired.String(), err)
+			return storageState{redisv1beta1.StorageReasonExpansionFailed, fmt.Sprintf("could not expand pvc %s to %s: %v", pvc.Name, desired.String(), err)}
+		}
+		recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonPVCResizeStarted, "Expanding pvc %s from %s to %s", pvc.Name, request.String(), desired.String())
+		return storageState{redisv1beta1.StorageReasonExpanding, fmt.Sprintf("pvc %s is expanded from %s to %s", pvc.Name, capacity.String(), desired.String())}
+	}
+
+	return recreateStatefulSetForStorage(cr, cl, recorder, stsName, desired)
+}
+
+// recreateStatefulSetForStorage deletes the statefulset with orphan cascade when its volume claim template has another
+// size, the pods keep running and are adopted by the statefulset which the next reconcile creates from the spec
+func recreateStatefulSetForStorage(cr client.Object, cl client.Client, recorder record.EventRecorder, stsName string, desired resource.Quantity) storageState {
+	logger := statefulSetLogger(cr.GetNamespace(), stsName)
+	sts, err := GetStatefulSet(cl, cr.GetNamespace(), stsName)
+	if errors.IsNotFound(err) {
+		return storageState{reason: redisv1beta1.StorageReasonResized}
+	}
+	if err != nil {
+		return storageState{redisv1beta1.StorageReasonExpansionFailed, fmt.Sprintf("could not get statefulset %s: %v", stsName, err)}
+	}
+	if sts.DeletionTimestamp != nil {
+		return storageState{redisv1beta1.StorageReasonStatefulSetRecreating, fmt.Sprintf("statefulset %s is recreated with the volume size %s", stsName, desired.String())}
+	}
+	for _, template := range sts.Spec.VolumeClaimTemplates {
+		if template.Name != stsName {
+			continue
+		}
+		size := template.Spec.Resources.Requests[corev1.ResourceStorage]
+		if size.Cmp(desired) == 0 {
+			break
+		}
+		if err := cl.Delete(context.TODO(), sts, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Could not delete the statefulset to update its volume claim template")
+			return storageState{redisv1beta1.StorageReasonExpansionFailed, fmt.Sprintf("could not recreate statefulset %s: %v", stsName, err)}
+		}
+		recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonPVCResized, "Resized the pvcs of %s from %s to %s", stsName, size.String(), desired.String())
+		recordEvent(recorder, cr, corev1.EventTypeNormal, EventReasonStatefulSetRecreated, "Recreating statefulset %s with orphaned pods for the volume size %s", stsName, desired.String())
+		return storageState{redisv1beta1.StorageReasonStatefulSetRecreating, fmt.Sprintf("statefulset %s is recreated with the volume size %s", stsName, desired.String())}
+	}
+	return storageState{reason: redisv1beta1.StorageReasonResized}
+}
+
+// storageCondition converts the state into the StorageResized condition
+func storageCondition(cr client.Object, state storageState) *metav1.Condition {
+	condition := &metav1.Condition{
+		Type:               redisv1beta1.ConditionStorageResized,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: cr.GetGeneration(),
+		Reason:             state.reason,
+		Message:            state.message,
+	}
+	if state.reason == redisv1beta1.StorageReasonResized {
+		condition.Status = metav1.ConditionTrue
+	}
+	return condition
+}
+
+// ReconcileRedisStorage expands the PVC of the standalone redis to the size of the volume claim template and
+// returns the StorageResized condition, or nil if the redis has no storage
+func ReconcileRedisStorage(cr *redisv1beta1.Redis, cl client.Client, recorder record.EventRecorder) *metav1.Condition {
+	desired, ok := desiredStorageSize(cr.Spec.Storage)
+	if !ok {
+		return nil
+	}
+	state := expandStatefulSetStorage(cr, cl, recorder, cr.ObjectMeta.Name, "standalone", "standalone", desired)
+	if state.reason == redisv1beta1.StorageReasonResized {
+		state.message = fmt.Sprintf("all pvcs have %s", desired.String())
+	}
+	return storageCondition(cr, state)
+}
+
+// ReconcileRedisClusterStorage expands the PVCs of the leaders and then of the followers to the size of the volume
+// claim template and returns the StorageResized condition, or nil if the cluster has no persistence
+func ReconcileRedisClusterStorage(cr *redisv1beta1.RedisCluster, cl client.Client, recorder record.EventRecorder) *metav1.Condition {
+	desired, ok := desiredStorageSize(cr.Spec.Storage)
+	if !ok || cr.Spec.PersistenceEnabled == nil || !*cr.Spec.PersistenceEnabled {
+		return nil
+	}
+	for _, role := range []string{"leader", "follower"} {
+		state := expandStatefulSetStorage(cr, cl, recorder, cr.ObjectMeta.Name+"-"+role, "cluster", role, desired)
+		if state.reason != redisv1beta1.StorageReasonResized {
+			return storageCondition(cr, state)
+		}
+	}
+	return storageCondition(cr, storageState{redisv1beta1.StorageReasonResized, fmt.Sprintf("all pvcs have %s", desired.String())})
+}
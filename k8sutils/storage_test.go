@@ -0,0 +1,135 @@
+package k8sutils
+
+import (
+	"context"
+	redisv1beta1 "redis-operator/api/v1beta1"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newStoragePVC returns a bound PVC of the standalone redis with the request and capacity
+func newStoragePVC(ordinal, request, capacity string, conditions ...corev1.PersistentVolumeClaimConditionType) *corev1.PersistentVolumeClaim {
+	storageClass := "standard"
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "redis-redis-" + ordinal,
+			Namespace: "default",
+			Labels:    getRedisLabels("redis", "standalone", "standalone", nil),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &storageClass,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(request)},
+			},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Phase:    corev1.ClaimBound,
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)},
+		},
+	}
+	for _, condition := range conditions {
+		pvc.Status.Conditions = append(pvc.Status.Conditions, corev1.PersistentVolumeClaimCondition{Type: condition, Status: corev1.ConditionTrue})
+	}
+	return pvc
+}
+
+// newStorageStatefulSet returns the statefulset of the standalone redis with the size of its volume claim template
+func newStorageStatefulSet(size string) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default"},
+		Spec: appsv1.StatefulSetSpec{VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis"},
+			Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+			}},
+		}}},
+	}
+}
+
+// pvcRequest returns the storage request of the PVC
+func pvcRequest(t *testing.T, cl client.Client, name string) string {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: name}, pvc); err != nil {
+		t.Fatalf("could not get pvc %s: %v", name, err)
+	}
+	request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	return request.String()
+}
+
+func TestReconcileRedisStorage(t *testing.T) {
+	allowExpansion := true
+	expandable := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "standard"}, AllowVolumeExpansion: &allowExpansion}
+	fixed := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "standard"}}
+
+	var tests = []struct {
+		name     string
+		objs     []client.Object
+		status   metav1.ConditionStatus
+		reason   string
+		requests map[string]string
+		deleted  bool
+	}{
+		{"expansion not allowed", []client.Object{fixed, newStorageStatefulSet("1Gi"), newStoragePVC("0", "1Gi", "1Gi")},
+			metav1.ConditionFalse, redisv1beta1.StorageReasonExpansionNotAllowed, map[string]string{"redis-redis-0": "1Gi"}, false},
+		{"shrink", []client.Object{expandable, newStorageStatefulSet("4Gi"), newStoragePVC("0", "4Gi", "4Gi")},
+			metav1.ConditionFalse, redisv1beta1.StorageReasonShrinkNotSupported, map[string]string{"redis-redis-0": "4Gi"}, false},
+		// only the first PVC is patched, the second one waits until the first one is resized
+		{"expand one at a time", []client.Object{expandable, newStorageStatefulSet("1Gi"), newStoragePVC("0", "1Gi", "1Gi"), newStoragePVC("1", "1Gi", "1Gi")},
+			metav1.ConditionFalse, redisv1beta1.StorageReasonExpanding, map[string]string{"redis-redis-0": "2Gi", "redis-redis-1": "1Gi"}, false},
+		{"file system resize pending", []client.Object{expandable, newStorageStatefulSet("1Gi"), newStoragePVC("0", "2Gi", "1Gi", corev1.PersistentVolumeClaimFileSystemResizePending), newStoragePVC("1", "1Gi", "1Gi")},
+			metav1.ConditionFalse, redisv1beta1.StorageReasonFileSystemResizePending, map[string]string{"redis-redis-0": "2Gi", "redis-redis-1": "1Gi"}, false},
+		{"recreate statefulset", []client.Object{expandable, newStorageStatefulSet("1Gi"), newStoragePVC("0", "2Gi", "2Gi")},
+			metav1.ConditionFalse, redisv1beta1.StorageReasonStatefulSetRecreating, map[string]string{"redis-redis-0": "2Gi"}, true},
+		{"resized", []client.Object{fixed, newStorageStatefulSet("2Gi"), newStoragePVC("0", "2Gi", "2Gi")},
+			metav1.ConditionTrue, redisv1beta1.StorageReasonResized, map[string]string{"redis-redis-0": "2Gi"}, false},
+	}
+
+	for _, tt := range tests {
+		cr := &redisv1beta1.Redis{
+			ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default", Generation: 3},
+			Spec: redisv1beta1.RedisSpec{Storage: &redisv1beta1.Storage{VolumeClaimTemplate: corev1.PersistentVolumeClaim{
+				Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2Gi")},
+				}},
+			}}},
+		}
+		cl := newFakeClient(tt.objs...)
+		condition := ReconcileRedisStorage(cr, cl, nil)
+		if condition == nil || condition.Status != tt.status || condition.Reason != tt.reason || condition.ObservedGeneration != 3 {
+			t.Errorf("%s: got condition %+v, want %s with reason %s", tt.name, condition, tt.status, tt.reason)
+			continue
+		}
+		for name, want := range tt.requests {
+			if got := pvcRequest(t, cl, name); got != want {
+				t.Errorf("%s: pvc %s requests %s, want %s", tt.name, name, got, want)
+			}
+		}
+		err := cl.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "redis"}, &appsv1.StatefulSet{})
+		if deleted := errors.IsNotFound(err); deleted != tt.deleted {
+			t.Errorf("%s: statefulset deleted = %t, want %t (%v)", tt.name, deleted, tt.deleted, err)
+		}
+	}
+}
+
+func TestReconcileRedisClusterStorageWithoutPersistence(t *testing.T) {
+	persistence := false
+	cr := &redisv1beta1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis-cluster", Namespace: "default"},
+		Spec: redisv1beta1.RedisClusterSpec{PersistenceEnabled: &persistence, Storage: &redisv1beta1.Storage{VolumeClaimTemplate: corev1.PersistentVolumeClaim{
+			Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2Gi")},
+			}},
+		}}},
+	}
+	if condition := ReconcileRedisClusterStorage(cr, newFakeClient(), nil); condition != nil {
+		t.Errorf("ReconcileRedisClusterStorage() = %+v, want no condition", condition)
+	}
+}
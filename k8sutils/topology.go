@@ -0,0 +1,56 @@
+package k8sutils
+
+import (
+	"context"
+	redisv1beta1 "redis-operator/api/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// generateTopologySpreadConstraints spreads the pods of a role evenly over the zones, and all
+// pods of the cluster over the nodes, so that a leader and its followers do not share a node
+func generateTopologySpreadConstraints(cr *redisv1beta1.RedisCluster, roleLabels map[string]string) []corev1.TopologySpreadConstraint {
+	return []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       cr.Spec.GetZoneKey(),
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     LabelSelectors(roleLabels),
+		},
+		{
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelHostname,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"redis_setup_type": "cluster"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "app",
+					Operator: metav1.LabelSelectorOpIn,
+					Values:   []string{cr.ObjectMeta.Name + "-leader", cr.ObjectMeta.Name + "-follower"},
+				}},
+			},
+		},
+	}
+}
+
+// getRedisPodZone returns the zone of the node the pod is scheduled on, or "" if it is unknown
+func getRedisPodZone(cl client.Client, namespace, podName, zoneKey string) string {
+	logger := generateRedisManagerLogger(namespace, podName)
+	pod := &corev1.Pod{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: podName}, pod); err != nil {
+		logger.Error(err, "Could not get redis pod to find its zone")
+		return ""
+	}
+	if pod.Spec.NodeName == "" {
+		return ""
+	}
+	node := &corev1.Node{}
+	if err := cl.Get(context.TODO(), types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+		logger.Error(err, "Could not get the node of the redis pod", "Node", pod.Spec.NodeName)
+		return ""
+	}
+	return node.Labels[zoneKey]
+}
@@ -17,8 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -26,13 +29,17 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	redisv1beta1 "redis-operator/api/v1beta1"
 	"redis-operator/controllers"
+	"redis-operator/k8sutils"
+	"redis-operator/k8sutils/capacity"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -49,14 +56,29 @@ func init() {
 }
 
 func main() {
+	// `manager capacity ...` sizes a Redis or RedisCluster without starting the operator
+	if len(os.Args) > 1 && os.Args[1] == "capacity" {
+		if err := capacity.RunCommand(os.Args[2:], os.Stdout); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var resyncPeriod time.Duration
+	var enableEvictionWebhook bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&resyncPeriod, "resync-period", controllers.DefaultResyncPeriod,
+		"The jittered interval at which healthy Redis setups are reconciled again.")
+	flag.BoolVar(&enableEvictionWebhook, "enable-eviction-webhook", false,
+		"Serve the pods/eviction webhook which protects the shards of the Redis clusters during node drains.")
 	opts := zap.Options{
 		Development: false,
 	}
@@ -84,22 +106,65 @@ func main() {
 		os.Exit(1)
 	}
 
+	k8sClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create kubernetes clientset")
+		os.Exit(1)
+	}
+	podExecutor := k8sutils.NewPodExecutor(mgr.GetConfig(), k8sClient)
+
+	// the slot layout of the clusters is served next to the metrics, e.g. /slots?namespace=default&cluster=redis-cluster&key=user:{42}
+	// it is authorized by its own ClusterRole, the metrics readers can not see the pods of other namespaces
+	if err := mgr.AddMetricsExtraHandler(k8sutils.SlotsPath, k8sutils.NewSlotsHandler(mgr.GetClient())); err != nil {
+		setupLog.Error(err, "unable to add the slots handler")
+		os.Exit(1)
+	}
+
+	if enableEvictionWebhook {
+		mgr.GetWebhookServer().Register(k8sutils.EvictionWebhookPath, &webhook.Admission{
+			Handler: k8sutils.NewEvictionValidator(mgr.GetClient(), mgr.GetEventRecorderFor("eviction-webhook")),
+		})
+	}
+
 	if err = (&controllers.RedisReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Redis"),
-		Scheme: mgr.GetScheme(),
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("Redis"),
+		Scheme:       mgr.GetScheme(),
+		Recorder:     mgr.GetEventRecorderFor("redis-controller"),
+		ResyncPeriod: resyncPeriod,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Redis")
 		os.Exit(1)
 	}
 	if err = (&controllers.RedisClusterReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("RedisCluster"),
-		Scheme: mgr.GetScheme(),
+		Client:       mgr.GetClient(),
+		Log:          ctrl.Log.WithName("controllers").WithName("RedisCluster"),
+		Scheme:       mgr.GetScheme(),
+		Recorder:     mgr.GetEventRecorderFor("rediscluster-controller"),
+		PodExecutor:  podExecutor,
+		ResyncPeriod: resyncPeriod,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RedisCluster")
 		os.Exit(1)
 	}
+	if err = (&controllers.RedisKeyAnalysisReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("RedisKeyAnalysis"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("rediskeyanalysis-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RedisKeyAnalysis")
+		os.Exit(1)
+	}
+	if err = (&controllers.RedisKeyCleanupReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("RedisKeyCleanup"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("rediskeycleanup-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RedisKeyCleanup")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("health", healthz.Ping); err != nil {